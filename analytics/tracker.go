@@ -28,6 +28,7 @@ const (
 	stepAbortedEventName           = "step_aborted"
 	stepPreparationFailedEventName = "step_preparation_failed"
 	stepSkippedEventName           = "step_skipped"
+	stepRetriedEventName           = "step_retried"
 	cliWarningEventName            = "cli_warning"
 	toolVersionSnapshotEventName   = "tool_version_snapshot"
 
@@ -60,6 +61,7 @@ const (
 	stackRevIdProperty            = "stack_rev_id"
 	snapshotProperty              = "snapshot"
 	toolVersionsProperty          = "tool_versions"
+	attemptProperty               = "attempt"
 
 	failedValue                    = "failed"
 	successfulValue                = "successful"
@@ -99,6 +101,10 @@ type StepResult struct {
 	ErrorMessage             string
 	Timeout, NoOutputTimeout time.Duration
 	Runtime                  time.Duration
+	// Attempt is the number of the step run (starting from 1) if the step has a retry policy
+	Attempt int
+	// WillRetry marks a failed attempt which is followed by another run of the step
+	WillRetry bool
 }
 
 type Tracker interface {
@@ -336,5 +342,12 @@ func mapStepResultToEvent(result StepResult) (string, analytics.Properties, erro
 
 	extraProperties[runTimeProperty] = int64(result.Runtime.Seconds())
 
+	if result.Attempt > 0 {
+		extraProperties[attemptProperty] = result.Attempt
+	}
+	if result.WillRetry {
+		eventName = stepRetriedEventName
+	}
+
 	return eventName, extraProperties, nil
 }
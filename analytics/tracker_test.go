@@ -114,6 +114,33 @@ func Test_mapStepResultToEvent(t *testing.T) {
 				"runtime": int64(0),
 			},
 		},
+		{
+			name: "Step failed, retried",
+			result: StepResult{
+				Status:    models.StepRunStatusCodeFailed,
+				Attempt:   1,
+				WillRetry: true,
+			},
+			expectedEvent: "step_retried",
+			expectedExtraProps: analytics.Properties{
+				"status":  "failed",
+				"attempt": 1,
+				"runtime": int64(0),
+			},
+		},
+		{
+			name: "Step succeeded after retry",
+			result: StepResult{
+				Status:  models.StepRunStatusCodeSuccess,
+				Attempt: 2,
+			},
+			expectedEvent: "step_finished",
+			expectedExtraProps: analytics.Properties{
+				"status":  "successful",
+				"attempt": 2,
+				"runtime": int64(0),
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -76,10 +76,17 @@ func getTrimmedStepName(stepRunResult models.StepRunResultsModel) string {
 		title = fmt.Sprintf("[Deprecated] %s", title)
 	}
 
+	var suffixParts []string
+	if reason := stepRunResult.Status.Name(); reason != "" {
+		suffixParts = append(suffixParts, reason)
+	}
+	if attempts := len(stepRunResult.Attempts); attempts > 1 {
+		suffixParts = append(suffixParts, fmt.Sprintf("%d attempts", attempts))
+	}
+
 	suffix := ""
-	reason := stepRunResult.Status.Name()
-	if reason != "" {
-		suffix = fmt.Sprintf("(%s)", reason)
+	if len(suffixParts) > 0 {
+		suffix = fmt.Sprintf("(%s)", strings.Join(suffixParts, ", "))
 	}
 
 	return trimTitle(title, suffix, titleBoxWidth)
@@ -432,17 +439,66 @@ func PrintRunningWorkflow(title string) {
 	log.Infof("Switching to workflow: %s", title)
 }
 
+// PrintRunningStage ...
+func PrintRunningStage(title string) {
+	log.Print()
+	log.Infof("Switching to stage: %s", title)
+}
+
 // PrintSummary ...
 func PrintSummary(buildRunResults models.BuildRunResultsModel) {
-	iconBoxWidth := len("   ")
-	timeBoxWidth := len(" time (s) ")
-	titleBoxWidth := stepRunSummaryBoxWidthInChars - 4 - iconBoxWidth - timeBoxWidth
+	printSummaryHeader(fmt.Sprintf("bitrise summary: %s", buildRunResults.WorkflowID))
+	printSummaryTableHeader()
+
+	runtime := printStepRunResultRows(buildRunResults.OrderedResults())
 
+	printSummaryFooter(runtime)
+}
+
+// PrintPipelineSummary ...
+func PrintPipelineSummary(pipelineRunResults models.PipelineRunResultsModel) {
+	printSummaryHeader(fmt.Sprintf("bitrise summary: %s", pipelineRunResults.PipelineID))
+	log.Printf("+%s+", strings.Repeat("-", stepRunSummaryBoxWidthInChars-2))
+
+	for _, stageResult := range pipelineRunResults.StageResults {
+		stageTitle := fmt.Sprintf("Stage: %s", stageResult.StageID)
+		if reason := stageResult.Status.Name(); reason != "" {
+			stageTitle = fmt.Sprintf("%s (%s)", stageTitle, reason)
+		}
+		log.Print(getRow(stageTitle))
+		log.Printf("+%s+", strings.Repeat("-", stepRunSummaryBoxWidthInChars-2))
+
+		for _, workflowResult := range stageResult.WorkflowResults {
+			log.Print(getRow(fmt.Sprintf("Workflow: %s", workflowResult.WorkflowID)))
+			printSummaryTableHeader()
+			printStepRunResultRows(workflowResult.OrderedResults())
+		}
+
+		for _, workflowID := range stageResult.AbortedWorkflows {
+			log.Print(getRow(fmt.Sprintf("Workflow: %s (Aborted)", workflowID)))
+			log.Printf("+%s+", strings.Repeat("-", stepRunSummaryBoxWidthInChars-2))
+		}
+	}
+
+	for _, workflowResult := range pipelineRunResults.WorkflowResults {
+		log.Print(getRow(fmt.Sprintf("Workflow: %s", workflowResult.WorkflowID)))
+		printSummaryTableHeader()
+		printStepRunResultRows(workflowResult.OrderedResults())
+	}
+
+	for _, workflowID := range pipelineRunResults.SkippedWorkflows {
+		log.Print(getRow(fmt.Sprintf("Workflow: %s (Skipped)", workflowID)))
+		log.Printf("+%s+", strings.Repeat("-", stepRunSummaryBoxWidthInChars-2))
+	}
+
+	printSummaryFooter(pipelineRunResults.RunTime)
+}
+
+func printSummaryHeader(title string) {
 	log.Print()
 	log.Print()
 	log.Printf("+%s+", strings.Repeat("-", stepRunSummaryBoxWidthInChars-2))
 
-	title := fmt.Sprintf("bitrise summary: %s", buildRunResults.WorkflowID)
 	whitespace := float64(stepRunSummaryBoxWidthInChars - 2 - len(title))
 	if whitespace < 0 {
 		whitespace = 0
@@ -450,13 +506,25 @@ func PrintSummary(buildRunResults models.BuildRunResultsModel) {
 	leftPadding := int(math.Floor(whitespace / 2.0))
 	rightPadding := int(math.Ceil(whitespace / 2.0))
 	log.Printf("|%s%s%s|", strings.Repeat(" ", leftPadding), title, strings.Repeat(" ", rightPadding))
+}
+
+func printSummaryTableHeader() {
+	iconBoxWidth := len("   ")
+	timeBoxWidth := len(" time (s) ")
+	titleBoxWidth := stepRunSummaryBoxWidthInChars - 4 - iconBoxWidth - timeBoxWidth
+
 	log.Printf("+%s+%s+%s+", strings.Repeat("-", iconBoxWidth), strings.Repeat("-", titleBoxWidth), strings.Repeat("-", timeBoxWidth))
 
 	whitespaceWidth := stepRunSummaryBoxWidthInChars - len("|   | title") - len("| time (s) |")
 	log.Printf("|   | title%s| time (s) |", strings.Repeat(" ", whitespaceWidth))
 	log.Printf("+%s+%s+%s+", strings.Repeat("-", iconBoxWidth), strings.Repeat("-", titleBoxWidth), strings.Repeat("-", timeBoxWidth))
+}
+
+func printStepRunResultRows(orderedResults []models.StepRunResultsModel) time.Duration {
+	iconBoxWidth := len("   ")
+	timeBoxWidth := len(" time (s) ")
+	titleBoxWidth := stepRunSummaryBoxWidthInChars - 4 - iconBoxWidth - timeBoxWidth
 
-	orderedResults := buildRunResults.OrderedResults()
 	tmpTime := time.Time{}
 	for _, stepRunResult := range orderedResults {
 		tmpTime = tmpTime.Add(stepRunResult.RunTime)
@@ -473,15 +541,17 @@ func PrintSummary(buildRunResults models.BuildRunResultsModel) {
 			}
 		}
 	}
-	runtime := tmpTime.Sub(time.Time{})
+	return tmpTime.Sub(time.Time{})
+}
 
+func printSummaryFooter(runtime time.Duration) {
 	runTimeStr, err := utils.FormattedSecondsToMax8Chars(runtime)
 	if err != nil {
 		log.Errorf("Failed to format time, error: %s", err)
 		runTimeStr = "999+ hour"
 	}
 
-	whitespaceWidth = stepRunSummaryBoxWidthInChars - len(fmt.Sprintf("| Total runtime: %s|", runTimeStr))
+	whitespaceWidth := stepRunSummaryBoxWidthInChars - len(fmt.Sprintf("| Total runtime: %s|", runTimeStr))
 	if whitespaceWidth < 0 {
 		log.Errorf("Invalid time box size for RunTime: %#v", runtime)
 		whitespaceWidth = 0
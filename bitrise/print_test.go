@@ -61,6 +61,28 @@ func TestGetTrimmedStepName(t *testing.T) {
 		expected := ""
 		require.Equal(t, expected, actual)
 	}
+
+	t.Log("retried step")
+	{
+		stepInfo := stepmanModels.StepInfoModel{
+			Step: stepmanModels.StepModel{
+				Title: pointers.NewStringPtr("Flaky step"),
+			},
+		}
+
+		result := models.StepRunResultsModel{
+			StepInfo: stepInfo,
+			Status:   models.StepRunStatusCodeFailed,
+			Attempts: []models.StepRunAttemptModel{
+				{Attempt: 1, Status: models.StepRunStatusCodeFailed, ExitCode: 1},
+				{Attempt: 2, Status: models.StepRunStatusCodeFailed, ExitCode: 1},
+			},
+		}
+
+		actual := getTrimmedStepName(result)
+		expected := "Flaky step (Failed, 2 attempts)"
+		require.Equal(t, expected, actual)
+	}
 }
 
 func Test_getRunningStepFooterMainSection(t *testing.T) {
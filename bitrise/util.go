@@ -8,7 +8,6 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/bitrise-io/bitrise/configs"
 	"github.com/bitrise-io/bitrise/log"
 	"github.com/bitrise-io/bitrise/models"
 	"github.com/bitrise-io/bitrise/tools"
@@ -169,14 +168,13 @@ func CollectEnvironmentsFromFileContent(bytes []byte) ([]envmanModels.Environmen
 }
 
 // CleanupStepWorkDir ...
-func CleanupStepWorkDir() error {
-	stepYMLPth := filepath.Join(configs.BitriseWorkDirPath, "current_step.yml")
+func CleanupStepWorkDir(workDirPath, stepsDirPath string) error {
+	stepYMLPth := filepath.Join(workDirPath, "current_step.yml")
 	if err := command.RemoveFile(stepYMLPth); err != nil {
 		return errors.New(fmt.Sprint("Failed to remove step yml: ", err))
 	}
 
-	stepDir := configs.BitriseWorkStepsDirPath
-	if err := command.RemoveDir(stepDir); err != nil {
+	if err := command.RemoveDir(stepsDirPath); err != nil {
 		return errors.New(fmt.Sprint("Failed to remove step work dir: ", err))
 	}
 	return nil
@@ -657,7 +655,7 @@ func removeStepDefaultsAndFillStepOutputs(stepListItem *models.StepListItemModel
 
 		workflowStep.Outputs = outputs
 
-		(*stepListItem)[compositeStepIDStr] = workflowStep
+		stepListItem.SetStep(compositeStepIDStr, workflowStep)
 	}
 
 	// Cleanup
@@ -673,12 +671,35 @@ func removeStepDefaultsAndFillStepOutputs(stepListItem *models.StepListItemModel
 
 // RemoveConfigRedundantFieldsAndFillStepOutputs ...
 func RemoveConfigRedundantFieldsAndFillStepOutputs(config *models.BitriseDataModel) error {
+	for _, bundle := range config.StepBundles {
+		if err := removeStepsDefaultsAndFillStepOutputs(bundle.Steps, config.DefaultStepLibSource); err != nil {
+			return err
+		}
+	}
 	for _, workflow := range config.Workflows {
-		for _, stepListItem := range workflow.Steps {
-			if err := removeStepDefaultsAndFillStepOutputs(&stepListItem, config.DefaultStepLibSource); err != nil {
+		if err := removeStepsDefaultsAndFillStepOutputs(workflow.Steps, config.DefaultStepLibSource); err != nil {
+			return err
+		}
+	}
+	return config.RemoveRedundantFields()
+}
+
+func removeStepsDefaultsAndFillStepOutputs(steps []models.StepListItemModel, defaultStepLibSource string) error {
+	for _, stepListItem := range steps {
+		if parallelSteps, ok := stepListItem.GetParallelSteps(); ok {
+			if err := removeStepsDefaultsAndFillStepOutputs(parallelSteps, defaultStepLibSource); err != nil {
 				return err
 			}
+			continue
+		}
+		if _, ok := stepListItem.GetStepBundleID(); ok {
+			// the steps of the bundle are processed with the step bundles of the config
+			continue
+		}
+
+		if err := removeStepDefaultsAndFillStepOutputs(&stepListItem, defaultStepLibSource); err != nil {
+			return err
 		}
 	}
-	return config.RemoveRedundantFields()
+	return nil
 }
@@ -18,10 +18,11 @@ import (
 
 type buildRunResultCollector struct {
 	tracker analytics.Tracker
+	logger  log.Logger
 }
 
-func newBuildRunResultCollector(tracker analytics.Tracker) buildRunResultCollector {
-	return buildRunResultCollector{tracker: tracker}
+func newBuildRunResultCollector(tracker analytics.Tracker, logger log.Logger) buildRunResultCollector {
+	return buildRunResultCollector{tracker: tracker, logger: logger}
 }
 
 func (r buildRunResultCollector) registerStepRunResults(
@@ -38,30 +39,33 @@ func (r buildRunResultCollector) registerStepRunResults(
 	printStepHeader bool,
 	redactedStepInputs map[string]string,
 	properties coreanalytics.Properties) {
+	r.registerRetriedStepRunResults(buildRunResults, stepExecutionId, stepStartTime, step, stepInfoPtr, stepIdxPtr,
+		status, exitCode, err, isLastStep, printStepHeader, redactedStepInputs, properties, nil)
+}
+
+// registerRetriedStepRunResults registers the final result of a step, which might have been run multiple times,
+// the attempts are the results of every run of the step, including the last one.
+func (r buildRunResultCollector) registerRetriedStepRunResults(
+	buildRunResults *models.BuildRunResultsModel,
+	stepExecutionId string,
+	stepStartTime time.Time,
+	step stepmanModels.StepModel,
+	stepInfoPtr stepmanModels.StepInfoModel,
+	stepIdxPtr int,
+	status models.StepRunStatus,
+	exitCode int,
+	err error,
+	isLastStep bool,
+	printStepHeader bool,
+	redactedStepInputs map[string]string,
+	properties coreanalytics.Properties,
+	attempts []models.StepRunAttemptModel) {
 
 	stepRuntime := time.Since(stepStartTime)
 
 	timeout, noOutputTimeout := time.Duration(-1), time.Duration(-1)
 	if status == models.StepRunStatusCodeFailed {
-		// Forward the status of a Step or a wrapped bitrise process.
-		switch exitCode {
-		case exitcode.CLIAbortedWithCustomTimeout:
-			status = models.StepRunStatusAbortedWithCustomTimeout
-		case exitcode.CLIAbortedWithNoOutputTimeout:
-			status = models.StepRunStatusAbortedWithNoOutputTimeout
-		}
-
-		var timeoutErr timeoutcmd.TimeoutError
-		if ok := errors.As(err, &timeoutErr); ok {
-			status = models.StepRunStatusAbortedWithCustomTimeout
-			timeout = timeoutErr.Timeout
-		}
-
-		var noOutputTimeoutErr timeoutcmd.NoOutputTimeoutError
-		if ok := errors.As(err, &noOutputTimeoutErr); ok {
-			status = models.StepRunStatusAbortedWithNoOutputTimeout
-			noOutputTimeout = noOutputTimeoutErr.Timeout
-		}
+		status, timeout, noOutputTimeout = failedStepRunStatus(exitCode, err)
 	}
 
 	stepInfoCopy := stepmanModels.StepInfoModel{
@@ -76,7 +80,7 @@ func (r buildRunResultCollector) registerStepRunResults(
 	}
 
 	if printStepHeader {
-		logStepStarted(stepInfoPtr, step, stepIdxPtr, stepExecutionId, stepStartTime)
+		logStepStarted(r.logger, stepInfoPtr, step, stepIdxPtr, stepExecutionId, stepStartTime)
 	}
 
 	errStr := ""
@@ -103,6 +107,12 @@ func (r buildRunResultCollector) registerStepRunResults(
 		NoOutputTimeout: noOutputTimeout,
 	}
 
+	attempt := 0
+	if len(attempts) > 0 {
+		stepResults.Attempts = attempts
+		attempt = len(attempts)
+	}
+
 	r.tracker.SendStepFinishedEvent(properties, analytics.StepResult{
 		Info:            prepareAnalyticsStepInfo(step, stepInfoPtr),
 		Status:          status,
@@ -110,9 +120,20 @@ func (r buildRunResultCollector) registerStepRunResults(
 		Timeout:         timeout,
 		NoOutputTimeout: noOutputTimeout,
 		Runtime:         stepRuntime,
+		Attempt:         attempt,
 	})
 
-	switch status {
+	if !appendStepRunResults(buildRunResults, stepResults) {
+		return
+	}
+
+	logStepFinished(r.logger, stepResults, stepExecutionId, isLastStep)
+}
+
+// appendStepRunResults adds the step result to the results list of its status,
+// it returns false for an unknown status.
+func appendStepRunResults(buildRunResults *models.BuildRunResultsModel, stepResults models.StepRunResultsModel) bool {
+	switch stepResults.Status {
 	case models.StepRunStatusCodeSuccess:
 		buildRunResults.SuccessSteps = append(buildRunResults.SuccessSteps, stepResults)
 	case models.StepRunStatusCodePreparationFailed:
@@ -128,15 +149,88 @@ func (r buildRunResultCollector) registerStepRunResults(
 	case models.StepRunStatusCodeSkippedWithRunIf:
 		buildRunResults.SkippedSteps = append(buildRunResults.SkippedSteps, stepResults)
 	default:
-		return
+		return false
+	}
+	return true
+}
+
+// registerStepRunAttempt reports a failed step run, which is followed by a retry.
+func (r buildRunResultCollector) registerStepRunAttempt(
+	attempt int,
+	attemptStartTime time.Time,
+	step stepmanModels.StepModel,
+	stepInfoPtr stepmanModels.StepInfoModel,
+	exitCode int,
+	err error,
+	properties coreanalytics.Properties) models.StepRunAttemptModel {
+
+	attemptResult := newStepRunAttempt(attempt, attemptStartTime, models.StepRunStatusCodeFailed, exitCode, err)
+	_, timeout, noOutputTimeout := failedStepRunStatus(exitCode, err)
+
+	r.tracker.SendStepFinishedEvent(properties, analytics.StepResult{
+		Info:            prepareAnalyticsStepInfo(step, stepInfoPtr),
+		Status:          attemptResult.Status,
+		ErrorMessage:    attemptResult.ErrorStr,
+		Timeout:         timeout,
+		NoOutputTimeout: noOutputTimeout,
+		Runtime:         attemptResult.RunTime,
+		Attempt:         attempt,
+		WillRetry:       true,
+	})
+
+	return attemptResult
+}
+
+func newStepRunAttempt(attempt int, attemptStartTime time.Time, status models.StepRunStatus, exitCode int, err error) models.StepRunAttemptModel {
+	if status == models.StepRunStatusCodeFailed {
+		status, _, _ = failedStepRunStatus(exitCode, err)
+	}
+
+	errStr := ""
+	if err != nil {
+		errStr = err.Error()
+	}
+
+	return models.StepRunAttemptModel{
+		Attempt:   attempt,
+		Status:    status,
+		ExitCode:  exitCode,
+		ErrorStr:  errStr,
+		StartTime: attemptStartTime,
+		RunTime:   time.Since(attemptStartTime),
+	}
+}
+
+// failedStepRunStatus forwards the abort reason of a failed Step or a wrapped bitrise process.
+func failedStepRunStatus(exitCode int, err error) (models.StepRunStatus, time.Duration, time.Duration) {
+	status := models.StepRunStatusCodeFailed
+	timeout, noOutputTimeout := time.Duration(-1), time.Duration(-1)
+
+	switch exitCode {
+	case exitcode.CLIAbortedWithCustomTimeout:
+		status = models.StepRunStatusAbortedWithCustomTimeout
+	case exitcode.CLIAbortedWithNoOutputTimeout:
+		status = models.StepRunStatusAbortedWithNoOutputTimeout
+	}
+
+	var timeoutErr timeoutcmd.TimeoutError
+	if ok := errors.As(err, &timeoutErr); ok {
+		status = models.StepRunStatusAbortedWithCustomTimeout
+		timeout = timeoutErr.Timeout
+	}
+
+	var noOutputTimeoutErr timeoutcmd.NoOutputTimeoutError
+	if ok := errors.As(err, &noOutputTimeoutErr); ok {
+		status = models.StepRunStatusAbortedWithNoOutputTimeout
+		noOutputTimeout = noOutputTimeoutErr.Timeout
 	}
 
-	logStepFinished(stepResults, stepExecutionId, isLastStep)
+	return status, timeout, noOutputTimeout
 }
 
-func logStepFinished(stepResults models.StepRunResultsModel, stepExecutionId string, isLastStep bool) {
+func logStepFinished(logger log.Logger, stepResults models.StepRunResultsModel, stepExecutionId string, isLastStep bool) {
 	params := stepFinishedParamsFromResults(stepResults, stepExecutionId, isLastStep)
-	log.PrintStepFinishedEvent(params)
+	logger.PrintStepFinishedEvent(params)
 }
 
 func stepFinishedParamsFromResults(results models.StepRunResultsModel, stepExecutionId string, isLastStep bool) log.StepFinishedParams {
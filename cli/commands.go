@@ -8,6 +8,7 @@ const (
 	JSONParamsBase64Key = "json-params-base64"
 
 	WorkflowKey = "workflow"
+	PipelineKey = "pipeline"
 
 	PatternKey        = "pattern"
 	PushBranchKey     = "push-branch"
@@ -119,6 +120,9 @@ var (
 			},
 		},
 		workflowListCommand,
+		pipelineListCommand,
+		stepCommand,
+		containerCommand,
 		{
 			Name:   "share",
 			Usage:  "Publish your step.",
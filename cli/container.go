@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/bitrise/cli/docker"
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/log"
+	"github.com/bitrise-io/bitrise/tools"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/urfave/cli"
+)
+
+const (
+	// KeepContainersKey ...
+	KeepContainersKey = "keep-containers"
+
+	containerSessionsDirName = "container-sessions"
+)
+
+var containerCommand = cli.Command{
+	Name:  "container",
+	Usage: "Docker container related commands.",
+	Subcommands: []cli.Command{
+		{
+			Name:      "shell",
+			Usage:     "Opens a shell in the kept container of the failed step of the workflow (see: bitrise run --keep-containers).",
+			ArgsUsage: "<workflow>",
+			Action: func(c *cli.Context) error {
+				if err := containerShell(c); err != nil {
+					failf("Failed to open container shell: %s", err)
+				}
+				return nil
+			},
+			Flags: []cli.Flag{
+				flInventory,
+			},
+		},
+	},
+}
+
+// containerSessionModel is the container and the envs of the step, which failed in a kept container.
+// The secrets are not saved, these are added again from the inventory when the shell is opened.
+type containerSessionModel struct {
+	WorkflowID    string   `json:"workflow"`
+	Runtime       string   `json:"runtime,omitempty"`
+	ContainerName string   `json:"container"`
+	Envs          []string `json:"envs"`
+}
+
+func containerSessionPath(workflowID string) (string, error) {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %s", err)
+	}
+
+	// the sessions are kept per project (working directory) and workflow, like the run checkpoints
+	projectHash := sha256.Sum256([]byte(workDir))
+	return filepath.Join(configs.GetBitriseHomeDirPath(), containerSessionsDirName, hex.EncodeToString(projectHash[:8]), workflowID+".json"), nil
+}
+
+// saveContainerSession saves the container (with the CLI of its runtime) and the envs (except the secrets) of the failed step.
+func saveContainerSession(workflowID, runtime, containerName string, envs []string, secrets []envmanModels.EnvironmentItemModel) error {
+	secretKeys, _ := tools.GetSecretKeysAndValues(secrets)
+	isSecret := map[string]bool{}
+	for _, key := range secretKeys {
+		isSecret[key] = true
+	}
+
+	session := containerSessionModel{WorkflowID: workflowID, Runtime: runtime, ContainerName: containerName}
+	for _, env := range envs {
+		key := strings.SplitN(env, "=", 2)[0]
+		if !isSecret[key] {
+			session.Envs = append(session.Envs, env)
+		}
+	}
+
+	pth, err := containerSessionPath(workflowID)
+	if err != nil {
+		return err
+	}
+
+	bytes, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to serialize container session: %s", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(pth), 0700); err != nil {
+		return fmt.Errorf("failed to create container sessions dir: %s", err)
+	}
+	// the step envs might contain sensitive values
+	return os.WriteFile(pth, bytes, 0600)
+}
+
+func loadContainerSession(workflowID string) (containerSessionModel, error) {
+	pth, err := containerSessionPath(workflowID)
+	if err != nil {
+		return containerSessionModel{}, err
+	}
+
+	bytes, err := os.ReadFile(pth)
+	if os.IsNotExist(err) {
+		return containerSessionModel{}, fmt.Errorf("no kept container found for workflow (%s), run the workflow with --%s first", workflowID, KeepContainersKey)
+	} else if err != nil {
+		return containerSessionModel{}, fmt.Errorf("failed to read container session: %s", err)
+	}
+
+	var session containerSessionModel
+	if err := json.Unmarshal(bytes, &session); err != nil {
+		return containerSessionModel{}, fmt.Errorf("failed to parse container session: %s", err)
+	}
+	return session, nil
+}
+
+// containerShellArgs returns the docker exec arguments of an interactive shell with the envs of the failed step,
+// bash is used if the image has it.
+func containerShellArgs(session containerSessionModel, secretEnvs []string) []string {
+	args := []string{"exec", "-it"}
+	for _, env := range append(session.Envs, secretEnvs...) {
+		args = append(args, "-e", env)
+	}
+	return append(args, session.ContainerName, "sh", "-c", "command -v bash >/dev/null && exec bash || exec sh")
+}
+
+func containerShell(c *cli.Context) error {
+	if len(c.Args()) < 1 {
+		return fmt.Errorf("no workflow specified")
+	}
+	workflowID := c.Args()[0]
+
+	session, err := loadContainerSession(workflowID)
+	if err != nil {
+		return err
+	}
+
+	secrets, err := CreateInventoryFromCLIParams("", c.String(InventoryKey))
+	if err != nil {
+		return fmt.Errorf("failed to create inventory: %s", err)
+	}
+	expandedSecrets, err := tools.ExpandEnvItems(secrets, os.Environ())
+	if err != nil {
+		return fmt.Errorf("failed to expand secrets: %s", err)
+	}
+	var secretEnvs []string
+	for key, value := range expandedSecrets {
+		secretEnvs = append(secretEnvs, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	runtime := session.Runtime
+	if runtime == "" {
+		runtime = docker.RuntimeDocker
+	}
+
+	log.Infof("Opening a shell in container: %s", session.ContainerName)
+	return command.NewWithStandardOuts(runtime, containerShellArgs(session, secretEnvs)...).SetStdin(os.Stdin).Run()
+}
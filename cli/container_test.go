@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/bitrise-io/bitrise/configs"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerSession(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	_, err := loadContainerSession("primary")
+	require.EqualError(t, err, "no kept container found for workflow (primary), run the workflow with --keep-containers first")
+
+	secrets := []envmanModels.EnvironmentItemModel{{"API_TOKEN": "secret"}}
+	require.NoError(t, saveContainerSession("primary", "podman", "bitrise-workflow-primary", []string{"API_TOKEN=secret", "BITRISE_SOURCE_DIR=/bitrise/src"}, secrets))
+
+	session, err := loadContainerSession("primary")
+	require.NoError(t, err)
+	require.Equal(t, containerSessionModel{
+		WorkflowID:    "primary",
+		Runtime:       "podman",
+		ContainerName: "bitrise-workflow-primary",
+		Envs:          []string{"BITRISE_SOURCE_DIR=/bitrise/src"},
+	}, session)
+
+	require.Equal(t, []string{
+		"exec", "-it",
+		"-e", "BITRISE_SOURCE_DIR=/bitrise/src",
+		"-e", "API_TOKEN=secret",
+		"bitrise-workflow-primary", "sh", "-c", "command -v bash >/dev/null && exec bash || exec sh",
+	}, containerShellArgs(session, []string{"API_TOKEN=secret"}))
+}
+
+func TestIsKeepContainers(t *testing.T) {
+	keep, err := isKeepContainers(true, nil)
+	require.NoError(t, err)
+	require.True(t, keep)
+
+	keep, err = isKeepContainers(false, []envmanModels.EnvironmentItemModel{{configs.KeepContainersEnvKey: "true"}})
+	require.NoError(t, err)
+	require.True(t, keep)
+
+	t.Setenv(configs.KeepContainersEnvKey, "true")
+	keep, err = isKeepContainers(false, []envmanModels.EnvironmentItemModel{{configs.KeepContainersEnvKey: "false"}})
+	require.NoError(t, err)
+	require.False(t, keep)
+
+	keep, err = isKeepContainers(false, nil)
+	require.NoError(t, err)
+	require.True(t, keep)
+}
+
+func TestContainerRuntimeName(t *testing.T) {
+	t.Setenv(configs.ContainerRuntimeEnvKey, "")
+	require.Equal(t, "docker", containerRuntimeName(nil))
+	require.Equal(t, "podman", containerRuntimeName(&configs.AgentConfig{ContainerRuntime: "podman"}))
+
+	t.Setenv(configs.ContainerRuntimeEnvKey, "docker")
+	require.Equal(t, "docker", containerRuntimeName(&configs.AgentConfig{ContainerRuntime: "podman"}))
+}
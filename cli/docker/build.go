@@ -0,0 +1,249 @@
+package docker
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+)
+
+const (
+	buildImageRepository = "bitrise-build"
+	defaultDockerfile    = "Dockerfile"
+)
+
+// buildImage builds the image of the container and returns its tag. The tag is the hash of the build context
+// (the files not excluded by .dockerignore), the Dockerfile, the build args, the target and the platform,
+// so the image is only rebuilt if any of these changed since the last build.
+func (cm *ContainerManager) buildImage(container models.Container, envs map[string]string) (string, error) {
+	options := BuildOptions{
+		Context:    expandEnvs(container.Build.Context, envs),
+		Dockerfile: container.Build.Dockerfile,
+		Target:     container.Build.Target,
+		Platform:   container.GetPlatform(),
+	}
+	if !filepath.IsAbs(options.Context) {
+		options.Context = filepath.Join(lookupEnv(configs.BitriseSourceDirEnvKey, envs), options.Context)
+	}
+	if options.Dockerfile == "" {
+		options.Dockerfile = defaultDockerfile
+	}
+	if len(container.Build.Args) > 0 {
+		options.Args = map[string]string{}
+		for name, value := range container.Build.Args {
+			options.Args[name] = expandEnvs(value, envs)
+		}
+	}
+
+	contextHash, err := buildContextHash(options)
+	if err != nil {
+		return "", fmt.Errorf("hash build context: %w", err)
+	}
+	options.Tag = fmt.Sprintf("%s:%s", buildImageRepository, contextHash[:16])
+
+	image, err := cm.runtime.InspectImage(options.Tag)
+	if err != nil {
+		cm.logger.Warnf("Failed to check whether the image was built already, building...: %s", err.Error())
+	} else if image != nil {
+		cm.logger.Infof("ℹ️ Build context is unchanged, using image: %s", options.Tag)
+		return options.Tag, nil
+	}
+
+	cm.logger.Infof("ℹ️ Building docker image: %s", options.Tag)
+	if err := cm.runtime.Build(options); err != nil {
+		return "", err
+	}
+	cm.logger.Infof("✅ Docker image built: %s", options.Tag)
+
+	return options.Tag, nil
+}
+
+// buildContextHash returns the sha256 hash of everything that determines the built image.
+func buildContextHash(options BuildOptions) (string, error) {
+	h := sha256.New()
+
+	if err := hashFile(h, "dockerfile", filepath.Join(options.Context, options.Dockerfile)); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "target\x00%s\x00platform\x00%s\x00", options.Target, options.Platform)
+
+	var argNames []string
+	for name := range options.Args {
+		argNames = append(argNames, name)
+	}
+	sort.Strings(argNames)
+	for _, name := range argNames {
+		fmt.Fprintf(h, "arg\x00%s=%s\x00", name, options.Args[name])
+	}
+
+	ignorePatterns, err := readDockerignore(options.Context)
+	if err != nil {
+		return "", err
+	}
+
+	err = filepath.WalkDir(options.Context, func(pth string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(options.Context, pth)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if isIgnored(relPath, ignorePatterns) {
+			// an excluded dir's content can only be included again by an exception pattern
+			if entry.IsDir() && !hasExceptions(ignorePatterns) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "path\x00%s\x00%s\x00%t\x00", relPath, info.Mode().Type(), info.Mode().Perm()&0111 != 0)
+
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(pth)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s\x00", target)
+		case info.Mode().IsRegular():
+			return hashFile(h, "file", pth)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(h hash.Hash, kind, pth string) error {
+	file, err := os.Open(pth)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fmt.Fprintf(h, "%s\x00", kind)
+	if _, err := io.Copy(h, file); err != nil {
+		return fmt.Errorf("read %s: %w", pth, err)
+	}
+	_, err = h.Write([]byte{0})
+	return err
+}
+
+type ignorePattern struct {
+	regexp    *regexp.Regexp
+	exception bool
+}
+
+// readDockerignore reads the .dockerignore patterns of the build context.
+// The `*`, `?` and `**` wildcards and the `!` exceptions are supported, like in the docker CLI.
+func readDockerignore(contextDir string) ([]ignorePattern, error) {
+	file, err := os.Open(filepath.Join(contextDir, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var patterns []ignorePattern
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pattern := ignorePattern{}
+		if strings.HasPrefix(line, "!") {
+			pattern.exception = true
+			line = strings.TrimSpace(line[1:])
+		}
+		line = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(line)), "/")
+
+		pattern.regexp, err = dockerignoreRegexp(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid .dockerignore pattern (%s): %w", line, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, scanner.Err()
+}
+
+func dockerignoreRegexp(pattern string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			// matches zero or more dirs
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+// isIgnored returns whether the path is excluded from the build context, the last matching pattern decides.
+// A pattern matching a dir excludes everything in the dir.
+func isIgnored(relPath string, patterns []ignorePattern) bool {
+	ignored := false
+	for _, pattern := range patterns {
+		if matchesPathOrParent(pattern.regexp, relPath) {
+			ignored = !pattern.exception
+		}
+	}
+	return ignored
+}
+
+func matchesPathOrParent(re *regexp.Regexp, relPath string) bool {
+	parts := strings.Split(relPath, "/")
+	for i := range parts {
+		if re.MatchString(strings.Join(parts[:i+1], "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasExceptions(patterns []ignorePattern) bool {
+	for _, pattern := range patterns {
+		if pattern.exception {
+			return true
+		}
+	}
+	return false
+}
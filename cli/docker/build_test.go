@@ -0,0 +1,106 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerManager_BuildImage(t *testing.T) {
+	sourceDir := t.TempDir()
+	contextDir := filepath.Join(sourceDir, "ci")
+	require.NoError(t, os.MkdirAll(filepath.Join(contextDir, "node_modules"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(contextDir, "Dockerfile.ci"), []byte("FROM ubuntu:22.04\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(contextDir, "setup.sh"), []byte("echo setup\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(contextDir, ".dockerignore"), []byte("node_modules\n"), 0644))
+	envs := map[string]string{configs.BitriseSourceDirEnvKey: sourceDir, "NPM_TOKEN": "token"}
+
+	container := models.Container{
+		Build: &models.ContainerBuild{
+			Context:    "ci",
+			Dockerfile: "Dockerfile.ci",
+			Args:       map[string]string{"NPM_TOKEN": "$NPM_TOKEN"},
+			Target:     "ci",
+		},
+	}
+
+	runtime := NewFakeRuntime()
+	manager := newTestContainerManager(runtime)
+
+	tag, err := manager.buildImage(container, envs)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(tag, "bitrise-build:"))
+	require.Equal(t, []string{"image-inspect " + tag, "build " + tag + " " + contextDir}, runtime.Commands)
+
+	// the build is reused if the context didn't change
+	require.NoError(t, os.WriteFile(filepath.Join(contextDir, "node_modules", "ignored.js"), []byte("ignored"), 0644))
+	runtime.Commands = nil
+	reusedTag, err := manager.buildImage(container, envs)
+	require.NoError(t, err)
+	require.Equal(t, tag, reusedTag)
+	require.Equal(t, []string{"image-inspect " + tag}, runtime.Commands)
+
+	// the image is rebuilt if the context changed
+	require.NoError(t, os.WriteFile(filepath.Join(contextDir, "setup.sh"), []byte("echo setup v2\n"), 0755))
+	changedTag, err := manager.buildImage(container, envs)
+	require.NoError(t, err)
+	require.NotEqual(t, tag, changedTag)
+
+	// or if a build arg changed
+	envs["NPM_TOKEN"] = "new-token"
+	changedArgTag, err := manager.buildImage(container, envs)
+	require.NoError(t, err)
+	require.NotEqual(t, changedTag, changedArgTag)
+}
+
+func TestIsIgnored(t *testing.T) {
+	dir := t.TempDir()
+	dockerignore := `
+# comment
+node_modules
+/build
+**/*.log
+!keep.log
+docs/?.md
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte(dockerignore), 0644))
+	patterns, err := readDockerignore(dir)
+	require.NoError(t, err)
+
+	tests := map[string]bool{
+		"node_modules":                true,
+		"node_modules/lib/index.js":   true,
+		"src/node_modules":            false,
+		"build/app":                   true,
+		"app/build":                   false,
+		"debug.log":                   true,
+		"logs/today/debug.log":        true,
+		"keep.log":                    false,
+		"docs/a.md":                   true,
+		"docs/ab.md":                  false,
+		"src/main.go":                 false,
+		"node_modules_backup/main.go": false,
+	}
+	for relPath, want := range tests {
+		require.Equal(t, want, isIgnored(relPath, patterns), relPath)
+	}
+}
+
+func TestBuildArgs(t *testing.T) {
+	require.Equal(t, []string{
+		"build", "--platform", "linux/arm64", "--file", "/src/ci/Dockerfile", "--tag", "bitrise-build:abc",
+		"--target", "ci", "--build-arg", "A=1", "--build-arg", "B=2", "/src/ci",
+	}, buildArgs(BuildOptions{
+		Context:    "/src/ci",
+		Dockerfile: "Dockerfile",
+		Tag:        "bitrise-build:abc",
+		Args:       map[string]string{"B": "2", "A": "1"},
+		Target:     "ci",
+		Platform:   "linux/arm64",
+	}))
+}
@@ -0,0 +1,143 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/bitrise/configs"
+)
+
+// composeNetworkOverride attaches the services of the compose file to the network of the workflow, instead of the default
+// network created by compose, so the services are reachable by their names from the workflow and step containers.
+const composeNetworkOverride = `networks:
+  default:
+    name: %s
+    external: true
+`
+
+// compose project names can only contain lowercase letters, digits, dashes and underscores
+var invalidComposeProjectChars = regexp.MustCompile(`[^a-z0-9_-]`)
+
+type composeProject struct {
+	options     ComposeOptions
+	overrideDir string
+}
+
+// StartComposeServices starts the services of the workflow's compose file on the network of the workflow,
+// and waits for them to be healthy. The compose file is relative to BITRISE_SOURCE_DIR, its env var references are expanded.
+func (cm *ContainerManager) StartComposeServices(
+	composeFile string,
+	workflowID string,
+	envs map[string]string,
+) ([]*RunningContainer, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.released {
+		return nil, fmt.Errorf("container manager was released already")
+	}
+
+	network := cm.networkName(workflowID)
+	if err := cm.runtime.EnsureNetwork(network); err != nil {
+		return nil, fmt.Errorf("ensure bitrise docker network: %w", err)
+	}
+	cm.networks[network] = true
+
+	composeFile = expandEnvs(composeFile, envs)
+	if !filepath.IsAbs(composeFile) {
+		composeFile = filepath.Join(lookupEnv(configs.BitriseSourceDirEnvKey, envs), composeFile)
+	}
+
+	overrideDir, err := os.MkdirTemp("", "bitrise-compose-")
+	if err != nil {
+		return nil, fmt.Errorf("create compose override dir: %w", err)
+	}
+	overridePath := filepath.Join(overrideDir, "network.yml")
+	if err := os.WriteFile(overridePath, []byte(fmt.Sprintf(composeNetworkOverride, network)), 0644); err != nil {
+		return nil, fmt.Errorf("write compose network override: %w", err)
+	}
+
+	project := composeProject{
+		options: ComposeOptions{
+			Project: invalidComposeProjectChars.ReplaceAllString(strings.ToLower(network), "_"),
+			Files:   []string{composeFile, overridePath},
+		},
+		overrideDir: overrideDir,
+	}
+	// Even on failure we save the project to make sure its containers will be cleaned up
+	cm.composeProjects[workflowID] = project
+
+	cm.logger.Infof("ℹ️ Starting the services of compose file: %s", composeFile)
+	if err := cm.runtime.ComposeUp(project.options); err != nil {
+		return nil, fmt.Errorf("compose up: %w", err)
+	}
+
+	serviceContainerIDs, err := cm.runtime.ComposeContainers(project.options)
+	if err != nil {
+		return nil, fmt.Errorf("list compose containers: %w", err)
+	}
+
+	var services []string
+	for service := range serviceContainerIDs {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	var containers []*RunningContainer
+	for _, service := range services {
+		ids := serviceContainerIDs[service]
+		for i, id := range ids {
+			state, err := cm.runtime.Inspect(id)
+			if err != nil {
+				return containers, fmt.Errorf("inspect container of compose service (%s): %w", service, err)
+			}
+
+			serviceName := service
+			if len(ids) > 1 {
+				serviceName = fmt.Sprintf("%s-%d", service, i+1)
+			}
+			containers = append(containers, &RunningContainer{
+				ID:          state.ID,
+				Name:        state.Name,
+				Runtime:     cm.runtime,
+				serviceName: serviceName,
+			})
+		}
+	}
+	cm.serviceContainers[workflowID] = append(cm.serviceContainers[workflowID], containers...)
+
+	for _, container := range containers {
+		if err := cm.healthCheckContainer(container); err != nil {
+			cm.printServiceLogs(container.serviceName, container)
+			return containers, fmt.Errorf("container health check: %w", err)
+		}
+	}
+
+	return containers, nil
+}
+
+// StopComposeServices removes the containers, volumes and networks of the workflow's compose project.
+func (cm *ContainerManager) StopComposeServices(workflowID string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	project, ok := cm.composeProjects[workflowID]
+	if !ok {
+		return nil
+	}
+	delete(cm.composeProjects, workflowID)
+
+	return cm.composeDown(project)
+}
+
+func (cm *ContainerManager) composeDown(project composeProject) error {
+	if err := cm.runtime.ComposeDown(project.options); err != nil {
+		return fmt.Errorf("compose down: %w", err)
+	}
+	if err := os.RemoveAll(project.overrideDir); err != nil {
+		return fmt.Errorf("remove compose override dir: %w", err)
+	}
+	return nil
+}
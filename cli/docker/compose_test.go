@@ -0,0 +1,78 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerManager_ComposeServices(t *testing.T) {
+	sourceDir := t.TempDir()
+	envs := map[string]string{configs.BitriseSourceDirEnvKey: sourceDir, "COMPOSE_DIR": "ci"}
+
+	runtime := NewFakeRuntime()
+	runtime.ComposeServices["db"] = []string{"db-1"}
+	runtime.ComposeServices["worker"] = []string{"worker-1", "worker-2"}
+	runtime.Health["db-1"] = "healthy"
+	manager := newTestContainerManager(runtime)
+
+	containers, err := manager.StartComposeServices("$COMPOSE_DIR/docker-compose.yml", "primary", envs)
+	require.NoError(t, err)
+	require.Equal(t, containers, manager.GetServiceContainers("primary"))
+
+	var names, serviceNames []string
+	for _, container := range containers {
+		names = append(names, container.Name)
+		serviceNames = append(serviceNames, container.serviceName)
+	}
+	require.Equal(t, []string{"db-1", "worker-1", "worker-2"}, names)
+	require.Equal(t, []string{"db", "worker-1", "worker-2"}, serviceNames)
+
+	// the services are attached to the network of the workflow
+	project := manager.composeProjects["primary"]
+	require.Equal(t, ComposeOptions{
+		Project: "bitrise-build-id-primary",
+		Files:   []string{filepath.Join(sourceDir, "ci", "docker-compose.yml"), filepath.Join(project.overrideDir, "network.yml")},
+	}, project.options)
+	override, err := os.ReadFile(project.options.Files[1])
+	require.NoError(t, err)
+	require.Contains(t, string(override), "name: bitrise-build-id-primary\n    external: true")
+	require.Equal(t, "network bitrise-build-id-primary", runtime.Commands[0])
+
+	require.NoError(t, manager.StopComposeServices("primary"))
+	require.Contains(t, runtime.Commands, "compose-down bitrise-build-id-primary")
+	require.NoDirExists(t, project.overrideDir)
+	require.Empty(t, runtime.Containers)
+	require.NoError(t, manager.StopComposeServices("primary"))
+}
+
+func TestContainerManager_UnhealthyComposeService(t *testing.T) {
+	runtime := NewFakeRuntime()
+	runtime.ComposeServices["db"] = []string{"db-1"}
+	runtime.Health["db-1"] = "unhealthy"
+	manager := newTestContainerManager(runtime)
+
+	_, err := manager.StartComposeServices("/src/docker-compose.yml", "Deploy.iOS", nil)
+	require.EqualError(t, err, "container health check: container (db-1) is unhealthy")
+	require.Contains(t, runtime.Commands, "logs db-1")
+
+	// the project name is a valid compose project name
+	require.Equal(t, "bitrise-build-id-deploy_ios", manager.composeProjects["Deploy.iOS"].options.Project)
+
+	require.NoError(t, manager.DestroyAllContainers())
+	require.Contains(t, runtime.Commands, "compose-down bitrise-build-id-deploy_ios")
+	require.Empty(t, runtime.Containers)
+	require.Empty(t, runtime.Networks)
+}
+
+func TestComposeArgs(t *testing.T) {
+	require.Equal(t, []string{
+		"compose", "--project-name", "bitrise-build-id-primary", "--file", "/src/docker-compose.yml", "--file", "/tmp/network.yml", "up", "--detach",
+	}, composeArgs(ComposeOptions{
+		Project: "bitrise-build-id-primary",
+		Files:   []string{"/src/docker-compose.yml", "/tmp/network.yml"},
+	}, "up", "--detach"))
+}
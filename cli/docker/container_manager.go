@@ -2,7 +2,6 @@ package docker
 
 import (
 	"bytes"
-	"context"
 	"fmt"
 	"io"
 	"os"
@@ -13,53 +12,71 @@ import (
 
 	"github.com/bitrise-io/bitrise/log"
 	"github.com/bitrise-io/bitrise/models"
-	"github.com/bitrise-io/go-utils/command"
 	"github.com/bitrise-io/go-utils/v2/redactwriter"
-	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/api/types/filters"
-	"github.com/docker/docker/client"
 )
 
 type RunningContainer struct {
-	ID   string
-	Name string
+	ID    string
+	Name  string
+	Image string
+	// Platform is the platform of the image (<os>/<arch>[/<variant>]), the steps run in the container are prepared for it
+	Platform string
+	Runtime  ContainerRuntime
+
+	// serviceName is the declared name of a service container, the host name of the container in its network.
+	serviceName string
+	// buildDirs are the mounted build dirs, whose ownership is restored to buildDirsOwner (the host user)
+	// before the container is removed, if the container doesn't run as the host user
+	buildDirs      []string
+	buildDirsOwner string
 }
 
 type containerCreateOptions struct {
-	name       string
-	volumes    []string
-	command    string
-	workingDir string
-	user       string
+	name           string
+	network        string
+	networkAliases []string
+	volumes        []string
+	command        string
+	workingDir     string
+	user           string
+	envs           []string
+	buildDirs      []string
+	buildDirsOwner string
 }
 
 func (rc *RunningContainer) Destroy() error {
-	_, err := command.New("docker", "rm", "--force", "--volumes", rc.Name).RunAndReturnTrimmedCombinedOutput()
-	if err != nil {
+	var restoreErr error
+	if len(rc.buildDirs) > 0 && rc.ID != "" {
+		restoreErr = rc.restoreBuildDirsOwnership()
+	}
+
+	// The container is removed even if the ownership couldn't be restored
+	if err := rc.Runtime.Remove(rc.Name); err != nil {
 		return fmt.Errorf("remove docker container: %w", err)
 	}
+	if restoreErr != nil {
+		return fmt.Errorf("restore the ownership of the build dirs: %w", restoreErr)
+	}
 	return nil
 }
 
-func (rc *RunningContainer) ExecuteCommandArgs(envs []string) []string {
-	args := []string{"exec"}
-
-	for _, env := range envs {
-		args = append(args, "-e", env)
-	}
-
-	args = append(args, rc.Name)
-
-	return args
+// ExecuteCommand returns the command (the runtime's CLI and its exec args) to run a command in the container,
+// the command to run is expected to be appended to the returned args.
+func (rc *RunningContainer) ExecuteCommand(envs []string) (string, []string) {
+	return rc.Runtime.Binary(), rc.Runtime.ExecArgs(rc.Name, envs)
 }
 
-const bitriseNetwork = "bitrise"
-
+// ContainerManager runs the containers of a build. The networks and container names are namespaced with the build execution ID,
+// so builds (and the workflows of a pipeline) running on the same host don't collide.
 type ContainerManager struct {
 	logger             DockerLogger
+	buildExecutionID   string
 	workflowContainers map[string]*RunningContainer
 	serviceContainers  map[string][]*RunningContainer
-	client             *client.Client
+	stepContainers     map[string]*RunningContainer
+	composeProjects    map[string]composeProject
+	networks           map[string]bool
+	runtime            ContainerRuntime
 
 	mu       sync.Mutex
 	released bool
@@ -105,41 +122,53 @@ func (dl *DockerLogger) Redact(s string) (string, error) {
 	return redactedValue, nil
 }
 
-func NewContainerManager(logger log.Logger, secrets []string) *ContainerManager {
-	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-	if err != nil {
-		logger.Warnf("Docker client failed to initialize (possibly running on unsupported environment): %s", err)
+// NewDockerLogger returns a logger which redacts the given secrets from the logged messages.
+func NewDockerLogger(logger log.Logger, secrets []string) DockerLogger {
+	return DockerLogger{
+		logger:  logger,
+		secrets: secrets,
 	}
+}
 
+func NewContainerManager(runtime ContainerRuntime, logger DockerLogger, buildExecutionID string) *ContainerManager {
 	return &ContainerManager{
-		logger: DockerLogger{
-			logger:  logger,
-			secrets: secrets,
-		},
+		logger:             logger,
+		buildExecutionID:   buildExecutionID,
 		workflowContainers: make(map[string]*RunningContainer),
 		serviceContainers:  make(map[string][]*RunningContainer),
-		client:             dockerClient,
+		stepContainers:     make(map[string]*RunningContainer),
+		composeProjects:    make(map[string]composeProject),
+		networks:           make(map[string]bool),
+		runtime:            runtime,
 	}
 }
 
+// networkName returns the network of the workflow, shared by the workflow, step and service containers of the workflow.
+func (cm *ContainerManager) networkName(workflowID string) string {
+	return fmt.Sprintf("bitrise-%s-%s", cm.buildExecutionID, workflowID)
+}
+
+// Login authenticates to the registry of the container, with its credential helper or its username and password.
+// Without credentials the auths and credential helpers of the user's docker config are used.
 func (cm *ContainerManager) Login(container models.Container, envs map[string]string) error {
+	if helper := container.Credentials.CredentialHelper; helper != "" {
+		cm.logger.Infof("ℹ️ Using docker credential helper (%s) for registry: %s", helper, container.Credentials.Server)
+		if err := cm.runtime.SetCredentialHelper(container.Credentials.Server, helper); err != nil {
+			return fmt.Errorf("set docker credential helper: %w", err)
+		}
+		return nil
+	}
+
 	if container.Credentials.Username != "" && container.Credentials.Password != "" {
 		cm.logger.Infof("ℹ️ Logging into docker registry: %s", container.Image)
 
 		resolvedPassword := resolveEnvVariable(container.Credentials.Password, envs)
-		args := []string{"login", "--username", container.Credentials.Username, "--password", resolvedPassword}
-
-		if container.Credentials.Server != "" {
-			args = append(args, container.Credentials.Server)
-		} else {
-			args = append(args, container.Image)
+		server := container.Credentials.Server
+		if server == "" {
+			server = container.Image
 		}
 
-		cm.logger.Infof("ℹ️ Running command: docker %s", strings.Join(args, " "))
-
-		out, err := command.New("docker", args...).RunAndReturnTrimmedCombinedOutput()
-		if err != nil {
-			cm.logger.Errorf(out)
+		if err := cm.runtime.Login(server, container.Credentials.Username, resolvedPassword); err != nil {
 			return fmt.Errorf("run docker login: %w", err)
 		}
 	}
@@ -153,18 +182,14 @@ func (cm *ContainerManager) StartWorkflowContainer(
 ) (*RunningContainer, error) {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
-	containerName := fmt.Sprintf("bitrise-workflow-%s", workflowID)
+	containerName := fmt.Sprintf("bitrise-workflow-%s-%s", cm.buildExecutionID, workflowID)
 
-	// TODO: handle default mounts if BITRISE_DOCKER_MOUNT_OVERRIDES is not provided
-	dockerMountOverrides := strings.Split(os.Getenv("BITRISE_DOCKER_MOUNT_OVERRIDES"), ",")
+	options, err := cm.buildContainerOptions(container, containerName, workflowID, envs)
+	if err != nil {
+		return nil, fmt.Errorf("start workflow container: %w", err)
+	}
 
-	runningContainer, err := cm.runContainer(container, containerCreateOptions{
-		name:       containerName,
-		volumes:    dockerMountOverrides,
-		command:    "sleep infinity",
-		workingDir: "/bitrise/src",
-		user:       "root",
-	}, envs)
+	runningContainer, err := cm.runContainer(container, options, envs)
 
 	// Even on failure we save the reference to make sure containers will be cleaned up
 	if runningContainer != nil {
@@ -175,13 +200,100 @@ func (cm *ContainerManager) StartWorkflowContainer(
 		return runningContainer, fmt.Errorf("start workflow container: %w", err)
 	}
 
-	if err := cm.healthCheckContainer(context.Background(), runningContainer); err != nil {
+	if err := cm.healthCheckContainer(runningContainer); err != nil {
+		return runningContainer, fmt.Errorf("container health check: %w", err)
+	}
+
+	return runningContainer, nil
+}
+
+// StartStepContainer starts a container for a single step run, the step is run in it with docker exec.
+// The volumes are the same as the workflow container's, so the work dir of the run (holding the envstores and the step sources)
+// is mounted too, and the step reads its inputs from and writes its outputs to the envstores the same way as on the host.
+func (cm *ContainerManager) StartStepContainer(
+	container models.Container,
+	workflowID string,
+	stepExecutionID string,
+	envs map[string]string,
+) (*RunningContainer, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	containerName := fmt.Sprintf("bitrise-step-%s", stepExecutionID)
+
+	options, err := cm.buildContainerOptions(container, containerName, workflowID, envs)
+	if err != nil {
+		return nil, fmt.Errorf("start step container: %w", err)
+	}
+
+	runningContainer, err := cm.runContainer(container, options, envs)
+
+	// Even on failure we save the reference to make sure containers will be cleaned up
+	if runningContainer != nil {
+		cm.stepContainers[stepExecutionID] = runningContainer
+	}
+
+	if err != nil {
+		return runningContainer, fmt.Errorf("start step container: %w", err)
+	}
+
+	if err := cm.healthCheckContainer(runningContainer); err != nil {
 		return runningContainer, fmt.Errorf("container health check: %w", err)
 	}
 
 	return runningContainer, nil
 }
 
+// buildContainerOptions returns the create options of a workflow or step container.
+// If the container doesn't run as the host user, the files it creates in the mounted build dirs are not owned by the host user,
+// so the ownership of the build dirs is restored when the container is removed.
+func (cm *ContainerManager) buildContainerOptions(
+	container models.Container,
+	containerName string,
+	workflowID string,
+	envs map[string]string,
+) (containerCreateOptions, error) {
+	volumes, workingDir := containerVolumes(container, envs)
+	hostUser := cm.runtime.HostUser()
+	user := buildContainerUser(container, hostUser)
+
+	userEnvs, err := userEnvs(container, user, volumes)
+	if err != nil {
+		return containerCreateOptions{}, err
+	}
+
+	options := containerCreateOptions{
+		name:       containerName,
+		network:    cm.networkName(workflowID),
+		volumes:    volumes,
+		command:    "sleep infinity",
+		workingDir: workingDir,
+		user:       user,
+		envs:       userEnvs,
+	}
+
+	// only the default build dirs are handed back to the host user, the volumes of the mount overrides and the container's own volumes
+	// are left as they are
+	if !isSameUser(user, hostUser) && os.Getenv(mountOverridesEnvKey) == "" {
+		options.buildDirs = defaultBuildDirs(envs)
+		options.buildDirsOwner = hostUser
+	}
+
+	return options, nil
+}
+
+// DestroyStepContainer removes the container of a finished step run.
+func (cm *ContainerManager) DestroyStepContainer(stepExecutionID string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	container, ok := cm.stepContainers[stepExecutionID]
+	if !ok {
+		return nil
+	}
+	delete(cm.stepContainers, stepExecutionID)
+
+	return container.Destroy()
+}
+
 func (cm *ContainerManager) StartServiceContainers(
 	services map[string]models.Container,
 	workflowID string,
@@ -191,13 +303,30 @@ func (cm *ContainerManager) StartServiceContainers(
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 	failedServices := make(map[string]error)
-	for serviceName := range services {
-		// Naming the container other than the service name, can cause issues with network calls
+	runningServices := make(map[string]*RunningContainer)
+	for _, serviceName := range sortedServiceNames(services) {
+		if err := cm.Login(services[serviceName], envs); err != nil {
+			cm.logger.Warnf("Service (%s) has docker credentials provided, but the authentication failed: %s", serviceName, err)
+		}
+
+		// Services run as the image's user by default
+		user := services[serviceName].User
+		if services[serviceName].MapHostUser {
+			user = cm.runtime.HostUser()
+		}
+
+		// The service is reachable by its declared name through a network alias, the build dirs are not mounted, only its own volumes
 		runningContainer, err := cm.runContainer(services[serviceName], containerCreateOptions{
-			name: serviceName,
+			name:           fmt.Sprintf("bitrise-service-%s-%s-%s", cm.buildExecutionID, workflowID, serviceName),
+			network:        cm.networkName(workflowID),
+			networkAliases: []string{serviceName},
+			volumes:        ownVolumes(services[serviceName], envs),
+			user:           user,
 		}, envs)
 		if runningContainer != nil {
+			runningContainer.serviceName = serviceName
 			containers = append(containers, runningContainer)
+			runningServices[serviceName] = runningContainer
 		}
 		if err != nil {
 			failedServices[serviceName] = err
@@ -215,10 +344,20 @@ func (cm *ContainerManager) StartServiceContainers(
 		return containers, errServices
 	}
 
-	for _, container := range containers {
-		if err := cm.healthCheckContainer(context.Background(), container); err != nil {
+	// the steps should not start until every service is healthy and passes its readiness probes
+	for _, serviceName := range sortedServiceNames(services) {
+		container := runningServices[serviceName]
+		if err := cm.healthCheckContainer(container); err != nil {
+			cm.printServiceLogs(serviceName, container)
 			return containers, fmt.Errorf("container health check: %w", err)
 		}
+
+		if readiness := services[serviceName].Readiness; readiness != nil {
+			if err := cm.waitForReadiness(serviceName, container, *readiness); err != nil {
+				cm.printServiceLogs(serviceName, container)
+				return containers, fmt.Errorf("service readiness: %w", err)
+			}
+		}
 	}
 
 	return containers, nil
@@ -243,7 +382,17 @@ func (cm *ContainerManager) DestroyAllContainers() error {
 		}
 	}
 
-	for _, containers := range cm.serviceContainers {
+	for _, container := range cm.stepContainers {
+		cm.logger.Infof("ℹ️ Removing step container: %s", container.Name)
+		if err := container.Destroy(); err != nil {
+			return fmt.Errorf("destroy step container: %w", err)
+		}
+	}
+
+	for workflowID, containers := range cm.serviceContainers {
+		if err := cm.saveServiceLogs(workflowID, containers, nil); err != nil {
+			cm.logger.Warnf("Failed to save service logs: %s", err)
+		}
 		for _, container := range containers {
 			if container == nil {
 				continue
@@ -255,16 +404,47 @@ func (cm *ContainerManager) DestroyAllContainers() error {
 		}
 	}
 
+	for workflowID, project := range cm.composeProjects {
+		cm.logger.Infof("ℹ️ Removing compose services: %s", project.options.Project)
+		if err := cm.composeDown(project); err != nil {
+			return fmt.Errorf("destroy compose services: %w", err)
+		}
+		delete(cm.composeProjects, workflowID)
+	}
+
+	for network := range cm.networks {
+		cm.logger.Infof("ℹ️ Removing network: %s", network)
+		if err := cm.runtime.RemoveNetwork(network); err != nil {
+			return fmt.Errorf("remove network: %w", err)
+		}
+		delete(cm.networks, network)
+	}
+
+	return nil
+}
+
+// RemoveRegistryConfig removes the temporary registry config with the registry logins of the build,
+// it should be called at the end of the build.
+func (cm *ContainerManager) RemoveRegistryConfig() error {
+	return cm.runtime.RemoveRegistryConfig()
+}
+
+// RemoveWorkflowNetwork removes the network of the workflow, once its containers are removed.
+func (cm *ContainerManager) RemoveWorkflowNetwork(workflowID string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	network := cm.networkName(workflowID)
+	if !cm.networks[network] {
+		return nil
+	}
+
+	if err := cm.runtime.RemoveNetwork(network); err != nil {
+		return fmt.Errorf("remove network (%s): %w", network, err)
+	}
+	delete(cm.networks, network)
 	return nil
 }
 
-// We are not using the docker sdk for pull, start and create commands because:
-//   - We want to make sure the end user can easily debug using the same docker command we issue
-//     (hard to convert between sdk and cli api)
-//   - We'd like to support options generically,
-//     with the SDK we would need to parse the string ourselves to convert them properly to their own type
-//   - SDK differs from the CLI in some cases, for example pulling from private registry requires the exact token
-//     it can't automatically use the docker config
 func (cm *ContainerManager) runContainer(
 	container models.Container,
 	options containerCreateOptions,
@@ -274,19 +454,27 @@ func (cm *ContainerManager) runContainer(
 		return nil, fmt.Errorf("container manager was released already")
 	}
 
-	if err := cm.ensureNetwork(); err != nil {
+	if err := cm.runtime.EnsureNetwork(options.network); err != nil {
 		return nil, fmt.Errorf("ensure bitrise docker network: %w", err)
 	}
+	cm.networks[options.network] = true
 
-	cm.logger.Infof("ℹ️ Pulling docker image: %s", container.Image)
-	err := cm.pullImageWithRetry(container)
-	if err != nil {
-		return nil, fmt.Errorf("pull docker image: %w", err)
+	if container.Build != nil {
+		image, err := cm.buildImage(container, envs)
+		if err != nil {
+			return nil, fmt.Errorf("build docker image: %w", err)
+		}
+		container.Image = image
+	} else {
+		cm.logger.Infof("ℹ️ Pulling docker image: %s", container.Image)
+		if err := cm.pullImageWithRetry(container); err != nil {
+			return nil, fmt.Errorf("pull docker image: %w", err)
+		}
+		cm.logger.Infof("✅ Docker image pulled: %s", container.Image)
 	}
-	cm.logger.Infof("✅ Docker image pulled: %s", container.Image)
 
 	cm.logger.Infof("ℹ️ Creating docker container: %s", container.Image)
-	err = cm.createContainer(container, options, envs)
+	err := cm.createContainer(container, options, envs)
 	if err != nil {
 		return nil, fmt.Errorf("create docker container: %w", err)
 	}
@@ -294,6 +482,8 @@ func (cm *ContainerManager) runContainer(
 
 	cm.logger.Infof("ℹ️ Starting docker container: %s", container.Image)
 	runningContainer, err := cm.startContainer(options)
+	runningContainer.Image = container.Image
+	runningContainer.Platform = container.GetPlatform()
 	if err != nil {
 		return runningContainer, fmt.Errorf("start docker container: %w", err)
 	}
@@ -306,22 +496,20 @@ func (cm *ContainerManager) startContainer(options containerCreateOptions) (*Run
 	// At this point the container has been created, but it's not running yet
 	// Even if we can't start it we need to return the container reference to make sure it will be cleaned up
 	runningContainer := &RunningContainer{
-		Name: options.name,
+		Name:           options.name,
+		Runtime:        cm.runtime,
+		buildDirs:      options.buildDirs,
+		buildDirsOwner: options.buildDirsOwner,
 	}
 
-	cm.logger.Infof("ℹ️ Running command: docker start %s", options.name)
-	out, err := command.New("docker", "start", options.name).RunAndReturnTrimmedCombinedOutput()
-	if err != nil {
-		cm.logger.Errorf(out)
+	if err := cm.runtime.Start(options.name); err != nil {
 		return runningContainer, fmt.Errorf("start docker container (%s): %w", options.name, err)
 	}
 
 	// We need to get the container ID to be able to check the health
 	// This also serves as a validation that the container is running
-	result, err := cm.getRunningContainer(context.Background(), options.name)
-	if result != nil {
-		runningContainer.ID = result.ID
-	}
+	state, err := cm.getRunningContainer(options.name)
+	runningContainer.ID = state.ID
 	if err != nil {
 		return runningContainer, fmt.Errorf("container (%s) unable to start properly: %w", options.name, err)
 	}
@@ -333,33 +521,25 @@ func (cm *ContainerManager) createContainer(
 	options containerCreateOptions,
 	envs map[string]string,
 ) error {
-	dockerRunArgs := []string{"create",
-		"--platform", "linux/amd64",
-		fmt.Sprintf("--network=%s", bitriseNetwork),
-	}
-
-	for _, o := range options.volumes {
-		dockerRunArgs = append(dockerRunArgs, "-v", o)
+	createOptions := CreateOptions{
+		Name:           options.name,
+		Image:          container.Image,
+		Platform:       container.GetPlatform(),
+		Network:        options.network,
+		NetworkAliases: options.networkAliases,
+		Volumes:        options.volumes,
+		Ports:          container.Ports,
+		WorkingDir:     options.workingDir,
+		User:           options.user,
 	}
 
 	for _, env := range container.Envs {
 		for name, value := range env {
 			resolvedValue := resolveEnvVariable(fmt.Sprintf("%s", value), envs)
-			dockerRunArgs = append(dockerRunArgs, "-e", fmt.Sprintf("%s=%s", name, resolvedValue))
+			createOptions.Envs = append(createOptions.Envs, fmt.Sprintf("%s=%s", name, resolvedValue))
 		}
 	}
-
-	for _, port := range container.Ports {
-		dockerRunArgs = append(dockerRunArgs, "-p", port)
-	}
-
-	if options.workingDir != "" {
-		dockerRunArgs = append(dockerRunArgs, "-w", options.workingDir)
-	}
-
-	if options.user != "" {
-		dockerRunArgs = append(dockerRunArgs, "-u", options.user)
-	}
+	createOptions.Envs = append(createOptions.Envs, options.envs...)
 
 	if container.Options != "" {
 		// This regex splits the string by spaces, but keeps quoted strings together
@@ -368,29 +548,16 @@ func (cm *ContainerManager) createContainer(
 		result := r.FindAllString(container.Options, -1)
 
 		// Remove quotes from the strings
-		var options []string
 		for _, result := range result {
-			options = append(options, strings.ReplaceAll(result, "\"", ""))
+			createOptions.Options = append(createOptions.Options, strings.ReplaceAll(result, "\"", ""))
 		}
-
-		dockerRunArgs = append(dockerRunArgs, options...)
 	}
 
-	dockerRunArgs = append(dockerRunArgs,
-		fmt.Sprintf("--name=%s", options.name),
-		container.Image,
-	)
-
 	if options.command != "" {
-		commandArgsList := strings.Split(options.command, " ")
-		dockerRunArgs = append(dockerRunArgs, commandArgsList...)
+		createOptions.Command = strings.Split(options.command, " ")
 	}
 
-	cm.logger.Infof("ℹ️ Running command: docker %s", strings.Join(dockerRunArgs, " "))
-
-	out, err := command.New("docker", dockerRunArgs...).RunAndReturnTrimmedCombinedOutput()
-	if err != nil {
-		cm.logger.Errorf(out)
+	if err := cm.runtime.Create(createOptions); err != nil {
 		return fmt.Errorf("create container (%s): %w", options.name, err)
 	}
 
@@ -398,6 +565,11 @@ func (cm *ContainerManager) createContainer(
 }
 
 func (cm *ContainerManager) pullImageWithRetry(container models.Container) error {
+	pull, err := cm.shouldPullImage(container)
+	if err != nil || !pull {
+		return err
+	}
+
 	pulling := true
 	defer func() {
 		pulling = false
@@ -414,11 +586,11 @@ func (cm *ContainerManager) pullImageWithRetry(container models.Container) error
 	}()
 
 	// In case of pull error we retry 3 times
-	var err error
 	retries := 0
 	for retries < 3 {
-		err = cm.pullImage(container)
+		err = cm.runtime.Pull(container.Image, container.GetPlatform())
 		if err != nil {
+			err = fmt.Errorf("pull container (%s): %w", container.Image, err)
 			cm.logger.Warnf("❌ Error during image pull: %s", err.Error())
 			cm.logger.Warnf("⏳ Failed to pull image, retrying (retry %d/3) ... ", retries+1)
 		} else {
@@ -429,73 +601,100 @@ func (cm *ContainerManager) pullImageWithRetry(container models.Container) error
 	return err
 }
 
-func (cm *ContainerManager) pullImage(container models.Container) error {
-	images, err := cm.client.ImageList(context.Background(), types.ImageListOptions{
-		Filters: filters.NewArgs(filters.Arg("reference", container.Image)),
-	})
+// shouldPullImage decides whether the image should be pulled, based on the pull policy of the container and the local image.
+func (cm *ContainerManager) shouldPullImage(container models.Container) (bool, error) {
+	policy := container.GetPullPolicy()
+	if policy == models.PullPolicyAlways {
+		return true, nil
+	}
+
+	image, err := cm.runtime.InspectImage(container.Image)
 	if err != nil {
+		if policy == models.PullPolicyNever {
+			return false, fmt.Errorf("inspect local image (%s): %w", container.Image, err)
+		}
 		cm.logger.Warnf("Failed to check whether local image exist already, pulling...: %s", err.Error())
-	} else if len(images) > 0 {
+		return true, nil
+	}
+
+	mismatch := localImageMismatch(container, image)
+	if mismatch == "" {
 		cm.logger.Infof("ℹ️ Image (%s) already exists locally", container.Image)
-		return nil
+		return false, nil
 	}
 
-	dockerRunArgs := []string{"pull", "--platform", "linux/amd64", container.Image}
-	cm.logger.Infof("ℹ️ Running command: docker %s", strings.Join(dockerRunArgs, " "))
-	out, err := command.New("docker", dockerRunArgs...).RunAndReturnTrimmedCombinedOutput()
-	if err != nil {
-		cm.logger.Errorf(out)
-		return fmt.Errorf("pull container (%s): %w", container.Image, err)
+	if policy == models.PullPolicyNever {
+		return false, fmt.Errorf("image (%s) %s and the pull policy is %s", container.Image, mismatch, models.PullPolicyNever)
 	}
-	return nil
+	cm.logger.Infof("ℹ️ Image (%s) %s, pulling...", container.Image, mismatch)
+	return true, nil
 }
 
-func (cm *ContainerManager) getRunningContainer(ctx context.Context, name string) (*types.Container, error) {
-	containers, err := cm.client.ContainerList(ctx, types.ContainerListOptions{
-		Filters: filters.NewArgs(filters.Arg("name", name)),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("list containers: %w", err)
+// localImageMismatch returns why the local image can't be used for the container, empty if it can be used.
+func localImageMismatch(container models.Container, image *ImageInfo) string {
+	if image == nil {
+		return "is not present locally"
 	}
 
-	if len(containers) != 1 {
-		return nil, fmt.Errorf("multiple containers with the same name found: %s", name)
+	if digest := container.ImageDigest(); digest != "" {
+		matches := false
+		for _, repoDigest := range image.RepoDigests {
+			if strings.HasSuffix(repoDigest, "@"+digest) {
+				matches = true
+				break
+			}
+		}
+		if !matches {
+			return "is present locally with a different digest"
+		}
 	}
 
-	if containers[0].State != "running" {
-		logs, err := cm.client.ContainerLogs(ctx, containers[0].ID, types.ContainerLogsOptions{
-			ShowStdout: true,
-			ShowStderr: true,
-		})
-		if err != nil {
-			return &containers[0], fmt.Errorf("container is not running: failed to get container logs: %w", err)
+	// the platform of the local image might be unknown, the variant is only compared if both define it
+	if image.OS != "" && image.Architecture != "" {
+		platform := strings.SplitN(container.GetPlatform(), "/", 3)
+		localPlatform := image.OS + "/" + image.Architecture
+		if platform[0] != image.OS || platform[1] != image.Architecture ||
+			(len(platform) == 3 && image.Variant != "" && platform[2] != image.Variant) {
+			if image.Variant != "" {
+				localPlatform += "/" + image.Variant
+			}
+			return fmt.Sprintf("is present locally for a different platform (%s)", localPlatform)
 		}
+	}
+	return ""
+}
 
-		content, err := io.ReadAll(logs)
+func (cm *ContainerManager) getRunningContainer(name string) (ContainerState, error) {
+	state, err := cm.runtime.Inspect(name)
+	if err != nil {
+		return state, fmt.Errorf("inspect container: %w", err)
+	}
+
+	if state.Status != "running" {
+		logs, err := cm.runtime.Logs(name)
 		if err != nil {
-			return &containers[0], fmt.Errorf("container is not running: failed to read container logs: %w", err)
+			return state, fmt.Errorf("container is not running: failed to get container logs: %w", err)
 		}
-		cm.logger.Errorf("Failed container (%s) logs:\n %s\n", name, string(content))
-		return &containers[0], fmt.Errorf("container (%s) is not running", name)
+		cm.logger.Errorf("Failed container (%s) logs:\n %s\n", name, logs)
+		return state, fmt.Errorf("container (%s) is not running", name)
 	}
-	return &containers[0], nil
-
+	return state, nil
 }
 
-func (cm *ContainerManager) healthCheckContainer(ctx context.Context, container *RunningContainer) error {
-	inspect, err := cm.client.ContainerInspect(ctx, container.ID)
+func (cm *ContainerManager) healthCheckContainer(container *RunningContainer) error {
+	state, err := cm.runtime.Inspect(container.Name)
 	if err != nil {
 		return fmt.Errorf("inspect container (%s): %w", container.Name, err)
 	}
 
-	if inspect.State.Health == nil {
+	if state.Health == "" {
 		cm.logger.Infof("✅ No healthcheck is defined for container (%s), assuming healthy...", container.Name)
 		return nil
 	}
 
 	retries := 0
-	for inspect.State.Health.Status != "healthy" {
-		if inspect.State.Health.Status == "unhealthy" {
+	for state.Health != "healthy" {
+		if state.Health == "unhealthy" {
 			cm.logger.Errorf("❌ Container (%s) is unhealthy...", container.Name)
 			return fmt.Errorf("container (%s) is unhealthy", container.Name)
 		}
@@ -507,7 +706,7 @@ func (cm *ContainerManager) healthCheckContainer(ctx context.Context, container
 		time.Sleep(time.Duration(sleep) * time.Second)
 
 		cm.logger.Infof("⏳ Waiting for container (%s) to be healthy... (retry: %ds)", container.Name, sleep)
-		inspect, err = cm.client.ContainerInspect(context.Background(), container.ID)
+		state, err = cm.runtime.Inspect(container.Name)
 		if err != nil {
 			return fmt.Errorf("inspect container (%s): %w", container.Name, err)
 		}
@@ -519,25 +718,6 @@ func (cm *ContainerManager) healthCheckContainer(ctx context.Context, container
 	return nil
 }
 
-func (cm *ContainerManager) ensureNetwork() error {
-	networks, err := cm.client.NetworkList(context.Background(), types.NetworkListOptions{
-		Filters: filters.NewArgs(filters.Arg("name", bitriseNetwork)),
-	})
-	if err != nil {
-		return fmt.Errorf("list networks: %w", err)
-	}
-
-	if len(networks) > 0 {
-		return nil
-	}
-
-	if _, err := cm.client.NetworkCreate(context.Background(), bitriseNetwork, types.NetworkCreate{}); err != nil {
-		return fmt.Errorf("create network: %w", err)
-	}
-
-	return nil
-}
-
 func resolveEnvVariable(value string, envs map[string]string) string {
 	if strings.HasPrefix(value, "$") {
 		if value, ok := envs[strings.TrimPrefix(value, "$")]; ok {
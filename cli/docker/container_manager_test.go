@@ -0,0 +1,239 @@
+package docker
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/log"
+	"github.com/bitrise-io/bitrise/models"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestContainerManager(runtime ContainerRuntime) *ContainerManager {
+	return NewContainerManager(runtime, NewDockerLogger(log.NewLogger(log.GetGlobalLoggerOpts()), []string{"secret-password"}), "build-id")
+}
+
+func TestContainerManager_StartWorkflowContainer(t *testing.T) {
+	t.Setenv(mountOverridesEnvKey, "/src:/bitrise/src")
+
+	runtime := NewFakeRuntime()
+	runtime.Images["postgres:16"] = &ImageInfo{OS: "linux", Architecture: "amd64"}
+	manager := newTestContainerManager(runtime)
+
+	container := models.Container{
+		Image: "ubuntu:22.04",
+		Envs:  []envmanModels.EnvironmentItemModel{{"TOKEN": "$API_TOKEN"}},
+		Ports: []string{"8080:8080"},
+		Credentials: models.DockerCredentials{
+			Username: "bitrise",
+			Password: "$REGISTRY_PASSWORD",
+		},
+		Options: `--cpus 2 --health-cmd "curl localhost"`,
+	}
+	envs := map[string]string{"API_TOKEN": "token", "REGISTRY_PASSWORD": "secret-password"}
+
+	require.NoError(t, manager.Login(container, envs))
+	workflowContainer, err := manager.StartWorkflowContainer(container, "primary", envs)
+	require.NoError(t, err)
+	// the ownership of the mount overrides is not restored
+	require.Equal(t, &RunningContainer{
+		ID:       "id-bitrise-workflow-build-id-primary",
+		Name:     "bitrise-workflow-build-id-primary",
+		Image:    "ubuntu:22.04",
+		Platform: "linux/amd64",
+		Runtime:  runtime,
+	}, workflowContainer)
+	require.Equal(t, workflowContainer, manager.GetWorkflowContainer("primary"))
+
+	_, err = manager.StartServiceContainers(map[string]models.Container{"postgres": {Image: "postgres:16", Volumes: []string{"$API_TOKEN-data:/var/lib/postgresql/data"}}}, "primary", envs)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"login bitrise ubuntu:22.04",
+		"network bitrise-build-id-primary",
+		"image-inspect ubuntu:22.04",
+		"pull --platform linux/amd64 ubuntu:22.04",
+		"create bitrise-workflow-build-id-primary ubuntu:22.04",
+		"start bitrise-workflow-build-id-primary",
+		"inspect bitrise-workflow-build-id-primary",
+		"inspect bitrise-workflow-build-id-primary",
+		"network bitrise-build-id-primary",
+		"image-inspect postgres:16",
+		"create bitrise-service-build-id-primary-postgres postgres:16",
+		"start bitrise-service-build-id-primary-postgres",
+		"inspect bitrise-service-build-id-primary-postgres",
+		"inspect bitrise-service-build-id-primary-postgres",
+	}, runtime.Commands)
+
+	require.Equal(t, CreateOptions{
+		Name:       "bitrise-workflow-build-id-primary",
+		Image:      "ubuntu:22.04",
+		Platform:   "linux/amd64",
+		Network:    "bitrise-build-id-primary",
+		Volumes:    []string{"/src:/bitrise/src"},
+		Envs:       []string{"TOKEN=token"},
+		Ports:      []string{"8080:8080"},
+		WorkingDir: "/bitrise/src",
+		User:       "root",
+		Options:    []string{"--cpus", "2", "--health-cmd", "curl localhost"},
+		Command:    []string{"sleep", "infinity"},
+	}, runtime.Created[0])
+
+	// the service is reachable by its name in the network of the workflow
+	require.Equal(t, "bitrise-build-id-primary", runtime.Created[1].Network)
+	require.Equal(t, []string{"postgres"}, runtime.Created[1].NetworkAliases)
+	// only the service's own volumes are mounted
+	require.Equal(t, []string{"token-data:/var/lib/postgresql/data"}, runtime.Created[1].Volumes)
+
+	require.NoError(t, manager.DestroyAllContainers())
+	require.Empty(t, runtime.Containers)
+	require.Empty(t, runtime.Networks)
+
+	_, err = manager.StartWorkflowContainer(container, "deploy", envs)
+	require.EqualError(t, err, "start workflow container: container manager was released already")
+}
+
+func TestContainerManager_Login(t *testing.T) {
+	runtime := NewFakeRuntime()
+	manager := newTestContainerManager(runtime)
+
+	services := map[string]models.Container{
+		"api": {
+			Image:       "ghcr.io/bitrise/api",
+			Credentials: models.DockerCredentials{Server: "ghcr.io", Username: "bitrise", Password: "$GHCR_TOKEN"},
+		},
+		"worker": {
+			Image:       "123.dkr.ecr.us-east-1.amazonaws.com/worker",
+			Credentials: models.DockerCredentials{Server: "123.dkr.ecr.us-east-1.amazonaws.com", CredentialHelper: "ecr-login"},
+		},
+		"redis": {Image: "redis"},
+	}
+	_, err := manager.StartServiceContainers(services, "primary", map[string]string{"GHCR_TOKEN": "token"})
+	require.NoError(t, err)
+
+	require.Contains(t, runtime.Commands, "login bitrise ghcr.io")
+	require.Contains(t, runtime.Commands, "credential-helper ecr-login 123.dkr.ecr.us-east-1.amazonaws.com")
+	require.Len(t, filterCommands(runtime.Commands, "login"), 1)
+
+	require.NoError(t, manager.RemoveRegistryConfig())
+	require.Contains(t, runtime.Commands, "registry-config-rm")
+}
+
+func filterCommands(commands []string, name string) []string {
+	var filtered []string
+	for _, command := range commands {
+		if strings.HasPrefix(command, name+" ") {
+			filtered = append(filtered, command)
+		}
+	}
+	return filtered
+}
+
+func TestContainerManager_UnhealthyServiceContainer(t *testing.T) {
+	runtime := NewFakeRuntime()
+	runtime.Health["bitrise-service-build-id-primary-redis"] = "unhealthy"
+	manager := newTestContainerManager(runtime)
+
+	containers, err := manager.StartServiceContainers(map[string]models.Container{"redis": {Image: "redis"}}, "primary", nil)
+	require.EqualError(t, err, "container health check: container (bitrise-service-build-id-primary-redis) is unhealthy")
+	require.Len(t, containers, 1)
+	require.Equal(t, containers, manager.GetServiceContainers("primary"))
+}
+
+func TestContainerManager_StartStepContainer(t *testing.T) {
+	origWorkDirPath := configs.BitriseWorkDirPath
+	configs.BitriseWorkDirPath = "/tmp/bitrise-work"
+	defer func() { configs.BitriseWorkDirPath = origWorkDirPath }()
+	t.Setenv(mountOverridesEnvKey, "")
+
+	runtime := NewFakeRuntime()
+	runtime.Errors["start"] = errors.New("fake start error")
+	manager := newTestContainerManager(runtime)
+
+	stepContainer, err := manager.StartStepContainer(models.Container{Image: "node:20"}, "primary", "step-id", map[string]string{
+		configs.BitriseSourceDirEnvKey:     "/project",
+		configs.BitriseDeployDirEnvKey:     "/tmp/deploy",
+		configs.BitriseTestDeployDirEnvKey: "/tmp/test-results",
+	})
+	require.EqualError(t, err, "start step container: start docker container: start docker container (bitrise-step-step-id): fake start error")
+	require.Equal(t, "bitrise-step-step-id", stepContainer.Name)
+	require.Equal(t, "/project", runtime.Created[0].WorkingDir)
+	require.Equal(t, "bitrise-build-id-primary", runtime.Created[0].Network)
+
+	// the container is removed even if it failed to start
+	require.NoError(t, manager.DestroyStepContainer("step-id"))
+	require.Empty(t, runtime.Containers)
+
+	require.NoError(t, manager.RemoveWorkflowNetwork("primary"))
+	require.Empty(t, runtime.Networks)
+	// only the networks created by the manager are removed
+	require.NoError(t, manager.RemoveWorkflowNetwork("deploy"))
+	require.NotContains(t, runtime.Commands, "network-rm bitrise-build-id-deploy")
+}
+
+func TestContainerManager_PullPolicy(t *testing.T) {
+	const digest = "sha256:1dbfc3c8fc8e7c4e1ab0e0dfd3d8ca2fd9b6e3b4e2b2c5c2f8c1f8d8a5b6c7d8"
+	amd64Image := &ImageInfo{OS: "linux", Architecture: "amd64"}
+
+	tests := []struct {
+		name       string
+		container  models.Container
+		localImage *ImageInfo
+		wantPull   bool
+		wantErr    string
+	}{
+		{name: "latest tag is always pulled", container: models.Container{Image: "ubuntu:latest"}, localImage: amd64Image, wantPull: true},
+		{name: "untagged image is always pulled", container: models.Container{Image: "localhost:5000/ubuntu"}, localImage: amd64Image, wantPull: true},
+		{name: "present image is not pulled", container: models.Container{Image: "ubuntu:22.04"}, localImage: amd64Image},
+		{name: "missing image is pulled", container: models.Container{Image: "ubuntu:22.04"}, wantPull: true},
+		{name: "image of a different platform is pulled", container: models.Container{Image: "ubuntu:22.04", Platform: "linux/arm64"}, localImage: amd64Image, wantPull: true},
+		{name: "if-not-present policy", container: models.Container{Image: "ubuntu:latest", PullPolicy: "if-not-present"}, localImage: amd64Image},
+		{name: "always policy", container: models.Container{Image: "ubuntu:22.04", PullPolicy: "always"}, localImage: amd64Image, wantPull: true},
+		{name: "never policy", container: models.Container{Image: "ubuntu:latest", PullPolicy: "never"}, localImage: amd64Image},
+		{
+			name:      "never policy with missing image",
+			container: models.Container{Image: "ubuntu:22.04", PullPolicy: "never"},
+			wantErr:   "image (ubuntu:22.04) is not present locally and the pull policy is never",
+		},
+		{
+			name:       "digest-pinned image with matching local digest",
+			container:  models.Container{Image: "ubuntu@" + digest},
+			localImage: &ImageInfo{OS: "linux", Architecture: "amd64", RepoDigests: []string{"docker.io/library/ubuntu@" + digest}},
+		},
+		{
+			name:       "digest-pinned image with different local digest",
+			container:  models.Container{Image: "ubuntu@" + digest, PullPolicy: "never"},
+			localImage: &ImageInfo{OS: "linux", Architecture: "amd64", RepoDigests: []string{"ubuntu@sha256:0000"}},
+			wantErr:    "image (ubuntu@" + digest + ") is present locally with a different digest and the pull policy is never",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runtime := NewFakeRuntime()
+			if tt.localImage != nil {
+				runtime.Images[tt.container.Image] = tt.localImage
+			}
+			manager := newTestContainerManager(runtime)
+
+			err := manager.pullImageWithRetry(tt.container)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantPull, containsCommand(runtime.Commands, "pull --platform "+tt.container.GetPlatform()+" "+tt.container.Image))
+		})
+	}
+}
+
+func containsCommand(commands []string, command string) bool {
+	for _, c := range commands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// dockerRuntime runs the docker CLI, the state of the images, containers and networks is queried with the docker SDK.
+//
+// We are not using the docker sdk for pull, start and create commands because:
+//   - We want to make sure the end user can easily debug using the same docker command we issue
+//     (hard to convert between sdk and cli api)
+//   - We'd like to support options generically,
+//     with the SDK we would need to parse the string ourselves to convert them properly to their own type
+//   - SDK differs from the CLI in some cases, for example pulling from private registry requires the exact token
+//     it can't automatically use the docker config
+type dockerRuntime struct {
+	cliRuntime
+	client *client.Client
+}
+
+func newDockerRuntime(logger DockerLogger) dockerRuntime {
+	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		logger.Warnf("Docker client failed to initialize (possibly running on unsupported environment): %s", err)
+	}
+
+	return dockerRuntime{
+		cliRuntime: cliRuntime{binary: RuntimeDocker, logger: logger, registryConfig: newDockerRegistryConfig()},
+		client:     dockerClient,
+	}
+}
+
+func (r dockerRuntime) InspectImage(image string) (*ImageInfo, error) {
+	inspect, _, err := r.client.ImageInspectWithRaw(context.Background(), image)
+	if client.IsErrNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &ImageInfo{
+		ID:           inspect.ID,
+		RepoDigests:  inspect.RepoDigests,
+		OS:           inspect.Os,
+		Architecture: inspect.Architecture,
+		Variant:      inspect.Variant,
+	}, nil
+}
+
+func (r dockerRuntime) Inspect(name string) (ContainerState, error) {
+	inspect, err := r.client.ContainerInspect(context.Background(), name)
+	if err != nil {
+		return ContainerState{}, err
+	}
+
+	state := ContainerState{ID: inspect.ID, Name: strings.TrimPrefix(inspect.Name, "/")}
+	if inspect.State != nil {
+		state.Status = inspect.State.Status
+		if inspect.State.Health != nil {
+			state.Health = inspect.State.Health.Status
+		}
+	}
+	return state, nil
+}
+
+// HostUser is root with rootless docker, as the container's root is mapped to the user running the docker daemon,
+// otherwise it is the uid:gid of the user running bitrise.
+func (r dockerRuntime) HostUser() string {
+	if r.client != nil {
+		info, err := r.client.Info(context.Background())
+		if err != nil {
+			r.logger.Warnf("Failed to get docker info: %s", err)
+		} else {
+			for _, option := range info.SecurityOptions {
+				if strings.Contains(option, "name=rootless") {
+					return "0:0"
+				}
+			}
+		}
+	}
+	return fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())
+}
+
+func (r dockerRuntime) Logs(name string) (string, error) {
+	logs, err := r.client.ContainerLogs(context.Background(), name, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer logs.Close()
+
+	// the stdout and stderr of containers without a TTY are multiplexed into the same stream
+	var content bytes.Buffer
+	if _, err := stdcopy.StdCopy(&content, &content, logs); err != nil {
+		return "", fmt.Errorf("read logs: %w", err)
+	}
+	return content.String(), nil
+}
+
+func (r dockerRuntime) EnsureNetwork(name string) error {
+	networks, err := r.client.NetworkList(context.Background(), types.NetworkListOptions{
+		Filters: filters.NewArgs(filters.Arg("name", name)),
+	})
+	if err != nil {
+		return fmt.Errorf("list networks: %w", err)
+	}
+
+	// the name filter matches substrings too
+	for _, network := range networks {
+		if network.Name == name {
+			return nil
+		}
+	}
+
+	if _, err := r.client.NetworkCreate(context.Background(), name, types.NetworkCreate{}); err != nil {
+		return fmt.Errorf("create network: %w", err)
+	}
+	return nil
+}
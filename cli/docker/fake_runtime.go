@@ -0,0 +1,242 @@
+package docker
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// FakeRuntime is an in-memory ContainerRuntime for unit tests, it records the commands it was called with
+// and keeps track of the pulled images, created containers and networks without running anything.
+type FakeRuntime struct {
+	// Images are the locally available images, pulled images are added to it.
+	Images map[string]*ImageInfo
+	// Health is the healthcheck status reported for the container with the given name.
+	Health map[string]string
+	// ContainerLogs are the logs returned for the container with the given name.
+	ContainerLogs map[string]string
+	// ComposeServices are the container names of the services started by compose up, keyed by the service.
+	ComposeServices map[string][]string
+	// Errors are returned by the command with the given name (login, pull, create, start, ...).
+	Errors map[string]error
+
+	Commands   []string
+	Created    []CreateOptions
+	Containers map[string]*ContainerState
+	Networks   map[string]bool
+
+	mu sync.Mutex
+}
+
+// NewFakeRuntime ...
+func NewFakeRuntime() *FakeRuntime {
+	return &FakeRuntime{
+		Images:          map[string]*ImageInfo{},
+		Health:          map[string]string{},
+		ContainerLogs:   map[string]string{},
+		ComposeServices: map[string][]string{},
+		Errors:          map[string]error{},
+		Containers:      map[string]*ContainerState{},
+		Networks:        map[string]bool{},
+	}
+}
+
+func (r *FakeRuntime) Binary() string {
+	return "fake-runtime"
+}
+
+func (r *FakeRuntime) Login(server, username, _ string) error {
+	return r.record("login", username, server)
+}
+
+func (r *FakeRuntime) SetCredentialHelper(server, helper string) error {
+	return r.record("credential-helper", helper, server)
+}
+
+func (r *FakeRuntime) RemoveRegistryConfig() error {
+	return r.record("registry-config-rm")
+}
+
+func (r *FakeRuntime) InspectImage(image string) (*ImageInfo, error) {
+	if err := r.record("image-inspect", image); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Images[image], nil
+}
+
+func (r *FakeRuntime) Pull(image, platform string) error {
+	if err := r.record("pull", "--platform", platform, image); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	platformParts := strings.SplitN(platform, "/", 3)
+	info := &ImageInfo{ID: "id-" + image, OS: platformParts[0]}
+	if len(platformParts) > 1 {
+		info.Architecture = platformParts[1]
+	}
+	if len(platformParts) > 2 {
+		info.Variant = platformParts[2]
+	}
+	if i := strings.Index(image, "@"); i != -1 {
+		info.RepoDigests = []string{image}
+	}
+	r.Images[image] = info
+	return nil
+}
+
+func (r *FakeRuntime) Build(options BuildOptions) error {
+	if err := r.record("build", options.Tag, options.Context); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Images[options.Tag] = &ImageInfo{ID: "id-" + options.Tag}
+	return nil
+}
+
+func (r *FakeRuntime) Create(options CreateOptions) error {
+	if err := r.record("create", options.Name, options.Image); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Created = append(r.Created, options)
+	r.Containers[options.Name] = &ContainerState{ID: "id-" + options.Name, Name: options.Name, Status: "created"}
+	return nil
+}
+
+func (r *FakeRuntime) Start(name string) error {
+	if err := r.record("start", name); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	container, ok := r.Containers[name]
+	if !ok {
+		return fmt.Errorf("no such container: %s", name)
+	}
+	container.Status = "running"
+	return nil
+}
+
+func (r *FakeRuntime) ExecArgs(name string, envs []string) []string {
+	return append([]string{"exec", name}, envs...)
+}
+
+func (r *FakeRuntime) Exec(name, user string, cmd []string) (string, error) {
+	args := []string{name, strings.Join(cmd, " ")}
+	if user != "" {
+		args = append([]string{"--user", user}, args...)
+	}
+	return "", r.record("exec", args...)
+}
+
+func (r *FakeRuntime) HostUser() string {
+	return "1001:1001"
+}
+
+func (r *FakeRuntime) Inspect(name string) (ContainerState, error) {
+	if err := r.record("inspect", name); err != nil {
+		return ContainerState{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	container, ok := r.Containers[name]
+	if !ok {
+		return ContainerState{}, fmt.Errorf("no such container: %s", name)
+	}
+	state := *container
+	state.Health = r.Health[name]
+	return state, nil
+}
+
+func (r *FakeRuntime) Logs(name string) (string, error) {
+	if err := r.record("logs", name); err != nil {
+		return "", err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ContainerLogs[name], nil
+}
+
+func (r *FakeRuntime) Remove(name string) error {
+	if err := r.record("rm", name); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.Containers, name)
+	return nil
+}
+
+func (r *FakeRuntime) EnsureNetwork(name string) error {
+	if err := r.record("network", name); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Networks[name] = true
+	return nil
+}
+
+func (r *FakeRuntime) RemoveNetwork(name string) error {
+	if err := r.record("network-rm", name); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.Networks, name)
+	return nil
+}
+
+func (r *FakeRuntime) ComposeUp(options ComposeOptions) error {
+	if err := r.record("compose-up", append([]string{options.Project}, options.Files...)...); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, names := range r.ComposeServices {
+		for _, name := range names {
+			r.Containers[name] = &ContainerState{ID: name, Name: name, Status: "running"}
+		}
+	}
+	return nil
+}
+
+// ComposeContainers returns the container names as the container IDs, the fake runtime accepts both.
+func (r *FakeRuntime) ComposeContainers(options ComposeOptions) (map[string][]string, error) {
+	if err := r.record("compose-ps", options.Project); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	containers := map[string][]string{}
+	for service, names := range r.ComposeServices {
+		containers[service] = append([]string{}, names...)
+	}
+	return containers, nil
+}
+
+func (r *FakeRuntime) ComposeDown(options ComposeOptions) error {
+	if err := r.record("compose-down", options.Project); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, names := range r.ComposeServices {
+		for _, name := range names {
+			delete(r.Containers, name)
+		}
+	}
+	return nil
+}
+
+func (r *FakeRuntime) record(name string, args ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Commands = append(r.Commands, strings.Join(append([]string{name}, args...), " "))
+	return r.Errors[name]
+}
@@ -0,0 +1,114 @@
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// podmanRuntime runs the podman CLI (rootless podman included), it doesn't need a daemon or a docker compatible socket.
+type podmanRuntime struct {
+	cliRuntime
+}
+
+func newPodmanRuntime(logger DockerLogger) podmanRuntime {
+	return podmanRuntime{cliRuntime: cliRuntime{binary: RuntimePodman, logger: logger, registryConfig: newPodmanRegistryConfig()}}
+}
+
+func (r podmanRuntime) InspectImage(image string) (*ImageInfo, error) {
+	exists, err := r.exists("image", "exists", image)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	out, err := r.command("image", "inspect", image).RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err, out)
+	}
+
+	var inspects []ImageInfo
+	if err := json.Unmarshal([]byte(out), &inspects); err != nil {
+		return nil, fmt.Errorf("parse image inspect output: %w", err)
+	}
+	if len(inspects) != 1 {
+		return nil, fmt.Errorf("expected 1 image, got: %d", len(inspects))
+	}
+	return &inspects[0], nil
+}
+
+func (r podmanRuntime) Inspect(name string) (ContainerState, error) {
+	out, err := r.command("container", "inspect", name).RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		return ContainerState{}, fmt.Errorf("%s: %s", err, out)
+	}
+	return parsePodmanInspect([]byte(out))
+}
+
+func (r podmanRuntime) EnsureNetwork(name string) error {
+	exists, err := r.exists("network", "exists", name)
+	if err != nil {
+		return fmt.Errorf("check network: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	if _, err := r.run("network", "create", name); err != nil {
+		return fmt.Errorf("create network: %w", err)
+	}
+	return nil
+}
+
+// HostUser is root, as podman runs rootful only as root and rootless podman maps the container's root to the user running podman.
+func (r podmanRuntime) HostUser() string {
+	return "0:0"
+}
+
+// exists runs a podman `exists` command, which exits with 1 if the object doesn't exist.
+func (r podmanRuntime) exists(args ...string) (bool, error) {
+	out, err := r.command(args...).RunAndReturnTrimmedCombinedOutput()
+	if err == nil {
+		return true, nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return false, fmt.Errorf("%s: %s", err, out)
+}
+
+type podmanInspectModel struct {
+	ID    string `json:"Id"`
+	Name  string `json:"Name"`
+	State struct {
+		Status string `json:"Status"`
+		Health *struct {
+			Status string `json:"Status"`
+		} `json:"Health"`
+		// Healthcheck is the name of the Health field before podman 4
+		Healthcheck *struct {
+			Status string `json:"Status"`
+		} `json:"Healthcheck"`
+	} `json:"State"`
+}
+
+func parsePodmanInspect(out []byte) (ContainerState, error) {
+	var inspects []podmanInspectModel
+	if err := json.Unmarshal(out, &inspects); err != nil {
+		return ContainerState{}, fmt.Errorf("parse inspect output: %w", err)
+	}
+	if len(inspects) != 1 {
+		return ContainerState{}, fmt.Errorf("expected 1 container, got: %d", len(inspects))
+	}
+
+	inspect := inspects[0]
+	state := ContainerState{ID: inspect.ID, Name: inspect.Name, Status: inspect.State.Status}
+	if inspect.State.Health != nil {
+		state.Health = inspect.State.Health.Status
+	} else if inspect.State.Healthcheck != nil {
+		state.Health = inspect.State.Healthcheck.Status
+	}
+	return state, nil
+}
@@ -0,0 +1,77 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePodmanInspect(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    ContainerState
+		wantErr string
+	}{
+		{
+			name: "no healthcheck",
+			out:  `[{"Id": "abc", "Name": "postgres", "State": {"Status": "running"}}]`,
+			want: ContainerState{ID: "abc", Name: "postgres", Status: "running"},
+		},
+		{
+			name: "healthcheck",
+			out:  `[{"Id": "abc", "State": {"Status": "running", "Health": {"Status": "starting"}}}]`,
+			want: ContainerState{ID: "abc", Status: "running", Health: "starting"},
+		},
+		{
+			name: "healthcheck before podman 4",
+			out:  `[{"Id": "abc", "State": {"Status": "running", "Healthcheck": {"Status": "healthy"}}}]`,
+			want: ContainerState{ID: "abc", Status: "running", Health: "healthy"},
+		},
+		{
+			name:    "no container",
+			out:     `[]`,
+			wantErr: "expected 1 container, got: 0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePodmanInspect([]byte(tt.out))
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCreateArgs(t *testing.T) {
+	require.Equal(t, []string{
+		"create", "--platform", "linux/amd64", "--network=bitrise",
+		"-v", "/src:/src", "-e", "CI=true", "-p", "8080:8080", "-w", "/src", "-u", "root",
+		"--cpus", "2", "--name=bitrise-workflow-primary", "ubuntu", "sleep", "infinity",
+	}, createArgs(CreateOptions{
+		Name:       "bitrise-workflow-primary",
+		Image:      "ubuntu",
+		Platform:   "linux/amd64",
+		Network:    "bitrise",
+		Volumes:    []string{"/src:/src"},
+		Envs:       []string{"CI=true"},
+		Ports:      []string{"8080:8080"},
+		WorkingDir: "/src",
+		User:       "root",
+		Options:    []string{"--cpus", "2"},
+		Command:    []string{"sleep", "infinity"},
+	}))
+	require.Equal(t, []string{
+		"create", "--network=bitrise-build-id-primary", "--network-alias=postgres",
+		"--name=bitrise-service-build-id-primary-postgres", "postgres:16",
+	}, createArgs(CreateOptions{
+		Name:           "bitrise-service-build-id-primary-postgres",
+		Image:          "postgres:16",
+		Network:        "bitrise-build-id-primary",
+		NetworkAliases: []string{"postgres"},
+	}))
+}
@@ -0,0 +1,94 @@
+package docker
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/bitrise-io/bitrise/models"
+)
+
+// probeTimeout limits a single TCP or HTTP probe, so that a hanging probe doesn't block the readiness check.
+const probeTimeout = 5 * time.Second
+
+// waitForReadiness runs the readiness probes of the service until all of them pass, or the readiness timeout elapses.
+func (cm *ContainerManager) waitForReadiness(serviceName string, container *RunningContainer, readiness models.ServiceReadiness) error {
+	timeout := readiness.GetTimeout()
+	interval := readiness.GetInterval()
+	deadline := time.Now().Add(timeout)
+
+	for {
+		err := cm.probeReadiness(container, readiness)
+		if err == nil {
+			cm.logger.Infof("✅ Service (%s) is ready", serviceName)
+			return nil
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			cm.logger.Errorf("❌ Service (%s) is not ready after %s: %s", serviceName, timeout, err)
+			return fmt.Errorf("service (%s) is not ready after %s: %w", serviceName, timeout, err)
+		}
+
+		cm.logger.Infof("⏳ Waiting for service (%s) to be ready: %s", serviceName, err)
+		time.Sleep(interval)
+	}
+}
+
+// probeReadiness runs every defined probe of the service, and returns the first failure.
+func (cm *ContainerManager) probeReadiness(container *RunningContainer, readiness models.ServiceReadiness) error {
+	if readiness.TCP != "" {
+		conn, err := net.DialTimeout("tcp", readiness.TCP, probeTimeout)
+		if err != nil {
+			return fmt.Errorf("tcp probe (%s): %w", readiness.TCP, err)
+		}
+		if err := conn.Close(); err != nil {
+			cm.logger.Warnf("Failed to close tcp probe connection: %s", err)
+		}
+	}
+
+	if readiness.HTTP != nil {
+		client := http.Client{Timeout: probeTimeout}
+		resp, err := client.Get(readiness.HTTP.URL)
+		if err != nil {
+			return fmt.Errorf("http probe (%s): %w", readiness.HTTP.URL, err)
+		}
+		if err := resp.Body.Close(); err != nil {
+			cm.logger.Warnf("Failed to close http probe response body: %s", err)
+		}
+
+		expected := readiness.HTTP.Status
+		if (expected != 0 && resp.StatusCode != expected) || (expected == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299)) {
+			return fmt.Errorf("http probe (%s): unexpected status: %d", readiness.HTTP.URL, resp.StatusCode)
+		}
+	}
+
+	if readiness.Command != "" {
+		out, err := cm.runtime.Exec(container.Name, "", []string{"sh", "-c", readiness.Command})
+		if err != nil {
+			return fmt.Errorf("command probe (%s): %s: %s", readiness.Command, err, out)
+		}
+	}
+
+	return nil
+}
+
+// printServiceLogs prints the logs of a service which failed to become healthy or ready.
+func (cm *ContainerManager) printServiceLogs(serviceName string, container *RunningContainer) {
+	logs, err := cm.runtime.Logs(container.Name)
+	if err != nil {
+		cm.logger.Warnf("Failed to get the logs of service (%s): %s", serviceName, err)
+		return
+	}
+	cm.logger.Errorf("Service (%s) logs:\n%s\n", serviceName, logs)
+}
+
+func sortedServiceNames(services map[string]models.Container) []string {
+	var names []string
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
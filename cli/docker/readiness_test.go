@@ -0,0 +1,74 @@
+package docker
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerManager_ServiceReadiness(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ready" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	t.Run("ready", func(t *testing.T) {
+		runtime := NewFakeRuntime()
+		manager := newTestContainerManager(runtime)
+
+		services := map[string]models.Container{
+			"api": {Image: "api", Readiness: &models.ServiceReadiness{
+				TCP:     listener.Addr().String(),
+				HTTP:    &models.ServiceReadinessHTTP{URL: server.URL + "/ready"},
+				Command: "curl -f localhost",
+			}},
+		}
+		_, err := manager.StartServiceContainers(services, "primary", nil)
+		require.NoError(t, err)
+		require.Contains(t, runtime.Commands, "exec bitrise-service-build-id-primary-api sh -c curl -f localhost")
+	})
+
+	t.Run("unexpected http status", func(t *testing.T) {
+		runtime := NewFakeRuntime()
+		manager := newTestContainerManager(runtime)
+
+		services := map[string]models.Container{
+			"api": {Image: "api", Readiness: &models.ServiceReadiness{
+				HTTP:    &models.ServiceReadinessHTTP{URL: server.URL + "/ready", Status: http.StatusOK},
+				Timeout: 1,
+			}},
+		}
+		_, err := manager.StartServiceContainers(services, "primary", nil)
+		require.EqualError(t, err, "service readiness: service (api) is not ready after 1s: http probe ("+server.URL+"/ready): unexpected status: 204")
+		require.Contains(t, runtime.Commands, "logs bitrise-service-build-id-primary-api")
+	})
+
+	t.Run("failing command", func(t *testing.T) {
+		runtime := NewFakeRuntime()
+		runtime.Errors["exec"] = errors.New("exit status 2")
+		manager := newTestContainerManager(runtime)
+
+		services := map[string]models.Container{
+			"postgres": {Image: "postgres", Readiness: &models.ServiceReadiness{Command: "pg_isready", Timeout: 1}},
+			"redis":    {Image: "redis"},
+		}
+		containers, err := manager.StartServiceContainers(services, "primary", nil)
+		require.EqualError(t, err, "service readiness: service (postgres) is not ready after 1s: command probe (pg_isready): exit status 2: ")
+		require.Contains(t, runtime.Commands, "logs bitrise-service-build-id-primary-postgres")
+		require.NotContains(t, runtime.Commands, "logs bitrise-service-build-id-primary-redis")
+		require.Len(t, containers, 2)
+	})
+}
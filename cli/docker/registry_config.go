@@ -0,0 +1,207 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const registryConfigFileName = "config.json"
+
+// registryConfig is the temporary registry config of the build, the registry logins and credential helpers of the build
+// are written to it instead of the user's config, and it is removed at the end of the build.
+// It is created on the first login, seeded with the auths and credential helpers of the user's config,
+// so the registries the user is already logged in to remain available.
+type registryConfig struct {
+	// envKey points the runtime's CLI to the config, to its dir (DOCKER_CONFIG) or to the file itself (REGISTRY_AUTH_FILE)
+	envKey       string
+	pointsToFile bool
+	// userConfigPaths are the possible config files of the user, the first existing one is used
+	userConfigPaths []string
+
+	mu  sync.Mutex
+	dir string
+}
+
+// registryConfigFile is the part of the docker config (and of the podman auth file) used for the registry authentication.
+// The credsStore of the user's config is not kept, as the logins of the build would be saved to the user's credential store,
+// the registries whose credentials are in the store are set up with the store as their credential helper instead.
+type registryConfigFile struct {
+	Auths       map[string]json.RawMessage `json:"auths,omitempty"`
+	CredHelpers map[string]string          `json:"credHelpers,omitempty"`
+	CredsStore  string                     `json:"credsStore,omitempty"`
+}
+
+// registryAuth is an entry of the config's auths, the auth is empty if the credentials are kept in the credential store.
+type registryAuth struct {
+	Auth string `json:"auth"`
+}
+
+func newDockerRegistryConfig() *registryConfig {
+	var userConfigPaths []string
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		userConfigPaths = append(userConfigPaths, filepath.Join(dir, registryConfigFileName))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		userConfigPaths = append(userConfigPaths, filepath.Join(home, ".docker", registryConfigFileName))
+	}
+
+	return &registryConfig{envKey: "DOCKER_CONFIG", userConfigPaths: userConfigPaths}
+}
+
+func newPodmanRegistryConfig() *registryConfig {
+	var userConfigPaths []string
+	if pth := os.Getenv("REGISTRY_AUTH_FILE"); pth != "" {
+		userConfigPaths = append(userConfigPaths, pth)
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		userConfigPaths = append(userConfigPaths, filepath.Join(dir, "containers", "auth.json"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		userConfigPaths = append(userConfigPaths,
+			filepath.Join(home, ".config", "containers", "auth.json"),
+			filepath.Join(home, ".docker", registryConfigFileName),
+		)
+	}
+
+	return &registryConfig{envKey: "REGISTRY_AUTH_FILE", pointsToFile: true, userConfigPaths: userConfigPaths}
+}
+
+// envs returns the env pointing the runtime's CLI to the temporary config, it is empty until the config is created.
+func (c *registryConfig) envs() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dir == "" {
+		return nil
+	}
+
+	value := c.dir
+	if c.pointsToFile {
+		value = c.path()
+	}
+	return []string{fmt.Sprintf("%s=%s", c.envKey, value)}
+}
+
+// ensure creates the temporary config if it doesn't exist yet.
+func (c *registryConfig) ensure() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dir != "" {
+		return nil
+	}
+
+	config, err := c.readUserConfig()
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "bitrise-registry-config-")
+	if err != nil {
+		return fmt.Errorf("create registry config dir: %w", err)
+	}
+	c.dir = dir
+
+	return c.write(config)
+}
+
+// setCredentialHelper configures the credential helper (docker-credential-<helper>) of the registry.
+func (c *registryConfig) setCredentialHelper(server, helper string) error {
+	if err := c.ensure(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	config, err := readRegistryConfigFile(c.path())
+	if err != nil {
+		return err
+	}
+	if config.CredHelpers == nil {
+		config.CredHelpers = map[string]string{}
+	}
+	config.CredHelpers[server] = helper
+	return c.write(config)
+}
+
+// remove removes the temporary config with the logins of the build.
+func (c *registryConfig) remove() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dir == "" {
+		return nil
+	}
+
+	if err := os.RemoveAll(c.dir); err != nil {
+		return fmt.Errorf("remove registry config dir: %w", err)
+	}
+	c.dir = ""
+	return nil
+}
+
+func (c *registryConfig) path() string {
+	return filepath.Join(c.dir, registryConfigFileName)
+}
+
+func (c *registryConfig) readUserConfig() (registryConfigFile, error) {
+	for _, pth := range c.userConfigPaths {
+		if _, err := os.Stat(pth); err != nil {
+			continue
+		}
+		config, err := readRegistryConfigFile(pth)
+		if err != nil {
+			return registryConfigFile{}, err
+		}
+		return withoutCredsStore(config), nil
+	}
+	return registryConfigFile{}, nil
+}
+
+// withoutCredsStore replaces the credential store of the config with the credential helpers of the registries,
+// whose credentials are in the store (their auths entry has no inline auth).
+func withoutCredsStore(config registryConfigFile) registryConfigFile {
+	if config.CredsStore == "" {
+		return config
+	}
+
+	for server, rawAuth := range config.Auths {
+		var auth registryAuth
+		if err := json.Unmarshal(rawAuth, &auth); err != nil || auth.Auth != "" {
+			continue
+		}
+		if _, ok := config.CredHelpers[server]; ok {
+			continue
+		}
+		if config.CredHelpers == nil {
+			config.CredHelpers = map[string]string{}
+		}
+		config.CredHelpers[server] = config.CredsStore
+	}
+	config.CredsStore = ""
+	return config
+}
+
+func (c *registryConfig) write(config registryConfigFile) error {
+	content, err := json.MarshalIndent(config, "", "\t")
+	if err != nil {
+		return fmt.Errorf("marshal registry config: %w", err)
+	}
+	if err := os.WriteFile(c.path(), content, 0600); err != nil {
+		return fmt.Errorf("write registry config: %w", err)
+	}
+	return nil
+}
+
+func readRegistryConfigFile(pth string) (registryConfigFile, error) {
+	content, err := os.ReadFile(pth)
+	if err != nil {
+		return registryConfigFile{}, fmt.Errorf("read registry config: %w", err)
+	}
+
+	var config registryConfigFile
+	if err := json.Unmarshal(content, &config); err != nil {
+		return registryConfigFile{}, fmt.Errorf("parse registry config (%s): %w", pth, err)
+	}
+	return config, nil
+}
@@ -0,0 +1,83 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryConfig(t *testing.T) {
+	userConfigDir := t.TempDir()
+	userConfig := `{
+	"auths": {"ghcr.io": {"auth": "dXNlcjp0b2tlbg=="}, "https://index.docker.io/v1/": {}},
+	"credHelpers": {"gcr.io": "gcloud"},
+	"credsStore": "desktop"
+}`
+	require.NoError(t, os.WriteFile(filepath.Join(userConfigDir, "config.json"), []byte(userConfig), 0600))
+	t.Setenv("DOCKER_CONFIG", userConfigDir)
+
+	config := newDockerRegistryConfig()
+	require.Empty(t, config.envs())
+
+	require.NoError(t, config.setCredentialHelper("123.dkr.ecr.us-east-1.amazonaws.com", "ecr-login"))
+	envs := config.envs()
+	require.Len(t, envs, 1)
+	require.True(t, strings.HasPrefix(envs[0], "DOCKER_CONFIG="))
+	configDir := strings.TrimPrefix(envs[0], "DOCKER_CONFIG=")
+	require.NotEqual(t, userConfigDir, configDir)
+
+	// the auths and credential helpers of the user's config are kept, its credential store is only used for the registries it stores
+	buildConfig, err := readRegistryConfigFile(filepath.Join(configDir, "config.json"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"auth": "dXNlcjp0b2tlbg=="}`, string(buildConfig.Auths["ghcr.io"]))
+	require.Equal(t, map[string]string{
+		"gcr.io":                              "gcloud",
+		"https://index.docker.io/v1/":         "desktop",
+		"123.dkr.ecr.us-east-1.amazonaws.com": "ecr-login",
+	}, buildConfig.CredHelpers)
+
+	content, err := os.ReadFile(filepath.Join(configDir, "config.json"))
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "credsStore")
+
+	require.NoError(t, config.remove())
+	require.NoDirExists(t, configDir)
+	require.Empty(t, config.envs())
+}
+
+func TestCLIRuntime_Login(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	// the fake CLI saves its args, the password read from its stdin and the docker config it uses
+	binDir := t.TempDir()
+	outputPath := filepath.Join(binDir, "output")
+	script := `#!/bin/sh
+echo "$@" > ` + outputPath + `
+cat >> ` + outputPath + `
+echo >> ` + outputPath + `
+echo "$DOCKER_CONFIG" >> ` + outputPath + `
+`
+	binary := filepath.Join(binDir, "docker")
+	require.NoError(t, os.WriteFile(binary, []byte(script), 0700))
+
+	runtime := cliRuntime{
+		binary:         binary,
+		logger:         NewDockerLogger(log.NewLogger(log.GetGlobalLoggerOpts()), nil),
+		registryConfig: newDockerRegistryConfig(),
+	}
+	require.NoError(t, runtime.Login("ghcr.io", "bitrise", "secret-password"))
+
+	output, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	require.Equal(t, "login --username bitrise --password-stdin ghcr.io", lines[0])
+	require.Equal(t, "secret-password", lines[1])
+	require.Equal(t, "DOCKER_CONFIG="+lines[2], runtime.registryConfig.envs()[0])
+
+	require.NoError(t, runtime.RemoveRegistryConfig())
+	require.NoDirExists(t, lines[2])
+}
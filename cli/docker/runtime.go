@@ -0,0 +1,322 @@
+package docker
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/command"
+)
+
+const (
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
+)
+
+// ContainerRuntime is the container engine the ContainerManager drives.
+type ContainerRuntime interface {
+	// Binary is the CLI of the runtime, the steps are run with its exec command.
+	Binary() string
+	// Login logs in to the registry, the login is saved to the temporary registry config of the build.
+	Login(server, username, password string) error
+	// SetCredentialHelper configures the docker credential helper of the registry in the temporary registry config of the build.
+	SetCredentialHelper(server, helper string) error
+	// RemoveRegistryConfig removes the temporary registry config, with the logins of the build.
+	RemoveRegistryConfig() error
+	// InspectImage returns the local image, nil if the image is not present locally.
+	InspectImage(image string) (*ImageInfo, error)
+	Pull(image, platform string) error
+	Build(options BuildOptions) error
+	Create(options CreateOptions) error
+	Start(name string) error
+	ExecArgs(name string, envs []string) []string
+	// Exec runs the command in the container as the given user (the container's user if empty) and returns its combined output.
+	Exec(name, user string, cmd []string) (string, error)
+	// HostUser is the container user (uid:gid) which owns the files in the mounted dirs as the user running bitrise.
+	HostUser() string
+	Inspect(name string) (ContainerState, error)
+	Logs(name string) (string, error)
+	Remove(name string) error
+	EnsureNetwork(name string) error
+	RemoveNetwork(name string) error
+	// ComposeUp creates and starts the services of the compose project in the background.
+	ComposeUp(options ComposeOptions) error
+	// ComposeContainers returns the IDs of the containers of the compose project, keyed by their service.
+	ComposeContainers(options ComposeOptions) (map[string][]string, error)
+	// ComposeDown removes the containers, the volumes and the networks of the compose project.
+	ComposeDown(options ComposeOptions) error
+}
+
+// CreateOptions describes a container to create, Options are the raw (already split) user defined create options.
+type CreateOptions struct {
+	Name     string
+	Image    string
+	Platform string
+	Network  string
+	// NetworkAliases are the additional host names of the container in its network.
+	NetworkAliases []string
+	Volumes        []string
+	Envs           []string
+	Ports          []string
+	WorkingDir     string
+	User           string
+	Options        []string
+	Command        []string
+}
+
+// BuildOptions describes an image build, the Dockerfile is relative to the context dir.
+type BuildOptions struct {
+	Context    string
+	Dockerfile string
+	Tag        string
+	Args       map[string]string
+	Target     string
+	Platform   string
+}
+
+// ComposeOptions identifies a compose project, the later files override the earlier ones.
+type ComposeOptions struct {
+	Project string
+	Files   []string
+}
+
+// ImageInfo is the inspected local image.
+type ImageInfo struct {
+	ID           string
+	RepoDigests  []string
+	OS           string
+	Architecture string
+	Variant      string
+}
+
+// ContainerState is the inspected state of a container, Health is empty if the container has no healthcheck.
+type ContainerState struct {
+	ID     string
+	Name   string
+	Status string
+	Health string
+}
+
+// NewRuntime returns the runtime with the given name (docker or podman).
+func NewRuntime(name string, logger DockerLogger) (ContainerRuntime, error) {
+	switch name {
+	case "", RuntimeDocker:
+		return newDockerRuntime(logger), nil
+	case RuntimePodman:
+		return newPodmanRuntime(logger), nil
+	default:
+		return nil, fmt.Errorf("unsupported container runtime (%s), supported runtimes: %s, %s", name, RuntimeDocker, RuntimePodman)
+	}
+}
+
+// cliRuntime implements the commands which are the same for the docker and podman CLIs.
+type cliRuntime struct {
+	binary         string
+	logger         DockerLogger
+	registryConfig *registryConfig
+}
+
+func (r cliRuntime) Binary() string {
+	return r.binary
+}
+
+func (r cliRuntime) Login(server, username, password string) error {
+	if err := r.registryConfig.ensure(); err != nil {
+		return err
+	}
+
+	// the password is passed on the stdin, so it doesn't show up in the process list and in the logged command
+	args := []string{"login", "--username", username, "--password-stdin"}
+	if server != "" {
+		args = append(args, server)
+	}
+	r.logger.Infof("ℹ️ Running command: %s %s", r.binary, strings.Join(args, " "))
+
+	out, err := r.command(args...).SetStdin(strings.NewReader(password)).RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		r.logger.Errorf(out)
+		return fmt.Errorf("%s login: %w", r.binary, err)
+	}
+	return nil
+}
+
+func (r cliRuntime) SetCredentialHelper(server, helper string) error {
+	return r.registryConfig.setCredentialHelper(server, helper)
+}
+
+func (r cliRuntime) RemoveRegistryConfig() error {
+	return r.registryConfig.remove()
+}
+
+func (r cliRuntime) Pull(image, platform string) error {
+	_, err := r.run("pull", "--platform", platform, image)
+	return err
+}
+
+func (r cliRuntime) Build(options BuildOptions) error {
+	_, err := r.run(buildArgs(options)...)
+	return err
+}
+
+func (r cliRuntime) Create(options CreateOptions) error {
+	_, err := r.run(createArgs(options)...)
+	return err
+}
+
+func (r cliRuntime) Start(name string) error {
+	_, err := r.run("start", name)
+	return err
+}
+
+func (r cliRuntime) ExecArgs(name string, envs []string) []string {
+	args := []string{"exec"}
+	for _, env := range envs {
+		args = append(args, "-e", env)
+	}
+	return append(args, name)
+}
+
+func (r cliRuntime) Exec(name, user string, cmd []string) (string, error) {
+	args := []string{"exec"}
+	if user != "" {
+		args = append(args, "--user", user)
+	}
+	args = append(args, name)
+	return r.command(append(args, cmd...)...).RunAndReturnTrimmedCombinedOutput()
+}
+
+func (r cliRuntime) Logs(name string) (string, error) {
+	return r.command("logs", name).RunAndReturnTrimmedCombinedOutput()
+}
+
+func (r cliRuntime) Remove(name string) error {
+	out, err := r.command("rm", "--force", "--volumes", name).RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, out)
+	}
+	return nil
+}
+
+func (r cliRuntime) RemoveNetwork(name string) error {
+	out, err := r.command("network", "rm", name).RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, out)
+	}
+	return nil
+}
+
+func (r cliRuntime) ComposeUp(options ComposeOptions) error {
+	_, err := r.run(composeArgs(options, "up", "--detach")...)
+	return err
+}
+
+func (r cliRuntime) ComposeContainers(options ComposeOptions) (map[string][]string, error) {
+	out, err := r.command(composeArgs(options, "ps", "--all", "--services")...).RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err, out)
+	}
+
+	containers := map[string][]string{}
+	for _, service := range strings.Fields(out) {
+		out, err := r.command(composeArgs(options, "ps", "--all", "--quiet", service)...).RunAndReturnTrimmedCombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", err, out)
+		}
+		containers[service] = strings.Fields(out)
+	}
+	return containers, nil
+}
+
+func (r cliRuntime) ComposeDown(options ComposeOptions) error {
+	_, err := r.run(composeArgs(options, "down", "--volumes", "--remove-orphans")...)
+	return err
+}
+
+// command returns the command of the runtime's CLI, using the temporary registry config of the build once it exists.
+func (r cliRuntime) command(args ...string) *command.Model {
+	cmd := command.New(r.binary, args...)
+	if envs := r.registryConfig.envs(); len(envs) > 0 {
+		cmd.AppendEnvs(envs...)
+	}
+	return cmd
+}
+
+// run logs and runs the command, the output is logged as an error if the command fails.
+func (r cliRuntime) run(args ...string) (string, error) {
+	r.logger.Infof("ℹ️ Running command: %s %s", r.binary, strings.Join(args, " "))
+
+	out, err := r.command(args...).RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		r.logger.Errorf(out)
+		return out, fmt.Errorf("%s %s: %w", r.binary, args[0], err)
+	}
+	return out, nil
+}
+
+func createArgs(options CreateOptions) []string {
+	args := []string{"create"}
+	if options.Platform != "" {
+		args = append(args, "--platform", options.Platform)
+	}
+	if options.Network != "" {
+		args = append(args, fmt.Sprintf("--network=%s", options.Network))
+	}
+	for _, alias := range options.NetworkAliases {
+		args = append(args, fmt.Sprintf("--network-alias=%s", alias))
+	}
+
+	for _, volume := range options.Volumes {
+		args = append(args, "-v", volume)
+	}
+
+	for _, env := range options.Envs {
+		args = append(args, "-e", env)
+	}
+
+	for _, port := range options.Ports {
+		args = append(args, "-p", port)
+	}
+
+	if options.WorkingDir != "" {
+		args = append(args, "-w", options.WorkingDir)
+	}
+
+	if options.User != "" {
+		args = append(args, "-u", options.User)
+	}
+
+	args = append(args, options.Options...)
+	args = append(args, fmt.Sprintf("--name=%s", options.Name), options.Image)
+	return append(args, options.Command...)
+}
+
+func composeArgs(options ComposeOptions, args ...string) []string {
+	composeArgs := []string{"compose", "--project-name", options.Project}
+	for _, file := range options.Files {
+		composeArgs = append(composeArgs, "--file", file)
+	}
+	return append(composeArgs, args...)
+}
+
+func buildArgs(options BuildOptions) []string {
+	args := []string{"build"}
+	if options.Platform != "" {
+		args = append(args, "--platform", options.Platform)
+	}
+	args = append(args, "--file", filepath.Join(options.Context, options.Dockerfile), "--tag", options.Tag)
+	if options.Target != "" {
+		args = append(args, "--target", options.Target)
+	}
+
+	var argNames []string
+	for name := range options.Args {
+		argNames = append(argNames, name)
+	}
+	sort.Strings(argNames)
+	for _, name := range argNames {
+		args = append(args, "--build-arg", fmt.Sprintf("%s=%s", name, options.Args[name]))
+	}
+
+	return append(args, options.Context)
+}
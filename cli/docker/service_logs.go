@@ -0,0 +1,67 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bitrise-io/bitrise/configs"
+)
+
+const serviceLogsDirName = "service-logs"
+
+// SaveServiceLogs saves the logs of the service containers of the workflow to
+// BITRISE_DEPLOY_DIR/service-logs/<workflow>/<service>.log with the secrets redacted,
+// so that the logs are available as build artifacts after the containers are removed.
+func (cm *ContainerManager) SaveServiceLogs(workflowID string, envs map[string]string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.saveServiceLogs(workflowID, cm.serviceContainers[workflowID], envs)
+}
+
+func (cm *ContainerManager) saveServiceLogs(workflowID string, containers []*RunningContainer, envs map[string]string) error {
+	if len(containers) == 0 {
+		return nil
+	}
+
+	deployDir := lookupEnv(configs.BitriseDeployDirEnvKey, envs)
+	if deployDir == "" {
+		return fmt.Errorf("%s is not set", configs.BitriseDeployDirEnvKey)
+	}
+	logsDir := filepath.Join(deployDir, serviceLogsDirName, workflowID)
+	if err := os.MkdirAll(logsDir, 0755); err != nil {
+		return fmt.Errorf("create service logs dir: %w", err)
+	}
+
+	var failedServices []string
+	for _, container := range containers {
+		if container == nil {
+			continue
+		}
+		if err := cm.saveContainerLogs(container, filepath.Join(logsDir, container.serviceName+".log")); err != nil {
+			cm.logger.Warnf("Failed to save the logs of service (%s): %s", container.serviceName, err)
+			failedServices = append(failedServices, container.serviceName)
+		}
+	}
+	if len(failedServices) > 0 {
+		return fmt.Errorf("failed to save the logs of services: %v", failedServices)
+	}
+
+	cm.logger.Infof("ℹ️ Service logs saved to: %s", logsDir)
+	return nil
+}
+
+func (cm *ContainerManager) saveContainerLogs(container *RunningContainer, pth string) error {
+	logs, err := container.Runtime.Logs(container.Name)
+	if err != nil {
+		return fmt.Errorf("get logs: %w", err)
+	}
+	redacted, err := cm.logger.Redact(logs)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(pth, []byte(redacted), 0644); err != nil {
+		return fmt.Errorf("write logs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package docker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerManager_SaveServiceLogs(t *testing.T) {
+	deployDir := t.TempDir()
+	envs := map[string]string{configs.BitriseDeployDirEnvKey: deployDir}
+
+	runtime := NewFakeRuntime()
+	runtime.ContainerLogs["bitrise-service-build-id-primary-postgres"] = "database system is ready, password: secret-password\n"
+	runtime.ContainerLogs["bitrise-service-build-id-primary-redis"] = "Ready to accept connections\n"
+	manager := newTestContainerManager(runtime)
+
+	services := map[string]models.Container{"postgres": {Image: "postgres"}, "redis": {Image: "redis"}}
+	_, err := manager.StartServiceContainers(services, "primary", envs)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.SaveServiceLogs("primary", envs))
+
+	postgresLogs, err := os.ReadFile(filepath.Join(deployDir, "service-logs", "primary", "postgres.log"))
+	require.NoError(t, err)
+	require.Equal(t, "database system is ready, password: [REDACTED]\n", string(postgresLogs))
+
+	redisLogs, err := os.ReadFile(filepath.Join(deployDir, "service-logs", "primary", "redis.log"))
+	require.NoError(t, err)
+	require.Equal(t, "Ready to accept connections\n", string(redisLogs))
+
+	// a workflow without services has no logs
+	require.NoError(t, manager.SaveServiceLogs("secondary", envs))
+	require.NoDirExists(t, filepath.Join(deployDir, "service-logs", "secondary"))
+}
+
+func TestContainerManager_DestroyAllContainersSavesServiceLogs(t *testing.T) {
+	deployDir := t.TempDir()
+	t.Setenv(configs.BitriseDeployDirEnvKey, deployDir)
+
+	runtime := NewFakeRuntime()
+	runtime.ContainerLogs["bitrise-service-build-id-primary-postgres"] = "database system is ready\n"
+	manager := newTestContainerManager(runtime)
+
+	_, err := manager.StartServiceContainers(map[string]models.Container{"postgres": {Image: "postgres"}}, "primary", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.DestroyAllContainers())
+	require.FileExists(t, filepath.Join(deployDir, "service-logs", "primary", "postgres.log"))
+	require.Empty(t, runtime.Containers)
+
+	// failing to get the logs doesn't prevent removing the containers
+	runtime.Errors["logs"] = errors.New("no such container")
+	manager = newTestContainerManager(runtime)
+	_, err = manager.StartServiceContainers(map[string]models.Container{"redis": {Image: "redis"}}, "primary", nil)
+	require.NoError(t, err)
+	require.NoError(t, manager.DestroyAllContainers())
+	require.Empty(t, runtime.Containers)
+}
@@ -0,0 +1,87 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+)
+
+const (
+	defaultBuildContainerUser = "root"
+	containerHomeDirName      = "container-home"
+)
+
+var numericUserRegexp = regexp.MustCompile(`^[0-9]+(:[0-9]+)?$`)
+
+// buildContainerUser returns the user of a workflow or step container: the host user if it is mapped,
+// the configured user, or root by default.
+func buildContainerUser(container models.Container, hostUser string) string {
+	if container.MapHostUser {
+		return hostUser
+	}
+	if container.User != "" {
+		return container.User
+	}
+	return defaultBuildContainerUser
+}
+
+// userEnvs returns the envs adjusting the container to its user. A user given by its uid most likely has no home dir in the image,
+// so HOME points to a writable dir: a dir in the mounted work dir (so it is shared by the containers of the build), or /tmp.
+func userEnvs(container models.Container, user string, volumes []string) ([]string, error) {
+	if !numericUserRegexp.MatchString(user) || isRootUser(user) {
+		return nil, nil
+	}
+	for _, env := range container.Envs {
+		if _, ok := env["HOME"]; ok {
+			return nil, nil
+		}
+	}
+
+	home := "/tmp"
+	for _, volume := range volumes {
+		if mountTarget(volume) == configs.BitriseWorkDirPath {
+			home = filepath.Join(configs.BitriseWorkDirPath, containerHomeDirName)
+			if err := os.MkdirAll(home, 0755); err != nil {
+				return nil, fmt.Errorf("create home dir: %w", err)
+			}
+			// the uid of the container's user might not be the uid of the host user
+			if err := os.Chmod(home, 0777); err != nil {
+				return nil, fmt.Errorf("create home dir: %w", err)
+			}
+			break
+		}
+	}
+	return []string{"HOME=" + home}, nil
+}
+
+// isSameUser returns whether the users are the same, root can be given by its name or its uid.
+func isSameUser(user, otherUser string) bool {
+	return user == otherUser || (isRootUser(user) && isRootUser(otherUser))
+}
+
+func isRootUser(user string) bool {
+	switch user {
+	case "root", "0", "root:root", "0:0":
+		return true
+	}
+	return false
+}
+
+// restoreBuildDirsOwnership hands the files created by the container in the mounted build dirs back to the host user,
+// so the build dirs can be cleaned up without sudo. It runs as root in the container, as only root can change the owner of a file.
+func (rc *RunningContainer) restoreBuildDirsOwnership() error {
+	uid := strings.SplitN(rc.buildDirsOwner, ":", 2)[0]
+	cmd := append([]string{"find"}, rc.buildDirs...)
+	cmd = append(cmd, "!", "-user", uid, "-exec", "chown", "-h", rc.buildDirsOwner, "{}", "+")
+
+	out, err := rc.Runtime.Exec(rc.Name, "0", cmd)
+	if err != nil && out != "" {
+		return fmt.Errorf("%w: %s", err, out)
+	}
+	return err
+}
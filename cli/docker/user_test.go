@@ -0,0 +1,144 @@
+package docker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerManager_ContainerUser(t *testing.T) {
+	origWorkDirPath := configs.BitriseWorkDirPath
+	configs.BitriseWorkDirPath = t.TempDir()
+	defer func() { configs.BitriseWorkDirPath = origWorkDirPath }()
+	t.Setenv(mountOverridesEnvKey, "")
+
+	envs := map[string]string{configs.BitriseSourceDirEnvKey: "/project", configs.BitriseDeployDirEnvKey: "/tmp/deploy"}
+	containerHome := filepath.Join(configs.BitriseWorkDirPath, containerHomeDirName)
+	restoreCommand := "exec --user 0 bitrise-step-step-id find /project /tmp/deploy " + configs.BitriseWorkDirPath + " ! -user 1001 -exec chown -h 1001:1001 {} +"
+
+	t.Run("root by default, the ownership of the build dirs is restored", func(t *testing.T) {
+		runtime := NewFakeRuntime()
+		manager := newTestContainerManager(runtime)
+
+		_, err := manager.StartStepContainer(models.Container{Image: "node:20"}, "primary", "step-id", envs)
+		require.NoError(t, err)
+		require.Equal(t, "root", runtime.Created[0].User)
+		require.Empty(t, runtime.Created[0].Envs)
+
+		require.NoError(t, manager.DestroyStepContainer("step-id"))
+		require.Equal(t, []string{restoreCommand, "rm bitrise-step-step-id"}, runtime.Commands[len(runtime.Commands)-2:])
+	})
+
+	t.Run("only the ownership of the default build dirs is restored", func(t *testing.T) {
+		runtime := NewFakeRuntime()
+		manager := newTestContainerManager(runtime)
+
+		_, err := manager.StartStepContainer(models.Container{Image: "node:20", Volumes: []string{"/cache:/cache"}}, "primary", "step-id", envs)
+		require.NoError(t, err)
+
+		require.NoError(t, manager.DestroyStepContainer("step-id"))
+		require.Equal(t, []string{restoreCommand}, filterCommands(runtime.Commands, "exec"))
+	})
+
+	t.Run("the ownership of the mount overrides is not restored", func(t *testing.T) {
+		t.Setenv(mountOverridesEnvKey, "/host/src:/bitrise/src")
+		runtime := NewFakeRuntime()
+		manager := newTestContainerManager(runtime)
+
+		_, err := manager.StartStepContainer(models.Container{Image: "node:20"}, "primary", "step-id", envs)
+		require.NoError(t, err)
+
+		require.NoError(t, manager.DestroyStepContainer("step-id"))
+		require.Empty(t, filterCommands(runtime.Commands, "exec"))
+	})
+
+	t.Run("host user", func(t *testing.T) {
+		runtime := NewFakeRuntime()
+		manager := newTestContainerManager(runtime)
+
+		_, err := manager.StartStepContainer(models.Container{Image: "node:20", MapHostUser: true}, "primary", "step-id", envs)
+		require.NoError(t, err)
+		require.Equal(t, "1001:1001", runtime.Created[0].User)
+		require.Equal(t, []string{"HOME=" + containerHome}, runtime.Created[0].Envs)
+		require.DirExists(t, containerHome)
+
+		require.NoError(t, manager.DestroyStepContainer("step-id"))
+		require.Empty(t, filterCommands(runtime.Commands, "exec"))
+	})
+
+	t.Run("uid with HOME set", func(t *testing.T) {
+		runtime := NewFakeRuntime()
+		manager := newTestContainerManager(runtime)
+
+		container := models.Container{Image: "node:20", User: "1000", Envs: []envmanModels.EnvironmentItemModel{{"HOME": "/home/node"}}}
+		_, err := manager.StartWorkflowContainer(container, "primary", envs)
+		require.NoError(t, err)
+		require.Equal(t, "1000", runtime.Created[0].User)
+		require.Equal(t, []string{"HOME=/home/node"}, runtime.Created[0].Envs)
+	})
+
+	t.Run("services run as the image's user", func(t *testing.T) {
+		runtime := NewFakeRuntime()
+		manager := newTestContainerManager(runtime)
+
+		_, err := manager.StartServiceContainers(map[string]models.Container{
+			"postgres": {Image: "postgres"},
+			"redis":    {Image: "redis", MapHostUser: true},
+		}, "primary", envs)
+		require.NoError(t, err)
+		require.Equal(t, "", runtime.Created[0].User)
+		require.Equal(t, "1001:1001", runtime.Created[1].User)
+	})
+
+	t.Run("the container is removed even if the ownership can't be restored", func(t *testing.T) {
+		runtime := NewFakeRuntime()
+		runtime.Errors["exec"] = errors.New("fake exec error")
+		manager := newTestContainerManager(runtime)
+
+		_, err := manager.StartStepContainer(models.Container{Image: "node:20"}, "primary", "step-id", envs)
+		require.NoError(t, err)
+
+		err = manager.DestroyStepContainer("step-id")
+		require.EqualError(t, err, "restore the ownership of the build dirs: fake exec error")
+		require.Empty(t, runtime.Containers)
+	})
+}
+
+func TestUserEnvs(t *testing.T) {
+	workDir := t.TempDir()
+	origWorkDirPath := configs.BitriseWorkDirPath
+	configs.BitriseWorkDirPath = workDir
+	defer func() { configs.BitriseWorkDirPath = origWorkDirPath }()
+
+	workDirVolume := workDir + ":" + workDir
+
+	tests := []struct {
+		name    string
+		user    string
+		volumes []string
+		want    []string
+	}{
+		{name: "root", user: "root", volumes: []string{workDirVolume}},
+		{name: "root uid", user: "0:0", volumes: []string{workDirVolume}},
+		{name: "user name", user: "node", volumes: []string{workDirVolume}},
+		{name: "uid", user: "1000:1000", volumes: []string{workDirVolume}, want: []string{"HOME=" + filepath.Join(workDir, containerHomeDirName)}},
+		{name: "uid without the work dir mounted", user: "1000", volumes: []string{"/src:/bitrise/src"}, want: []string{"HOME=/tmp"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := userEnvs(models.Container{}, tt.user, tt.volumes)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	info, err := os.Stat(filepath.Join(workDir, containerHomeDirName))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0777), info.Mode().Perm())
+}
@@ -0,0 +1,100 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+)
+
+const (
+	mountOverridesEnvKey = "BITRISE_DOCKER_MOUNT_OVERRIDES"
+	overriddenSourceDir  = "/bitrise/src"
+)
+
+// containerVolumes returns the volumes to mount into a workflow or step container and the working dir of the container.
+// The container's own volumes are added on top of the build dir mounts, with their env var references expanded.
+func containerVolumes(container models.Container, envs map[string]string) ([]string, string) {
+	volumes, workingDir := buildDirMounts(envs)
+	return append(volumes, ownVolumes(container, envs)...), workingDir
+}
+
+// ownVolumes returns the volumes of the container (or service) with their env var references expanded.
+func ownVolumes(container models.Container, envs map[string]string) []string {
+	var volumes []string
+	for _, volume := range container.Volumes {
+		volumes = append(volumes, expandEnvs(volume, envs))
+	}
+	return volumes
+}
+
+// buildDirMounts returns the mounts of the build dirs and the working dir of the container.
+//
+// BITRISE_DOCKER_MOUNT_OVERRIDES (a comma separated list of volumes) replaces the default mounts, in this case the source dir
+// is expected to be mounted to /bitrise/src. Otherwise the source dir, the deploy dir, the test deploy dir and the work dir
+// (holding the envstores and the step sources) are mounted to the same paths as on the host, so that the BITRISE_* dir envs
+// point to the same files in the container as for the steps running on the host.
+func buildDirMounts(envs map[string]string) ([]string, string) {
+	var volumes []string
+
+	if overrides := os.Getenv(mountOverridesEnvKey); overrides != "" {
+		for _, volume := range strings.Split(overrides, ",") {
+			if volume != "" {
+				volumes = append(volumes, volume)
+			}
+		}
+		return volumes, overriddenSourceDir
+	}
+
+	for _, dir := range defaultBuildDirs(envs) {
+		volumes = append(volumes, fmt.Sprintf("%s:%s", dir, dir))
+	}
+	return volumes, lookupEnv(configs.BitriseSourceDirEnvKey, envs)
+}
+
+// defaultBuildDirs returns the build dirs mounted by default: the source dir, the deploy dir, the test deploy dir and the work dir.
+func defaultBuildDirs(envs map[string]string) []string {
+	dirs := []string{
+		lookupEnv(configs.BitriseSourceDirEnvKey, envs),
+		lookupEnv(configs.BitriseDeployDirEnvKey, envs),
+		lookupEnv(configs.BitriseTestDeployDirEnvKey, envs),
+		configs.BitriseWorkDirPath,
+	}
+
+	var buildDirs []string
+	mounted := map[string]bool{}
+	for _, dir := range dirs {
+		if dir == "" || mounted[dir] {
+			continue
+		}
+		mounted[dir] = true
+		buildDirs = append(buildDirs, dir)
+	}
+	return buildDirs
+}
+
+// mountTarget returns the container path of a <host path>:<container path>[:<options>] volume.
+func mountTarget(volume string) string {
+	parts := strings.Split(volume, ":")
+	if len(parts) < 2 {
+		return volume
+	}
+	return parts[1]
+}
+
+// lookupEnv returns the value of the env from the envs of the run, falling back to the process env.
+func lookupEnv(key string, envs map[string]string) string {
+	if value, ok := envs[key]; ok {
+		return value
+	}
+	return os.Getenv(key)
+}
+
+// expandEnvs expands the env var references of the value with the envs of the run, falling back to the process env.
+func expandEnvs(value string, envs map[string]string) string {
+	return os.Expand(value, func(key string) string {
+		return lookupEnv(key, envs)
+	})
+}
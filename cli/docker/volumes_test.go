@@ -0,0 +1,78 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerVolumes(t *testing.T) {
+	origWorkDirPath := configs.BitriseWorkDirPath
+	configs.BitriseWorkDirPath = "/tmp/bitrise-work"
+	defer func() { configs.BitriseWorkDirPath = origWorkDirPath }()
+
+	t.Setenv(configs.BitriseSourceDirEnvKey, "/project")
+	t.Setenv(configs.BitriseDeployDirEnvKey, "/tmp/deploy")
+	t.Setenv(configs.BitriseTestDeployDirEnvKey, "/tmp/test-results")
+	t.Setenv(mountOverridesEnvKey, "")
+
+	container := models.Container{
+		Image:   "ubuntu",
+		Volumes: []string{"$BITRISE_SOURCE_DIR/.gradle:/root/.gradle", "${CACHE_DIR}:/cache:ro"},
+	}
+
+	t.Run("default mounts", func(t *testing.T) {
+		volumes, workingDir := containerVolumes(container, map[string]string{"CACHE_DIR": "/tmp/cache"})
+		require.Equal(t, "/project", workingDir)
+		require.Equal(t, []string{
+			"/project:/project",
+			"/tmp/deploy:/tmp/deploy",
+			"/tmp/test-results:/tmp/test-results",
+			"/tmp/bitrise-work:/tmp/bitrise-work",
+			"/project/.gradle:/root/.gradle",
+			"/tmp/cache:/cache:ro",
+		}, volumes)
+	})
+
+	t.Run("envs of the run take precedence", func(t *testing.T) {
+		volumes, workingDir := containerVolumes(models.Container{}, map[string]string{
+			configs.BitriseSourceDirEnvKey: "/project/app",
+			configs.BitriseDeployDirEnvKey: "/project/app",
+		})
+		require.Equal(t, "/project/app", workingDir)
+		require.Equal(t, []string{
+			"/project/app:/project/app",
+			"/tmp/test-results:/tmp/test-results",
+			"/tmp/bitrise-work:/tmp/bitrise-work",
+		}, volumes)
+	})
+
+	t.Run("mount overrides", func(t *testing.T) {
+		t.Setenv(mountOverridesEnvKey, "/src:/bitrise/src,,/deploy:/bitrise/deploy")
+
+		volumes, workingDir := containerVolumes(container, map[string]string{"CACHE_DIR": "/tmp/cache"})
+		require.Equal(t, "/bitrise/src", workingDir)
+		require.Equal(t, []string{
+			"/src:/bitrise/src",
+			"/deploy:/bitrise/deploy",
+			"/project/.gradle:/root/.gradle",
+			"/tmp/cache:/cache:ro",
+		}, volumes)
+	})
+}
+
+func TestOwnVolumes(t *testing.T) {
+	t.Setenv(configs.BitriseSourceDirEnvKey, "/project")
+	t.Setenv(mountOverridesEnvKey, "/src:/bitrise/src")
+
+	service := models.Container{
+		Image:   "postgres:16",
+		Volumes: []string{"${DATA_DIR}:/var/lib/postgresql/data"},
+	}
+
+	// the build dirs (and their overrides) are not mounted, only the container's own volumes
+	require.Equal(t, []string{"/tmp/data:/var/lib/postgresql/data"}, ownVolumes(service, map[string]string{"DATA_DIR": "/tmp/data"}))
+	require.Empty(t, ownVolumes(models.Container{Image: "redis"}, map[string]string{}))
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/log"
+	"github.com/bitrise-io/bitrise/models"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/command"
+)
+
+const pipelineArtifactsDirName = "pipeline_artifacts"
+
+// pipelineArtifactStore stores the files and envs exported by the workflows of a pipeline run,
+// so that they can be passed to the later workflows of the same run.
+// The exported files are copied under the Bitrise data home dir, the exported file envs point to these copies,
+// the exported envs are kept in memory, as they are only used by the same run.
+type pipelineArtifactStore struct {
+	rootDir string
+
+	mu   sync.Mutex
+	envs map[string][]envmanModels.EnvironmentItemModel
+}
+
+func newPipelineArtifactStore(buildExecutionID string) (*pipelineArtifactStore, error) {
+	rootDir := filepath.Join(configs.GetBitriseHomeDirPath(), pipelineArtifactsDirName, buildExecutionID)
+	if err := os.MkdirAll(rootDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create artifact store dir: %s", err)
+	}
+
+	return &pipelineArtifactStore{
+		rootDir: rootDir,
+		envs:    map[string][]envmanModels.EnvironmentItemModel{},
+	}, nil
+}
+
+// export stores the exported files and envs of the workflow.
+// envs are the expanded envs of the finished workflow, the exported envs and file paths are resolved from these.
+// A missing env or file is not an error, it is only reported as a warning.
+func (s *pipelineArtifactStore) export(workflowID string, exports models.WorkflowExportsModel, envs map[string]string) error {
+	lookup := func(key string) string {
+		if value, ok := envs[key]; ok {
+			return value
+		}
+		return os.Getenv(key)
+	}
+
+	var exported []envmanModels.EnvironmentItemModel
+
+	for _, key := range exports.Envs {
+		value, ok := envs[key]
+		if !ok {
+			log.Warnf("Env (%s) exported by workflow (%s) is not set, skipping...", key, workflowID)
+			continue
+		}
+		exported = append(exported, envmanModels.EnvironmentItemModel{key: value})
+	}
+
+	for _, file := range exports.Files {
+		pth := os.Expand(file.Path, lookup)
+
+		info, err := os.Stat(pth)
+		if os.IsNotExist(err) {
+			log.Warnf("File (%s) exported by workflow (%s) does not exist, skipping...", pth, workflowID)
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to check exported file (%s): %s", pth, err)
+		}
+
+		dstDir := filepath.Join(s.rootDir, workflowID, file.EnvKey)
+		if err := os.MkdirAll(dstDir, 0755); err != nil {
+			return fmt.Errorf("failed to create dir for exported file (%s): %s", pth, err)
+		}
+
+		dst := filepath.Join(dstDir, filepath.Base(pth))
+		if info.IsDir() {
+			err = command.CopyDir(pth, dst, true)
+		} else {
+			err = command.CopyFile(pth, dst)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to store exported file (%s): %s", pth, err)
+		}
+
+		exported = append(exported, envmanModels.EnvironmentItemModel{file.EnvKey: dst})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.envs[workflowID] = exported
+
+	return nil
+}
+
+// environments returns the envs exported by the given workflows, in the order of the workflows,
+// so a later workflow's export overrides an earlier one's with the same key.
+func (s *pipelineArtifactStore) environments(workflowIDs []string) []envmanModels.EnvironmentItemModel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var environments []envmanModels.EnvironmentItemModel
+	for _, workflowID := range workflowIDs {
+		environments = append(environments, s.envs[workflowID]...)
+	}
+	return environments
+}
+
+// cleanup removes the stored files of the pipeline run.
+func (s *pipelineArtifactStore) cleanup() error {
+	return os.RemoveAll(s.rootDir)
+}
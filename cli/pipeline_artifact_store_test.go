@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/models"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelineArtifactStore(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	deployDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(deployDir, "app.ipa"), []byte("ipa"), 0600))
+
+	store, err := newPipelineArtifactStore("build-id")
+	require.NoError(t, err)
+
+	exports := models.WorkflowExportsModel{
+		Envs: []string{"APP_VERSION", "NOT_SET"},
+		Files: []models.ExportedFileModel{
+			{Path: "$BITRISE_DEPLOY_DIR/app.ipa", EnvKey: "BITRISE_IPA_PATH"},
+			{Path: "$BITRISE_DEPLOY_DIR/missing.apk", EnvKey: "BITRISE_APK_PATH"},
+		},
+	}
+	envs := map[string]string{
+		"APP_VERSION":        "1.0.0",
+		"BITRISE_DEPLOY_DIR": deployDir,
+	}
+	require.NoError(t, store.export("build", exports, envs))
+
+	storedIPAPath := filepath.Join(store.rootDir, "build", "BITRISE_IPA_PATH", "app.ipa")
+	content, err := os.ReadFile(storedIPAPath)
+	require.NoError(t, err)
+	require.Equal(t, "ipa", string(content))
+
+	require.Equal(t, []envmanModels.EnvironmentItemModel{
+		{"APP_VERSION": "1.0.0"},
+		{"BITRISE_IPA_PATH": storedIPAPath},
+	}, store.environments([]string{"build", "unknown"}))
+
+	require.NoError(t, store.cleanup())
+	require.NoDirExists(t, store.rootDir)
+}
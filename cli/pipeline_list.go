@@ -0,0 +1,372 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/bitrise/log"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/bitrise-io/bitrise/output"
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/urfave/cli"
+)
+
+const (
+	// formatDOT prints the pipelines as a Graphviz DOT graph
+	formatDOT = "dot"
+	// formatMermaid prints the pipelines as a Mermaid flowchart
+	formatMermaid = "mermaid"
+)
+
+var pipelineListCommand = cli.Command{
+	Name:  "pipelines",
+	Usage: "List of available pipelines in config, with their stages and workflows.",
+	Action: func(c *cli.Context) error {
+		if err := pipelineList(c); err != nil {
+			log.Errorf("List of available pipelines in config failed, error: %s", err)
+			os.Exit(1)
+		}
+		return nil
+	},
+	Flags: []cli.Flag{
+		flConfig,
+		flConfigBase64,
+		cli.StringFlag{
+			Name:  "format",
+			Usage: "Output format. Accepted: raw, json, dot, mermaid.",
+		},
+		cli.StringFlag{
+			Name:  PipelineKey,
+			Usage: "Print only the specified pipeline.",
+		},
+	},
+}
+
+// PipelineListOutputModel ...
+type PipelineListOutputModel struct {
+	Pipelines []PipelineInfoModel `json:"pipelines"`
+	Warnings  []string            `json:"warnings,omitempty"`
+}
+
+// PipelineInfoModel ...
+type PipelineInfoModel struct {
+	ID          string                      `json:"id"`
+	Title       string                      `json:"title,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Stages      []PipelineStageInfoModel    `json:"stages,omitempty"`
+	Workflows   []PipelineWorkflowInfoModel `json:"workflows,omitempty"`
+}
+
+// PipelineStageInfoModel ...
+type PipelineStageInfoModel struct {
+	ID        string                      `json:"id"`
+	Title     string                      `json:"title,omitempty"`
+	Workflows []PipelineWorkflowInfoModel `json:"workflows"`
+}
+
+// PipelineWorkflowInfoModel ...
+type PipelineWorkflowInfoModel struct {
+	ID        string   `json:"id"`
+	DependsOn []string `json:"depends_on,omitempty"`
+	// Chain lists the workflow together with its before_run and after_run workflows, in execution order.
+	Chain []string `json:"chain"`
+}
+
+// pipelineGraphNode is a workflow run of a pipeline's execution graph,
+// dependsOn holds the indexes of the workflow runs which have to finish before this one starts.
+type pipelineGraphNode struct {
+	workflow  PipelineWorkflowInfoModel
+	stageID   string
+	dependsOn []int
+}
+
+func newPipelineInfo(pipelineID string, pipeline models.PipelineModel, config models.BitriseDataModel) (PipelineInfoModel, error) {
+	info := PipelineInfoModel{
+		ID:          pipelineID,
+		Title:       pipeline.Title,
+		Summary:     pipeline.Summary,
+		Description: pipeline.Description,
+	}
+
+	for _, stageListItem := range pipeline.Stages {
+		stageID, err := models.GetStageIDFromListItemModel(stageListItem)
+		if err != nil {
+			return PipelineInfoModel{}, err
+		}
+
+		stage, ok := config.Stages[stageID]
+		if !ok {
+			return PipelineInfoModel{}, fmt.Errorf("stage (%s) defined in pipeline (%s), but does not exist", stageID, pipelineID)
+		}
+
+		stageInfo := PipelineStageInfoModel{ID: stageID, Title: stage.Title}
+		for _, workflowListItem := range stage.Workflows {
+			workflowID, err := models.GetWorkflowIDFromListItemModel(workflowListItem)
+			if err != nil {
+				return PipelineInfoModel{}, err
+			}
+
+			stageInfo.Workflows = append(stageInfo.Workflows, PipelineWorkflowInfoModel{
+				ID:    workflowID,
+				Chain: walkWorkflows(workflowID, config.Workflows, nil),
+			})
+		}
+		info.Stages = append(info.Stages, stageInfo)
+	}
+
+	for _, workflowID := range topologicalGraphWorkflowOrder(pipeline.Workflows) {
+		info.Workflows = append(info.Workflows, PipelineWorkflowInfoModel{
+			ID:        workflowID,
+			DependsOn: pipeline.Workflows[workflowID].DependsOn,
+			Chain:     walkWorkflows(workflowID, config.Workflows, nil),
+		})
+	}
+
+	return info, nil
+}
+
+// graphNodes returns the workflow runs of the pipeline:
+// a stage's workflows depend on all the workflows of the previous stage,
+// a graph pipeline's workflows depend on their depends_on workflows.
+func (info PipelineInfoModel) graphNodes() []pipelineGraphNode {
+	var nodes []pipelineGraphNode
+
+	var previousStageNodes []int
+	for _, stage := range info.Stages {
+		var stageNodes []int
+		for _, workflow := range stage.Workflows {
+			stageNodes = append(stageNodes, len(nodes))
+			nodes = append(nodes, pipelineGraphNode{
+				workflow:  workflow,
+				stageID:   stage.ID,
+				dependsOn: previousStageNodes,
+			})
+		}
+		previousStageNodes = stageNodes
+	}
+
+	nodeIdxByWorkflowID := map[string]int{}
+	for _, workflow := range info.Workflows {
+		node := pipelineGraphNode{workflow: workflow}
+		for _, dependencyID := range workflow.DependsOn {
+			node.dependsOn = append(node.dependsOn, nodeIdxByWorkflowID[dependencyID])
+		}
+		nodeIdxByWorkflowID[workflow.ID] = len(nodes)
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
+// String ...
+func (output PipelineListOutputModel) String() string {
+	message := ""
+	for _, warning := range output.Warnings {
+		message += colorstring.Yellow(warning) + "\n"
+	}
+
+	if len(output.Pipelines) == 0 {
+		return message + colorstring.Red("Config doesn't contain any pipeline")
+	}
+
+	message += "Pipelines\n"
+	message += "---------\n"
+	for _, pipeline := range output.Pipelines {
+		message += fmt.Sprintf("⚡️ %s\n", colorstring.Green(pipeline.ID))
+		if pipeline.Title != "" {
+			message += fmt.Sprintf("  %s: %s\n", colorstring.Yellow("Title"), pipeline.Title)
+		}
+		if pipeline.Summary != "" {
+			message += fmt.Sprintf("  %s: %s\n", colorstring.Yellow("Summary"), pipeline.Summary)
+		}
+		if pipeline.Description != "" {
+			message += fmt.Sprintf("  %s: %s\n", colorstring.Yellow("Description"), pipeline.Description)
+		}
+
+		if len(pipeline.Stages) > 0 {
+			message += fmt.Sprintf("  %s:\n", colorstring.Yellow("Stages"))
+			for i, stage := range pipeline.Stages {
+				message += fmt.Sprintf("    %d. %s\n", i+1, stage.ID)
+				for _, workflow := range stage.Workflows {
+					message += fmt.Sprintf("       - %s\n", strings.Join(workflow.Chain, " -> "))
+				}
+			}
+		}
+
+		if len(pipeline.Workflows) > 0 {
+			message += fmt.Sprintf("  %s:\n", colorstring.Yellow("Workflows"))
+			for _, workflow := range pipeline.Workflows {
+				message += fmt.Sprintf("    - %s", strings.Join(workflow.Chain, " -> "))
+				if len(workflow.DependsOn) > 0 {
+					message += fmt.Sprintf(" (depends on: %s)", strings.Join(workflow.DependsOn, ", "))
+				}
+				message += "\n"
+			}
+		}
+
+		message += fmt.Sprintf("  %s: bitrise run --pipeline %s\n", colorstring.Yellow("Run with"), pipeline.ID)
+		message += "\n"
+	}
+
+	return message
+}
+
+// JSON ...
+func (output PipelineListOutputModel) JSON() string {
+	data, err := json.MarshalIndent(output, "", "\t")
+	if err != nil {
+		return fmt.Sprintf(`{"error":"%s"}`, err.Error())
+	}
+	return string(data) + "\n"
+}
+
+// DOT renders the execution graph of the pipelines in the Graphviz DOT language:
+// every workflow run is a cluster of its before_run, own and after_run workflows.
+func (output PipelineListOutputModel) DOT() string {
+	message := "digraph pipelines {\n"
+	message += "  compound=true;\n"
+	message += "  node [shape=box];\n"
+
+	for pipelineIdx, pipeline := range output.Pipelines {
+		message += fmt.Sprintf("  subgraph cluster_p%d {\n", pipelineIdx)
+		message += fmt.Sprintf("    label=%q;\n", pipeline.ID)
+
+		nodes := pipeline.graphNodes()
+		for nodeIdx, node := range nodes {
+			message += fmt.Sprintf("    subgraph cluster_p%d_w%d {\n", pipelineIdx, nodeIdx)
+			message += fmt.Sprintf("      label=%q;\n", graphNodeLabel(node))
+			for chainIdx, workflowID := range node.workflow.Chain {
+				message += fmt.Sprintf("      %s [label=%q];\n", graphChainNodeID(pipelineIdx, nodeIdx, chainIdx), workflowID)
+			}
+			for chainIdx := 1; chainIdx < len(node.workflow.Chain); chainIdx++ {
+				message += fmt.Sprintf("      %s -> %s;\n", graphChainNodeID(pipelineIdx, nodeIdx, chainIdx-1), graphChainNodeID(pipelineIdx, nodeIdx, chainIdx))
+			}
+			message += "    }\n"
+		}
+
+		for nodeIdx, node := range nodes {
+			for _, dependencyIdx := range node.dependsOn {
+				from := graphChainNodeID(pipelineIdx, dependencyIdx, len(nodes[dependencyIdx].workflow.Chain)-1)
+				to := graphChainNodeID(pipelineIdx, nodeIdx, 0)
+				message += fmt.Sprintf("    %s -> %s [ltail=cluster_p%d_w%d, lhead=cluster_p%d_w%d];\n", from, to, pipelineIdx, dependencyIdx, pipelineIdx, nodeIdx)
+			}
+		}
+
+		message += "  }\n"
+	}
+
+	message += "}\n"
+	return message
+}
+
+// Mermaid renders the execution graph of the pipelines as a Mermaid flowchart:
+// every workflow run is a subgraph of its before_run, own and after_run workflows.
+func (output PipelineListOutputModel) Mermaid() string {
+	message := "flowchart TD\n"
+
+	for pipelineIdx, pipeline := range output.Pipelines {
+		message += fmt.Sprintf("  subgraph p%d [%q]\n", pipelineIdx, pipeline.ID)
+
+		nodes := pipeline.graphNodes()
+		for nodeIdx, node := range nodes {
+			message += fmt.Sprintf("    subgraph p%d_w%d [%q]\n", pipelineIdx, nodeIdx, graphNodeLabel(node))
+			for chainIdx, workflowID := range node.workflow.Chain {
+				message += fmt.Sprintf("      %s[%q]\n", graphChainNodeID(pipelineIdx, nodeIdx, chainIdx), workflowID)
+			}
+			for chainIdx := 1; chainIdx < len(node.workflow.Chain); chainIdx++ {
+				message += fmt.Sprintf("      %s --> %s\n", graphChainNodeID(pipelineIdx, nodeIdx, chainIdx-1), graphChainNodeID(pipelineIdx, nodeIdx, chainIdx))
+			}
+			message += "    end\n"
+		}
+
+		for nodeIdx, node := range nodes {
+			for _, dependencyIdx := range node.dependsOn {
+				message += fmt.Sprintf("    p%d_w%d --> p%d_w%d\n", pipelineIdx, dependencyIdx, pipelineIdx, nodeIdx)
+			}
+		}
+
+		message += "  end\n"
+	}
+
+	return message
+}
+
+func graphNodeLabel(node pipelineGraphNode) string {
+	if node.stageID != "" {
+		return fmt.Sprintf("%s: %s", node.stageID, node.workflow.ID)
+	}
+	return node.workflow.ID
+}
+
+func graphChainNodeID(pipelineIdx, nodeIdx, chainIdx int) string {
+	return fmt.Sprintf("p%d_w%d_%d", pipelineIdx, nodeIdx, chainIdx)
+}
+
+func pipelineList(c *cli.Context) error {
+	bitriseConfigBase64Data := c.String(ConfigBase64Key)
+	bitriseConfigPath := c.String(ConfigKey)
+	pipelineID := c.String(PipelineKey)
+
+	format := c.String("format")
+	if format == "" {
+		format = output.FormatRaw
+	}
+	if format != output.FormatRaw && format != output.FormatJSON && format != formatDOT && format != formatMermaid {
+		showSubcommandHelp(c)
+		return fmt.Errorf("invalid format: %s", format)
+	}
+
+	bitriseConfig, warnings, err := CreateBitriseConfigFromCLIParams(bitriseConfigBase64Data, bitriseConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to create bitrise config: %s", err)
+	}
+
+	pipelineListOutput, err := createPipelineListOutput(bitriseConfig, pipelineID)
+	if err != nil {
+		return err
+	}
+	pipelineListOutput.Warnings = warnings
+
+	switch format {
+	case formatDOT:
+		fmt.Print(pipelineListOutput.DOT())
+	case formatMermaid:
+		fmt.Print(pipelineListOutput.Mermaid())
+	case output.FormatJSON:
+		NewDefaultJSONLogger().Print(pipelineListOutput)
+	default:
+		NewDefaultRawLogger().Print(pipelineListOutput)
+	}
+
+	return nil
+}
+
+func createPipelineListOutput(config models.BitriseDataModel, pipelineID string) (PipelineListOutputModel, error) {
+	var pipelineIDs []string
+	if pipelineID != "" {
+		if _, ok := config.Pipelines[pipelineID]; !ok {
+			return PipelineListOutputModel{}, fmt.Errorf("specified Pipeline (%s) does not exist", pipelineID)
+		}
+		pipelineIDs = []string{pipelineID}
+	} else {
+		for id := range config.Pipelines {
+			pipelineIDs = append(pipelineIDs, id)
+		}
+		sort.Strings(pipelineIDs)
+	}
+
+	pipelineListOutput := PipelineListOutputModel{Pipelines: []PipelineInfoModel{}}
+	for _, id := range pipelineIDs {
+		info, err := newPipelineInfo(id, config.Pipelines[id], config)
+		if err != nil {
+			return PipelineListOutputModel{}, err
+		}
+		pipelineListOutput.Pipelines = append(pipelineListOutput.Pipelines, info)
+	}
+
+	return pipelineListOutput, nil
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/stretchr/testify/require"
+)
+
+const pipelineListTestConfig = `
+format_version: "13"
+default_step_lib_source: "https://github.com/bitrise-io/bitrise-steplib.git"
+
+pipelines:
+  staged:
+    stages:
+    - build: {}
+    - test: {}
+  graph:
+    workflows:
+      build: {}
+      test:
+        depends_on: [ build ]
+
+stages:
+  build:
+    workflows:
+    - build: {}
+  test:
+    workflows:
+    - test: {}
+    - lint: {}
+
+workflows:
+  _setup: {}
+  _cleanup: {}
+  build:
+    before_run: [ _setup ]
+    after_run: [ _cleanup ]
+  test: {}
+  lint: {}
+`
+
+func TestCreatePipelineListOutput(t *testing.T) {
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(pipelineListTestConfig))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	pipelineListOutput, err := createPipelineListOutput(config, "")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(pipelineListOutput.Pipelines))
+
+	graph := pipelineListOutput.Pipelines[0]
+	require.Equal(t, "graph", graph.ID)
+	require.Equal(t, []PipelineWorkflowInfoModel{
+		{ID: "build", Chain: []string{"_setup", "build", "_cleanup"}},
+		{ID: "test", DependsOn: []string{"build"}, Chain: []string{"test"}},
+	}, graph.Workflows)
+
+	staged := pipelineListOutput.Pipelines[1]
+	require.Equal(t, "staged", staged.ID)
+	require.Equal(t, []PipelineStageInfoModel{
+		{ID: "build", Workflows: []PipelineWorkflowInfoModel{{ID: "build", Chain: []string{"_setup", "build", "_cleanup"}}}},
+		{ID: "test", Workflows: []PipelineWorkflowInfoModel{{ID: "test", Chain: []string{"test"}}, {ID: "lint", Chain: []string{"lint"}}}},
+	}, staged.Stages)
+
+	_, err = createPipelineListOutput(config, "missing")
+	require.EqualError(t, err, "specified Pipeline (missing) does not exist")
+}
+
+func TestPipelineListOutputModel_Graphs(t *testing.T) {
+	config, _, err := bitrise.ConfigModelFromYAMLBytes([]byte(pipelineListTestConfig))
+	require.NoError(t, err)
+
+	pipelineListOutput, err := createPipelineListOutput(config, "staged")
+	require.NoError(t, err)
+
+	require.Equal(t, `flowchart TD
+  subgraph p0 ["staged"]
+    subgraph p0_w0 ["build: build"]
+      p0_w0_0["_setup"]
+      p0_w0_1["build"]
+      p0_w0_2["_cleanup"]
+      p0_w0_0 --> p0_w0_1
+      p0_w0_1 --> p0_w0_2
+    end
+    subgraph p0_w1 ["test: test"]
+      p0_w1_0["test"]
+    end
+    subgraph p0_w2 ["test: lint"]
+      p0_w2_0["lint"]
+    end
+    p0_w0 --> p0_w1
+    p0_w0 --> p0_w2
+  end
+`, pipelineListOutput.Mermaid())
+
+	require.Equal(t, `digraph pipelines {
+  compound=true;
+  node [shape=box];
+  subgraph cluster_p0 {
+    label="staged";
+    subgraph cluster_p0_w0 {
+      label="build: build";
+      p0_w0_0 [label="_setup"];
+      p0_w0_1 [label="build"];
+      p0_w0_2 [label="_cleanup"];
+      p0_w0_0 -> p0_w0_1;
+      p0_w0_1 -> p0_w0_2;
+    }
+    subgraph cluster_p0_w1 {
+      label="test: test";
+      p0_w1_0 [label="test"];
+    }
+    subgraph cluster_p0_w2 {
+      label="test: lint";
+      p0_w2_0 [label="lint"];
+    }
+    p0_w0_2 -> p0_w1_0 [ltail=cluster_p0_w0, lhead=cluster_p0_w1];
+    p0_w0_2 -> p0_w2_0 [ltail=cluster_p0_w0, lhead=cluster_p0_w2];
+  }
+}
+`, pipelineListOutput.DOT())
+}
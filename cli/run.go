@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"sort"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -42,26 +43,44 @@ const (
 
 var workflowNotSpecifiedErr = errors.New("workflow not specified")
 var utilityWorkflowSpecifiedErr = errors.New("utility workflow specified")
+var workflowAndPipelineSpecifiedErr = errors.New("both workflow and pipeline specified")
+var resumePipelineErr = errors.New("resuming a pipeline is not supported")
 var workflowRunFailedErr = errors.New("workflow run failed")
 
 type RunConfig struct {
 	Modes    models.WorkflowRunModes
 	Config   models.BitriseDataModel
 	Workflow string
+	// Pipeline is the ID of the pipeline to run, if set the Workflow is ignored
+	Pipeline string
 	Secrets  []envmanModels.EnvironmentItemModel
+	// FromWorkflow and FromStep set where a previous run of the Workflow should be resumed from
+	FromWorkflow string
+	FromStep     string
+	// Checkpoints saves the state of the run before each step, so that the run can be resumed
+	Checkpoints bool
+	// KeepContainers skips removing the docker containers of a failed workflow, so that they can be debugged
+	KeepContainers bool
 }
 
 var runCommand = cli.Command{
 	Name:    "run",
 	Aliases: []string{"r"},
-	Usage:   "Runs a specified Workflow.",
+	Usage:   "Runs a specified Workflow or Pipeline.",
 	Action:  run,
 	Flags: []cli.Flag{
 		// cli params
 		cli.StringFlag{Name: WorkflowKey, Usage: "workflow id to run."},
+		cli.StringFlag{Name: PipelineKey, Usage: "pipeline id to run."},
 		cli.StringFlag{Name: ConfigKey + ", " + configShortKey, Usage: "Path where the workflow config file is located."},
 		cli.StringFlag{Name: InventoryKey + ", " + inventoryShortKey, Usage: "Path of the inventory file."},
 		cli.BoolFlag{Name: secretFilteringFlag, Usage: "Hide secret values from the log."},
+		cli.BoolFlag{Name: DryRunKey, Usage: "Print the execution plan (workflows, resolved step versions, inputs and run_if results) without running any step."},
+		cli.StringFlag{Name: DryRunFormatKey, Usage: "Format of the --dry-run execution plan. Accepted: table, json."},
+		cli.StringFlag{Name: FromStepKey, Usage: "Resume the previous run of the workflow from the given step (step index starting from 0, or step ID)."},
+		cli.StringFlag{Name: FromWorkflowKey, Usage: "Resume the previous run of the workflow from the given before_run or after_run workflow."},
+		cli.BoolFlag{Name: CheckpointsKey, Usage: "Save the state of the run before each step, so that the run can be resumed with --from-step or --from-workflow."},
+		cli.BoolFlag{Name: KeepContainersKey, Usage: "Keep the docker containers of a failed workflow, use `bitrise container shell` to open a shell in them."},
 
 		// cli params used in CI mode
 		cli.StringFlag{Name: JSONParamsKey, Usage: "Specify command flags with json string-string hash."},
@@ -92,10 +111,29 @@ func run(c *cli.Context) error {
 		} else if err == utilityWorkflowSpecifiedErr {
 			printAboutUtilityWorkflowsText()
 			failf("Utility workflows can't be triggered directly")
+		} else if err == workflowAndPipelineSpecifiedErr {
+			failf("Either a workflow or a pipeline can be run, not both")
+		} else if err == resumePipelineErr {
+			failf("--%s and --%s can only be used with a workflow", FromStepKey, FromWorkflowKey)
 		}
 		failf("Failed to process arguments: %s", err)
 	}
 
+	if c.Bool(DryRunKey) {
+		format := c.String(DryRunFormatKey)
+		if format == "" {
+			format = dryRunFormatTable
+		}
+		if format != dryRunFormatTable && format != dryRunFormatJSON {
+			failf("Invalid dry run format: %s", format)
+		}
+
+		if err := printDryRunPlan(*config, format); err != nil {
+			failf("Failed to create execution plan: %s", err)
+		}
+		return nil
+	}
+
 	agentConfig, err := setupAgentConfig()
 	if err != nil {
 		failf("Failed to process agent config: %w", err)
@@ -113,9 +151,13 @@ func run(c *cli.Context) error {
 		<-signalInterruptChan
 		shouldWaitForCleanup = true
 		log.Info("Cancelling bitrise run...")
-		if err := runner.dockerManager.DestroyAllContainers(); err != nil {
+		runner.cancelBuild()
+		if runner.config.KeepContainers {
+			log.Warnf("Keeping the docker containers, remove them with: docker rm --force <container>")
+		} else if err := runner.dockerManager.DestroyAllContainers(); err != nil {
 			log.Warnf("Failed to destroy all containers: %s", err)
 		}
+		runner.removeRegistryConfig()
 		cleanupSynchronCancelFunc()
 	}()
 
@@ -170,28 +212,67 @@ func setupAgentConfig() (*configs.AgentConfig, error) {
 
 type WorkflowRunner struct {
 	config RunConfig
+	// buildExecutionID identifies the run in the analytics events, the docker networks and container names
+	buildExecutionID string
 
 	// agentConfig is only non-nil if the CLI is configured to run in agent mode
 	agentConfig   *configs.AgentConfig
 	dockerManager DockerManager
+
+	// stepPreparationLock serializes the step activations and toolkit preparations of concurrently running workflows,
+	// as these share the host wide steplib and toolkit caches.
+	stepPreparationLock *sync.Mutex
+
+	// buildContext is cancelled when the build is interrupted (e.g. Ctrl-C), cancelBuild cancels it.
+	buildContext context.Context
+	cancelBuild  context.CancelFunc
+
+	// artifactStore is only non-nil during a pipeline run, it passes the exports of the workflows to the later workflows
+	artifactStore *pipelineArtifactStore
 }
 
 func NewWorkflowRunner(config RunConfig, agentConfig *configs.AgentConfig) WorkflowRunner {
 	_, stepSecretValues := tools.GetSecretKeysAndValues(config.Secrets)
+	dockerLogger := docker.NewDockerLogger(log.NewLogger(log.GetGlobalLoggerOpts()), stepSecretValues)
+	runtime, err := docker.NewRuntime(containerRuntimeName(agentConfig), dockerLogger)
+	if err != nil {
+		log.Warnf("%s, using %s", err, docker.RuntimeDocker)
+		runtime, _ = docker.NewRuntime(docker.RuntimeDocker, dockerLogger)
+	}
+	buildExecutionID := uuid.Must(uuid.NewV4()).String()
+	buildContext, cancelBuild := context.WithCancel(context.Background())
+
 	return WorkflowRunner{
-		config:        config,
-		dockerManager: docker.NewContainerManager(log.NewLogger(log.GetGlobalLoggerOpts()), stepSecretValues),
-		agentConfig:   agentConfig,
+		config:              config,
+		buildExecutionID:    buildExecutionID,
+		dockerManager:       docker.NewContainerManager(runtime, dockerLogger, buildExecutionID),
+		agentConfig:         agentConfig,
+		stepPreparationLock: &sync.Mutex{},
+		buildContext:        buildContext,
+		cancelBuild:         cancelBuild,
 	}
 }
 
-func (r WorkflowRunner) RunWorkflowsWithSetupAndCheckForUpdate() (int, error) {
-	if r.config.Workflow == "" {
-		return 1, workflowNotSpecifiedErr
+// removeRegistryConfig removes the temporary registry config holding the registry logins of the build.
+func (r WorkflowRunner) removeRegistryConfig() {
+	if err := r.dockerManager.RemoveRegistryConfig(); err != nil {
+		log.Warnf("Failed to remove the docker registry config: %s", err)
 	}
-	_, exist := r.config.Config.Workflows[r.config.Workflow]
-	if !exist {
-		return 1, fmt.Errorf("specified Workflow (%s) does not exist", r.config.Workflow)
+}
+
+func (r WorkflowRunner) RunWorkflowsWithSetupAndCheckForUpdate() (int, error) {
+	if r.config.Pipeline != "" {
+		if _, exist := r.config.Config.Pipelines[r.config.Pipeline]; !exist {
+			return 1, fmt.Errorf("specified Pipeline (%s) does not exist", r.config.Pipeline)
+		}
+	} else {
+		if r.config.Workflow == "" {
+			return 1, workflowNotSpecifiedErr
+		}
+		_, exist := r.config.Config.Workflows[r.config.Workflow]
+		if !exist {
+			return 1, fmt.Errorf("specified Workflow (%s) does not exist", r.config.Workflow)
+		}
 	}
 
 	tracker := analytics.NewDefaultTracker()
@@ -220,7 +301,15 @@ func (r WorkflowRunner) RunWorkflowsWithSetupAndCheckForUpdate() (int, error) {
 		}()
 	}
 
-	if buildRunResults, err := r.runWorkflows(tracker); err != nil {
+	defer r.removeRegistryConfig()
+
+	if r.config.Pipeline != "" {
+		if pipelineRunResults, err := r.runPipeline(tracker); err != nil {
+			return 1, fmt.Errorf("failed to run pipeline: %s", err)
+		} else if pipelineRunResults.IsBuildFailed() {
+			return pipelineRunResults.ExitCode(), workflowRunFailedErr
+		}
+	} else if buildRunResults, err := r.runWorkflows(tracker); err != nil {
 		return 1, fmt.Errorf("failed to run workflow: %s", err)
 	} else if buildRunResults.IsBuildFailed() {
 		return buildRunResults.ExitCode(), workflowRunFailedErr
@@ -236,40 +325,70 @@ func (r WorkflowRunner) RunWorkflowsWithSetupAndCheckForUpdate() (int, error) {
 func (r WorkflowRunner) runWorkflows(tracker analytics.Tracker) (models.BuildRunResultsModel, error) {
 	startTime := time.Now()
 
-	// Register run modes
-	if err := registerRunModes(r.config.Modes); err != nil {
-		return models.BuildRunResultsModel{}, fmt.Errorf("failed to register workflow run modes: %s", err)
+	if err := r.prepareBuild(); err != nil {
+		return models.BuildRunResultsModel{}, err
 	}
 
-	targetWorkflow := r.config.Config.Workflows[r.config.Workflow]
-	if targetWorkflow.Title == "" {
-		targetWorkflow.Title = r.config.Workflow
+	// Trigger WillStartRun
+	buildRunStartModel := models.BuildRunStartModel{
+		EventName:   string(plugins.WillStartRun),
+		StartTime:   startTime,
+		ProjectType: r.config.Config.ProjectType,
+	}
+	if err := plugins.TriggerEvent(plugins.WillStartRun, buildRunStartModel); err != nil {
+		log.Warnf("Failed to trigger WillStartRun, error: %s", err)
 	}
 
-	// Envman setup
-	if err := os.Setenv(configs.EnvstorePathEnvKey, configs.OutputEnvstorePath); err != nil {
-		return models.BuildRunResultsModel{}, fmt.Errorf("failed to add env, err: %s", err)
+	buildIDProperties := coreanalytics.Properties{analytics.BuildExecutionID: r.buildExecutionID}
+
+	executionContext := newWorkflowExecutionContext(r.config.Workflow, r.config.Modes)
+	if err := r.prepareCheckpoints(executionContext); err != nil {
+		return models.BuildRunResultsModel{}, err
 	}
 
-	if err := os.Setenv(configs.FormattedOutputPathEnvKey, configs.FormattedOutputPath); err != nil {
-		return models.BuildRunResultsModel{}, fmt.Errorf("failed to add env, err: %s", err)
+	buildRunResults, err := r.runWorkflowWithBeforeAndAfterRuns(r.config.Workflow, startTime, tracker, buildIDProperties, executionContext)
+	if err != nil {
+		return models.BuildRunResultsModel{}, err
 	}
 
-	if err := tools.EnvmanInit(configs.OutputEnvstorePath, false); err != nil {
-		return models.BuildRunResultsModel{}, fmt.Errorf("failed to run envman init: %s", err)
+	// Build finished
+	bitrise.PrintSummary(buildRunResults)
+
+	// The build status env is only set once the build finished, as the workflows of a pipeline stage run concurrently,
+	// the steps get the status of their own workflow by their envs.
+	if err := bitrise.SetBuildFailedEnv(buildRunResults.IsBuildFailed()); err != nil {
+		log.Error("Failed to set Build Status envs")
 	}
 
-	// App level environment
-	environments := append(r.config.Secrets, r.config.Config.App.Environments...)
+	// Trigger WorkflowRunDidFinish
+	buildRunResults.EventName = string(plugins.DidFinishRun)
+	if err := plugins.TriggerEvent(plugins.DidFinishRun, buildRunResults); err != nil {
+		log.Warnf("Failed to trigger WorkflowRunDidFinish, error: %s", err)
+	}
 
-	if err := os.Setenv("BITRISE_TRIGGERED_WORKFLOW_ID", r.config.Workflow); err != nil {
-		return models.BuildRunResultsModel{}, fmt.Errorf("failed to set BITRISE_TRIGGERED_WORKFLOW_ID env: %s", err)
+	return buildRunResults, nil
+}
+
+// prepareBuild registers the run modes, sets up envman and bootstraps the toolkits,
+// it needs to be called once per build, before running any workflow.
+func (r WorkflowRunner) prepareBuild() error {
+	// Register run modes
+	if err := registerRunModes(r.config.Modes); err != nil {
+		return fmt.Errorf("failed to register workflow run modes: %s", err)
 	}
-	if err := os.Setenv("BITRISE_TRIGGERED_WORKFLOW_TITLE", targetWorkflow.Title); err != nil {
-		return models.BuildRunResultsModel{}, fmt.Errorf("failed to set BITRISE_TRIGGERED_WORKFLOW_TITLE env: %s", err)
+
+	// Envman setup
+	if err := os.Setenv(configs.EnvstorePathEnvKey, configs.OutputEnvstorePath); err != nil {
+		return fmt.Errorf("failed to add env, err: %s", err)
 	}
 
-	environments = append(environments, targetWorkflow.Environments...)
+	if err := os.Setenv(configs.FormattedOutputPathEnvKey, configs.FormattedOutputPath); err != nil {
+		return fmt.Errorf("failed to add env, err: %s", err)
+	}
+
+	if err := tools.EnvmanInit(configs.OutputEnvstorePath, false); err != nil {
+		return fmt.Errorf("failed to run envman init: %s", err)
+	}
 
 	// Bootstrap Toolkits
 	for _, aToolkit := range toolkits.AllSupportedToolkits() {
@@ -279,56 +398,81 @@ func (r WorkflowRunner) runWorkflows(tracker analytics.Tracker) (models.BuildRun
 			// the toolkit's `PrepareForStepRun` can bootstrap for itself later if required
 			// or if the system installed version is not sufficient
 			if err := aToolkit.Bootstrap(); err != nil {
-				return models.BuildRunResultsModel{}, fmt.Errorf("failed to bootstrap the required toolkit for the step (%s), error: %s",
+				return fmt.Errorf("failed to bootstrap the required toolkit for the step (%s), error: %s",
 					toolkitName, err)
 			}
 		}
 	}
 
-	// Trigger WillStartRun
-	buildRunStartModel := models.BuildRunStartModel{
-		EventName:   string(plugins.WillStartRun),
-		StartTime:   startTime,
-		ProjectType: r.config.Config.ProjectType,
+	return nil
+}
+
+// runWorkflowWithBeforeAndAfterRuns runs the target workflow together with its before_run and after_run workflows.
+func (r WorkflowRunner) runWorkflowWithBeforeAndAfterRuns(targetWorkflowID string, startTime time.Time, tracker analytics.Tracker, buildIDProperties coreanalytics.Properties, executionContext *workflowExecutionContext) (models.BuildRunResultsModel, error) {
+	targetWorkflow := r.config.Config.Workflows[targetWorkflowID]
+	if targetWorkflow.Title == "" {
+		targetWorkflow.Title = targetWorkflowID
 	}
-	if err := plugins.TriggerEvent(plugins.WillStartRun, buildRunStartModel); err != nil {
-		log.Warnf("Failed to trigger WillStartRun, error: %s", err)
+
+	// The triggered workflow envs are workflow specific, so they are passed as envs instead of setting them for the whole process
+	environments := []envmanModels.EnvironmentItemModel{
+		{"BITRISE_TRIGGERED_WORKFLOW_ID": targetWorkflowID},
+		{"BITRISE_TRIGGERED_WORKFLOW_TITLE": targetWorkflow.Title},
+	}
+
+	// App level environment
+	// The env items of the config are copied, as the workflows of a pipeline run concurrently and the items are normalized
+	// while the steps are prepared
+	environments = append(environments, copyEnvironmentItems(r.config.Secrets)...)
+	if executionContext.resumeFrom != nil {
+		// the checkpoint holds every non secret and non sensitive env of the resumed run, including the step outputs
+		sensitiveEnvironments, err := resumedSensitiveEnvironmentItems(r.config.Config, targetWorkflowID, executionContext.resumeFrom.WorkflowIdx)
+		if err != nil {
+			return models.BuildRunResultsModel{}, err
+		}
+		environments = append(environments, sensitiveEnvironments...)
+		environments = append(environments, executionContext.resumeFrom.Environments...)
+	} else {
+		environments = append(environments, copyEnvironmentItems(r.config.Config.App.Environments)...)
+		environments = append(environments, copyEnvironmentItems(executionContext.inheritedEnvironments)...)
+		environments = append(environments, copyEnvironmentItems(targetWorkflow.Environments)...)
 	}
 
 	// Prepare workflow run parameters
 	buildRunResults := models.BuildRunResultsModel{
-		WorkflowID:     r.config.Workflow,
+		WorkflowID:     targetWorkflowID,
 		StartTime:      startTime,
 		StepmanUpdates: map[string]int{},
 		ProjectType:    r.config.Config.ProjectType,
 	}
 
-	plan := createWorkflowRunPlan(r.config.Modes, r.config.Workflow, r.config.Config.Workflows, func() string { return uuid.Must(uuid.NewV4()).String() })
+	plan := createWorkflowRunPlan(r.config.Modes, targetWorkflowID, r.config.Config.Workflows, r.config.Config.StepBundles, func() string { return uuid.Must(uuid.NewV4()).String() })
 	if len(plan.ExecutionPlan) < 1 {
 		return models.BuildRunResultsModel{}, fmt.Errorf("execution plan doesn't have any workflow to run")
 	}
 
-	buildIDProperties := coreanalytics.Properties{analytics.BuildExecutionID: uuid.Must(uuid.NewV4()).String()}
-
 	log.PrintBitriseStartedEvent(plan)
 
 	// Run workflows
 	for i, workflowRunPlan := range plan.ExecutionPlan {
+		if executionContext.resumeFrom != nil && i < executionContext.resumeFrom.WorkflowIdx {
+			log.Infof("Resuming the run, skipping workflow: %s", workflowRunPlan.WorkflowID)
+			continue
+		}
+		executionContext.workflowIdx = i
+
 		isLastWorkflow := i == len(plan.ExecutionPlan)-1
 		workflowToRun := r.config.Config.Workflows[workflowRunPlan.WorkflowID]
 		if workflowToRun.Title == "" {
 			workflowToRun.Title = workflowRunPlan.WorkflowID
 		}
-		buildRunResults = r.runWorkflow(workflowRunPlan, workflowRunPlan.WorkflowID, workflowToRun, r.config.Config.DefaultStepLibSource, buildRunResults, &environments, r.config.Secrets, isLastWorkflow, tracker, buildIDProperties)
+		buildRunResults = r.runWorkflow(workflowRunPlan, workflowRunPlan.WorkflowID, workflowToRun, r.config.Config.DefaultStepLibSource, buildRunResults, &environments, r.config.Secrets, isLastWorkflow, tracker, buildIDProperties, executionContext)
 	}
 
-	// Build finished
-	bitrise.PrintSummary(buildRunResults)
-
-	// Trigger WorkflowRunDidFinish
-	buildRunResults.EventName = string(plugins.DidFinishRun)
-	if err := plugins.TriggerEvent(plugins.DidFinishRun, buildRunResults); err != nil {
-		log.Warnf("Failed to trigger WorkflowRunDidFinish, error: %s", err)
+	// the exports of a failed run are not passed to the later workflows of the pipeline
+	if r.artifactStore != nil && !buildRunResults.IsBuildFailed() {
+		exports := runPlanExports(plan, r.config.Config.Workflows)
+		r.exportWorkflowArtifacts(targetWorkflowID, exports, environments)
 	}
 
 	return buildRunResults, nil
@@ -339,6 +483,7 @@ func processArgs(c *cli.Context) (*RunConfig, error) {
 	if workflowToRunID == "" && len(c.Args()) > 0 {
 		workflowToRunID = c.Args()[0]
 	}
+	pipelineToRunID := c.String(PipelineKey)
 
 	var prGlobalFlagPtr *bool
 	if c.GlobalIsSet(PRKey) {
@@ -384,11 +529,27 @@ func processArgs(c *cli.Context) (*RunConfig, error) {
 		return nil, fmt.Errorf("failed to parse command params: %s", err)
 	}
 
-	if runParams.WorkflowToRunID == "" {
-		return nil, workflowNotSpecifiedErr
+	if pipelineToRunID != "" {
+		runParams.PipelineToRunID = pipelineToRunID
 	}
-	if strings.HasPrefix(runParams.WorkflowToRunID, "_") {
-		return nil, utilityWorkflowSpecifiedErr
+
+	fromStep := c.String(FromStepKey)
+	fromWorkflow := c.String(FromWorkflowKey)
+
+	if runParams.PipelineToRunID != "" {
+		if runParams.WorkflowToRunID != "" {
+			return nil, workflowAndPipelineSpecifiedErr
+		}
+		if fromStep != "" || fromWorkflow != "" {
+			return nil, resumePipelineErr
+		}
+	} else {
+		if runParams.WorkflowToRunID == "" {
+			return nil, workflowNotSpecifiedErr
+		}
+		if strings.HasPrefix(runParams.WorkflowToRunID, "_") {
+			return nil, utilityWorkflowSpecifiedErr
+		}
 	}
 
 	inventoryEnvironments, err := CreateInventoryFromCLIParams(runParams.InventoryBase64Data, runParams.InventoryPath)
@@ -426,6 +587,16 @@ func processArgs(c *cli.Context) (*RunConfig, error) {
 
 	noOutputTimeout := readNoOutputTimoutConfiguration(inventoryEnvironments)
 
+	keepContainers, err := isKeepContainers(c.Bool(KeepContainersKey), inventoryEnvironments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check keep containers mode: %s", err)
+	}
+
+	checkpoints, err := isCheckpoints(c.Bool(CheckpointsKey), inventoryEnvironments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check checkpoints mode: %s", err)
+	}
+
 	return &RunConfig{
 		Modes: models.WorkflowRunModes{
 			CIMode:                  isCIMode,
@@ -435,9 +606,14 @@ func processArgs(c *cli.Context) (*RunConfig, error) {
 			SecretFilteringMode:     enabledFiltering,
 			SecretEnvsFilteringMode: enabledEnvsFiltering,
 		},
-		Config:   bitriseConfig,
-		Workflow: runParams.WorkflowToRunID,
-		Secrets:  inventoryEnvironments,
+		Config:         bitriseConfig,
+		Workflow:       runParams.WorkflowToRunID,
+		Pipeline:       runParams.PipelineToRunID,
+		Secrets:        inventoryEnvironments,
+		FromWorkflow:   fromWorkflow,
+		FromStep:       fromStep,
+		Checkpoints:    checkpoints,
+		KeepContainers: keepContainers,
 	}, nil
 }
 
@@ -461,25 +637,16 @@ func registerRunModes(modes models.WorkflowRunModes) error {
 	return nil
 }
 
-func createWorkflowRunPlan(modes models.WorkflowRunModes, targetWorkflow string, workflows map[string]models.WorkflowModel, uuidProvider func() string) models.WorkflowRunPlan {
+func createWorkflowRunPlan(modes models.WorkflowRunModes, targetWorkflow string, workflows map[string]models.WorkflowModel, stepBundles map[string]models.StepBundleModel, uuidProvider func() string) models.WorkflowRunPlan {
 	var executionPlan []models.WorkflowExecutionPlan
 	workflowList := walkWorkflows(targetWorkflow, workflows, nil)
 	for _, workflowID := range workflowList {
 		workflow := workflows[workflowID]
 
-		var stepPlan []models.StepExecutionPlan
-		for _, stepItem := range workflow.Steps {
-			stepID, _ := stepItem.GetStepIDAndStep()
-			stepPlan = append(stepPlan, models.StepExecutionPlan{
-				UUID:   uuidProvider(),
-				StepID: stepID,
-			})
-		}
-
 		executionPlan = append(executionPlan, models.WorkflowExecutionPlan{
 			UUID:       uuidProvider(),
 			WorkflowID: workflowID,
-			Steps:      stepPlan,
+			Steps:      createStepExecutionPlans(workflow.Steps, stepBundles, "", nil, uuidProvider),
 		})
 	}
 
@@ -501,6 +668,57 @@ func createWorkflowRunPlan(modes models.WorkflowRunModes, targetWorkflow string,
 	}
 }
 
+// createStepExecutionPlans creates the plans of the steps, the referenced step bundles are expanded to the steps of the bundle.
+// stepBundleID and stepBundleEnvs are set when the steps are the steps of a step bundle.
+func createStepExecutionPlans(steps []models.StepListItemModel, stepBundles map[string]models.StepBundleModel, stepBundleID string, stepBundleEnvs []envmanModels.EnvironmentItemModel, uuidProvider func() string) []models.StepExecutionPlan {
+	var stepPlans []models.StepExecutionPlan
+	for _, stepItem := range steps {
+		if bundleID, ok := stepItem.GetStepBundleID(); ok {
+			bundle := stepBundles[bundleID]
+			_, reference := stepItem.GetStepIDAndStep()
+			bundleEnvs := append(append([]envmanModels.EnvironmentItemModel{}, stepBundleEnvs...), stepBundleInputs(bundle.Inputs, reference.Inputs)...)
+			bundleEnvs = append(bundleEnvs, bundle.Environments...)
+
+			stepPlans = append(stepPlans, createStepExecutionPlans(bundle.Steps, stepBundles, bundleID, bundleEnvs, uuidProvider)...)
+			continue
+		}
+
+		stepID, _ := stepItem.GetStepIDAndStep()
+		stepPlan := models.StepExecutionPlan{
+			UUID:           uuidProvider(),
+			StepID:         stepID,
+			StepBundleID:   stepBundleID,
+			Step:           stepItem,
+			StepBundleEnvs: stepBundleEnvs,
+		}
+		if parallelSteps, ok := stepItem.GetParallelSteps(); ok {
+			stepPlan.Steps = createStepExecutionPlans(parallelSteps, stepBundles, stepBundleID, stepBundleEnvs, uuidProvider)
+		}
+		stepPlans = append(stepPlans, stepPlan)
+	}
+	return stepPlans
+}
+
+// stepBundleInputs returns the inputs of a step bundle, the values set by the bundle reference override the defaults of the bundle.
+func stepBundleInputs(inputs, overrides []envmanModels.EnvironmentItemModel) []envmanModels.EnvironmentItemModel {
+	var bundleInputs []envmanModels.EnvironmentItemModel
+	for _, input := range inputs {
+		key, _, err := input.GetKeyValuePair()
+		if err != nil {
+			continue
+		}
+
+		for _, override := range overrides {
+			if overrideKey, _, err := override.GetKeyValuePair(); err == nil && overrideKey == key {
+				input = override
+				break
+			}
+		}
+		bundleInputs = append(bundleInputs, input)
+	}
+	return bundleInputs
+}
+
 func walkWorkflows(workflowID string, workflows map[string]models.WorkflowModel, workflowStack []string) []string {
 	workflow := workflows[workflowID]
 	for _, before := range workflow.BeforeRun {
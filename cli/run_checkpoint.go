@@ -0,0 +1,278 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/log"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/bitrise-io/bitrise/tools"
+	envmanModels "github.com/bitrise-io/envman/models"
+)
+
+const (
+	// FromStepKey ...
+	FromStepKey = "from-step"
+	// FromWorkflowKey ...
+	FromWorkflowKey = "from-workflow"
+	// CheckpointsKey ...
+	CheckpointsKey = "checkpoints"
+
+	checkpointsDirName = "checkpoints"
+)
+
+// runCheckpointModel holds the checkpoints of the last run of a workflow (together with its before and after run workflows).
+type runCheckpointModel struct {
+	WorkflowID string                `json:"workflow"`
+	Steps      []stepCheckpointModel `json:"steps"`
+}
+
+// stepCheckpointModel is the state of the run right before the step starts:
+// the accumulated environments, including the outputs of the previous steps.
+type stepCheckpointModel struct {
+	// WorkflowIdx is the index of the workflow in the before_run, workflow, after_run chain
+	WorkflowIdx  int                                 `json:"workflow_idx"`
+	WorkflowID   string                              `json:"workflow"`
+	StepIdx      int                                 `json:"step_idx"`
+	Environments []envmanModels.EnvironmentItemModel `json:"environments"`
+}
+
+// runCheckpointStore saves a checkpoint after each step of the run, as the starting state of the next step,
+// so that a failed run can be resumed without re-executing the earlier steps.
+// The secrets and the sensitive envs are not saved, the secrets are added again when the run is resumed.
+type runCheckpointStore struct {
+	pth        string
+	secretKeys map[string]bool
+	checkpoint runCheckpointModel
+}
+
+func newRunCheckpointStore(workflowID string, secrets []envmanModels.EnvironmentItemModel) (*runCheckpointStore, error) {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %s", err)
+	}
+
+	// the checkpoints are kept per project (working directory) and workflow
+	projectHash := sha256.Sum256([]byte(workDir))
+	pth := filepath.Join(configs.GetBitriseHomeDirPath(), checkpointsDirName, hex.EncodeToString(projectHash[:8]), workflowID+".json")
+
+	secretKeys := map[string]bool{}
+	keys, _ := tools.GetSecretKeysAndValues(secrets)
+	for _, key := range keys {
+		secretKeys[key] = true
+	}
+
+	return &runCheckpointStore{
+		pth:        pth,
+		secretKeys: secretKeys,
+		checkpoint: runCheckpointModel{WorkflowID: workflowID},
+	}, nil
+}
+
+// load reads the checkpoints of the previous run.
+func (s *runCheckpointStore) load() error {
+	bytes, err := os.ReadFile(s.pth)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no checkpoint found for workflow (%s), run the workflow with --%s first", s.checkpoint.WorkflowID, CheckpointsKey)
+	} else if err != nil {
+		return fmt.Errorf("failed to read checkpoints: %s", err)
+	}
+
+	var checkpoint runCheckpointModel
+	if err := json.Unmarshal(bytes, &checkpoint); err != nil {
+		return fmt.Errorf("failed to parse checkpoints: %s", err)
+	}
+	s.checkpoint = checkpoint
+
+	return nil
+}
+
+// find returns the checkpoint saved before the given step.
+func (s *runCheckpointStore) find(workflowIdx, stepIdx int) (stepCheckpointModel, bool) {
+	for _, stepCheckpoint := range s.checkpoint.Steps {
+		if stepCheckpoint.WorkflowIdx == workflowIdx && stepCheckpoint.StepIdx == stepIdx {
+			return stepCheckpoint, true
+		}
+	}
+	return stepCheckpointModel{}, false
+}
+
+// save stores the checkpoint of the step, the checkpoints of the same and later steps of a previous run are dropped.
+func (s *runCheckpointStore) save(workflowIdx int, workflowID string, stepIdx int, environments []envmanModels.EnvironmentItemModel) error {
+	var steps []stepCheckpointModel
+	for _, stepCheckpoint := range s.checkpoint.Steps {
+		if stepCheckpoint.WorkflowIdx < workflowIdx || (stepCheckpoint.WorkflowIdx == workflowIdx && stepCheckpoint.StepIdx < stepIdx) {
+			steps = append(steps, stepCheckpoint)
+		}
+	}
+
+	var filteredEnvironments []envmanModels.EnvironmentItemModel
+	for _, env := range environments {
+		key, _, err := env.GetKeyValuePair()
+		if err != nil {
+			return err
+		}
+		if s.secretKeys[key] {
+			continue
+		}
+		isSensitive, err := isSensitiveEnvironmentItem(env)
+		if err != nil {
+			return err
+		}
+		if !isSensitive {
+			filteredEnvironments = append(filteredEnvironments, env)
+		}
+	}
+
+	s.checkpoint.Steps = append(steps, stepCheckpointModel{
+		WorkflowIdx:  workflowIdx,
+		WorkflowID:   workflowID,
+		StepIdx:      stepIdx,
+		Environments: filteredEnvironments,
+	})
+
+	bytes, err := json.Marshal(s.checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to serialize checkpoints: %s", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.pth), 0700); err != nil {
+		return fmt.Errorf("failed to create checkpoints dir: %s", err)
+	}
+	// the step outputs might contain sensitive values
+	return os.WriteFile(s.pth, bytes, 0600)
+}
+
+// sensitiveEnvironmentItems returns the sensitive envs of the config, which are not saved in the checkpoints,
+// so these are added again when the run is resumed (the sensitive step outputs of the previous run are not available).
+func sensitiveEnvironmentItems(environments []envmanModels.EnvironmentItemModel) ([]envmanModels.EnvironmentItemModel, error) {
+	var sensitiveEnvironments []envmanModels.EnvironmentItemModel
+	for _, env := range environments {
+		isSensitive, err := isSensitiveEnvironmentItem(env)
+		if err != nil {
+			return nil, err
+		}
+		if isSensitive {
+			sensitiveEnvironments = append(sensitiveEnvironments, env)
+		}
+	}
+	return sensitiveEnvironments, nil
+}
+
+// resumedSensitiveEnvironmentItems returns the sensitive envs of the app, the target workflow
+// and the workflows of the run up to the resumed one (workflowIdx), as these were available for the resumed step in the previous run.
+func resumedSensitiveEnvironmentItems(config models.BitriseDataModel, targetWorkflowID string, workflowIdx int) ([]envmanModels.EnvironmentItemModel, error) {
+	environments := copyEnvironmentItems(config.App.Environments)
+	environments = append(environments, copyEnvironmentItems(config.Workflows[targetWorkflowID].Environments)...)
+	for i, workflowID := range walkWorkflows(targetWorkflowID, config.Workflows, nil) {
+		if i > workflowIdx {
+			break
+		}
+		environments = append(environments, copyEnvironmentItems(config.Workflows[workflowID].Environments)...)
+	}
+
+	return sensitiveEnvironmentItems(environments)
+}
+
+func isSensitiveEnvironmentItem(env envmanModels.EnvironmentItemModel) (bool, error) {
+	options, err := env.GetOptions()
+	if err != nil {
+		return false, err
+	}
+	return options.IsSensitive != nil && *options.IsSensitive, nil
+}
+
+// resolveResumePoint returns the position of the workflow and step a run should be resumed from.
+// fromWorkflow defaults to the triggered workflow, fromStep is either the index (starting from 0) or the ID of the step.
+func resolveResumePoint(fromWorkflow, fromStep, targetWorkflowID string, config models.BitriseDataModel) (int, int, error) {
+	if fromWorkflow == "" {
+		fromWorkflow = targetWorkflowID
+	}
+
+	workflowIdx := -1
+	for idx, workflowID := range walkWorkflows(targetWorkflowID, config.Workflows, nil) {
+		if workflowID == fromWorkflow {
+			workflowIdx = idx
+			break
+		}
+	}
+	if workflowIdx == -1 {
+		return 0, 0, fmt.Errorf("workflow (%s) is not run by workflow (%s)", fromWorkflow, targetWorkflowID)
+	}
+
+	if fromStep == "" {
+		return workflowIdx, 0, nil
+	}
+
+	// the step indexes are the indexes of the run plan, where the step bundles are expanded
+	steps := createStepExecutionPlans(config.Workflows[fromWorkflow].Steps, config.StepBundles, "", nil, func() string { return "" })
+	if stepIdx, err := strconv.Atoi(fromStep); err == nil {
+		if stepIdx < 0 || stepIdx >= len(steps) {
+			return 0, 0, fmt.Errorf("workflow (%s) has no step with index: %d", fromWorkflow, stepIdx)
+		}
+		return workflowIdx, stepIdx, nil
+	}
+
+	for stepIdx, stepPlan := range steps {
+		compositeStepIDStr, _, err := models.GetStepIDStepDataPair(stepPlan.Step)
+		if err != nil {
+			return 0, 0, err
+		}
+		if compositeStepIDStr == fromStep {
+			return workflowIdx, stepIdx, nil
+		}
+
+		stepIDData, err := models.CreateStepIDDataFromString(compositeStepIDStr, config.DefaultStepLibSource)
+		if err == nil && stepIDData.IDorURI == fromStep {
+			return workflowIdx, stepIdx, nil
+		}
+	}
+
+	return 0, 0, fmt.Errorf("workflow (%s) has no step with ID: %s", fromWorkflow, fromStep)
+}
+
+// prepareCheckpoints sets up the checkpoint store of the workflow run, if the checkpoints are enabled or the run is resumed,
+// and if the run is resumed, it restores the checkpoint of the step the run continues from.
+func (r WorkflowRunner) prepareCheckpoints(executionContext *workflowExecutionContext) error {
+	isResumed := r.config.FromWorkflow != "" || r.config.FromStep != ""
+	if !r.config.Checkpoints && !isResumed {
+		return nil
+	}
+
+	checkpoints, err := newRunCheckpointStore(r.config.Workflow, r.config.Secrets)
+	if err != nil {
+		return err
+	}
+	executionContext.checkpoints = checkpoints
+
+	if !isResumed {
+		return nil
+	}
+
+	workflowIdx, stepIdx, err := resolveResumePoint(r.config.FromWorkflow, r.config.FromStep, r.config.Workflow, r.config.Config)
+	if err != nil {
+		return fmt.Errorf("failed to resume workflow: %s", err)
+	}
+	if workflowIdx == 0 && stepIdx == 0 {
+		// nothing to skip, the run starts from the beginning
+		return nil
+	}
+
+	if err := checkpoints.load(); err != nil {
+		return fmt.Errorf("failed to resume workflow: %s", err)
+	}
+
+	stepCheckpoint, ok := checkpoints.find(workflowIdx, stepIdx)
+	if !ok {
+		return fmt.Errorf("failed to resume workflow: the previous run didn't reach step (%d) of workflow (%s)", stepIdx, walkWorkflows(r.config.Workflow, r.config.Config.Workflows, nil)[workflowIdx])
+	}
+	log.Infof("Resuming workflow (%s) from step (%d) of workflow (%s)", r.config.Workflow, stepIdx, stepCheckpoint.WorkflowID)
+	executionContext.resumeFrom = &stepCheckpoint
+
+	return nil
+}
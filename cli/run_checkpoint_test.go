@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/bitrise/configs"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveResumePoint(t *testing.T) {
+	configStr := `
+format_version: "13"
+default_step_lib_source: "https://github.com/bitrise-io/bitrise-steplib.git"
+
+workflows:
+  _setup:
+    steps:
+    - script@1: {}
+  primary:
+    before_run: [ _setup ]
+    steps:
+    - git-clone@8: {}
+    - script@1: {}
+`
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	tests := []struct {
+		name            string
+		fromWorkflow    string
+		fromStep        string
+		wantWorkflowIdx int
+		wantStepIdx     int
+		wantErr         string
+	}{
+		{name: "step index", fromStep: "1", wantWorkflowIdx: 1, wantStepIdx: 1},
+		{name: "step ID", fromStep: "script", wantWorkflowIdx: 1, wantStepIdx: 1},
+		{name: "composite step ID", fromStep: "git-clone@8", wantWorkflowIdx: 1, wantStepIdx: 0},
+		{name: "before run workflow", fromWorkflow: "_setup", wantWorkflowIdx: 0, wantStepIdx: 0},
+		{name: "invalid step index", fromStep: "2", wantErr: "workflow (primary) has no step with index: 2"},
+		{name: "unknown step ID", fromStep: "deploy", wantErr: "workflow (primary) has no step with ID: deploy"},
+		{name: "unknown workflow", fromWorkflow: "deploy", wantErr: "workflow (deploy) is not run by workflow (primary)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workflowIdx, stepIdx, err := resolveResumePoint(tt.fromWorkflow, tt.fromStep, "primary", config)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantWorkflowIdx, workflowIdx)
+			require.Equal(t, tt.wantStepIdx, stepIdx)
+		})
+	}
+}
+
+func TestIsCheckpoints(t *testing.T) {
+	checkpoints, err := isCheckpoints(false, nil)
+	require.NoError(t, err)
+	require.False(t, checkpoints)
+
+	checkpoints, err = isCheckpoints(true, nil)
+	require.NoError(t, err)
+	require.True(t, checkpoints)
+
+	t.Setenv(configs.CheckpointsEnvKey, "true")
+	checkpoints, err = isCheckpoints(false, []envmanModels.EnvironmentItemModel{{configs.CheckpointsEnvKey: "false"}})
+	require.NoError(t, err)
+	require.False(t, checkpoints)
+
+	checkpoints, err = isCheckpoints(false, nil)
+	require.NoError(t, err)
+	require.True(t, checkpoints)
+}
+
+func TestRunWorkflows_ResumeFromStep(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	markerDir := t.TempDir()
+	stepDir := t.TempDir()
+	stepYML := `
+title: Run script
+toolkit:
+  bash:
+    entry_file: step.sh
+inputs:
+- script: ""
+`
+	stepSH := `#!/bin/bash
+set -e
+eval "$script"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.yml"), []byte(stepYML), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.sh"), []byte(stepSH), 0700))
+
+	createConfig := func(checkpointValue string) RunConfig {
+		configStr := fmt.Sprintf(`
+format_version: "13"
+default_step_lib_source: "https://github.com/bitrise-io/bitrise-steplib.git"
+
+app:
+  envs:
+  - CHECKPOINT_VALUE: %[3]s
+  - API_TOKEN: token
+    opts:
+      is_sensitive: true
+
+workflows:
+  _setup:
+    envs:
+    - SETUP_TOKEN: setup-token
+      opts:
+        is_sensitive: true
+    steps:
+    - path::%[1]s:
+        title: Setup
+  primary:
+    before_run:
+    - _setup
+    steps:
+    - path::%[1]s:
+        title: First
+        inputs:
+        - script: touch %[2]s/first
+    - path::%[1]s:
+        title: Second
+        inputs:
+        - script: |-
+            [ -f %[2]s/allow_second ]
+            [ "$CHECKPOINT_VALUE" == "original" ]
+            [ "$API_TOKEN" == "token" ]
+            [ "$SETUP_TOKEN" == "setup-token" ]
+`, stepDir, markerDir, checkpointValue)
+
+		config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+		require.NoError(t, err)
+		require.Equal(t, 0, len(warnings))
+
+		return RunConfig{Config: config, Workflow: "primary"}
+	}
+
+	require.NoError(t, configs.InitPaths())
+
+	// the checkpoints are only saved if they are enabled
+	buildRunResults, err := NewWorkflowRunner(createConfig("original"), nil).runWorkflows(noOpTracker{})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(buildRunResults.FailedSteps))
+	require.NoDirExists(t, filepath.Join(configs.GetBitriseHomeDirPath(), checkpointsDirName))
+
+	require.NoError(t, os.Remove(filepath.Join(markerDir, "first")))
+	require.NoError(t, configs.InitPaths())
+	runConfig := createConfig("original")
+	runConfig.Checkpoints = true
+	buildRunResults, err = NewWorkflowRunner(runConfig, nil).runWorkflows(noOpTracker{})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(buildRunResults.SuccessSteps))
+	require.Equal(t, 1, len(buildRunResults.FailedSteps))
+
+	// the sensitive envs are not saved
+	checkpoints, err := newRunCheckpointStore("primary", nil)
+	require.NoError(t, err)
+	checkpointsContent, err := os.ReadFile(checkpoints.pth)
+	require.NoError(t, err)
+	require.Contains(t, string(checkpointsContent), "CHECKPOINT_VALUE")
+	require.NotContains(t, string(checkpointsContent), "API_TOKEN")
+	require.NotContains(t, string(checkpointsContent), "SETUP_TOKEN")
+
+	require.NoError(t, os.Remove(filepath.Join(markerDir, "first")))
+	require.NoError(t, os.WriteFile(filepath.Join(markerDir, "allow_second"), nil, 0600))
+
+	// the resumed run restores the envs of the previous run, instead of reading them from the modified config,
+	// the sensitive envs of the app and of the workflows started before the resume point are read from the config
+	require.NoError(t, configs.InitPaths())
+	runConfig = createConfig("modified")
+	runConfig.FromStep = "1"
+	buildRunResults, err = NewWorkflowRunner(runConfig, nil).runWorkflows(noOpTracker{})
+	require.NoError(t, err)
+	require.False(t, buildRunResults.IsBuildFailed())
+	require.Equal(t, 1, len(buildRunResults.SuccessSteps))
+	require.NoFileExists(t, filepath.Join(markerDir, "first"))
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/bitrise-io/bitrise/cli/docker"
 	"github.com/bitrise-io/bitrise/configs"
 	"github.com/bitrise-io/bitrise/log"
 	envmanModels "github.com/bitrise-io/envman/models"
@@ -49,3 +50,46 @@ func readNoOutputTimoutConfiguration(inventoryEnvironments []envmanModels.Enviro
 
 	return time.Duration(timeout) * time.Second
 }
+
+// isKeepContainers returns whether the docker containers of a failed workflow should be kept for debugging,
+// the --keep-containers flag can be set by the BITRISE_DOCKER_KEEP_CONTAINERS inventory or process env as well.
+func isKeepContainers(keepContainersFlag bool, inventoryEnvironments []envmanModels.EnvironmentItemModel) (bool, error) {
+	return isEnabledByFlagOrEnv(keepContainersFlag, configs.KeepContainersEnvKey, inventoryEnvironments)
+}
+
+// isCheckpoints returns whether the checkpoints of the run should be saved, so that the run can be resumed,
+// the --checkpoints flag can be set by the BITRISE_CHECKPOINTS inventory or process env as well.
+func isCheckpoints(checkpointsFlag bool, inventoryEnvironments []envmanModels.EnvironmentItemModel) (bool, error) {
+	return isEnabledByFlagOrEnv(checkpointsFlag, configs.CheckpointsEnvKey, inventoryEnvironments)
+}
+
+func isEnabledByFlagOrEnv(flag bool, envKey string, inventoryEnvironments []envmanModels.EnvironmentItemModel) (bool, error) {
+	if flag {
+		return true, nil
+	}
+
+	for _, env := range inventoryEnvironments {
+		key, value, err := env.GetKeyValuePair()
+		if err != nil {
+			return false, err
+		}
+
+		if key == envKey && value != "" {
+			return value == "true", nil
+		}
+	}
+
+	return os.Getenv(envKey) == "true", nil
+}
+
+// containerRuntimeName returns the container runtime set by the BITRISE_CONTAINER_RUNTIME env or the agent config,
+// docker is used if neither sets it.
+func containerRuntimeName(agentConfig *configs.AgentConfig) string {
+	if runtime := os.Getenv(configs.ContainerRuntimeEnvKey); runtime != "" {
+		return runtime
+	}
+	if agentConfig != nil && agentConfig.ContainerRuntime != "" {
+		return agentConfig.ContainerRuntime
+	}
+	return docker.RuntimeDocker
+}
@@ -0,0 +1,436 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/bitrise/log"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/bitrise-io/bitrise/tools"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/pathutil"
+	stepmanModels "github.com/bitrise-io/stepman/models"
+)
+
+const (
+	// DryRunKey ...
+	DryRunKey = "dry-run"
+	// DryRunFormatKey ...
+	DryRunFormatKey = "format"
+
+	dryRunFormatTable = "table"
+	dryRunFormatJSON  = "json"
+)
+
+// DryRunPlanModel is the execution plan of a workflow or pipeline run, resolved without running any step.
+type DryRunPlanModel struct {
+	WorkflowID string                    `json:"workflow,omitempty"`
+	PipelineID string                    `json:"pipeline,omitempty"`
+	CIMode     bool                      `json:"ci_mode"`
+	PRMode     bool                      `json:"pr_mode"`
+	Workflows  []DryRunWorkflowPlanModel `json:"workflows"`
+}
+
+// DryRunWorkflowPlanModel ...
+type DryRunWorkflowPlanModel struct {
+	WorkflowID string `json:"workflow"`
+	// TriggeredWorkflowID is the workflow whose before_run and after_run chain this workflow is part of.
+	TriggeredWorkflowID string                `json:"triggered_workflow"`
+	StageID             string                `json:"stage,omitempty"`
+	Steps               []DryRunStepPlanModel `json:"steps"`
+}
+
+// DryRunStepPlanModel ...
+type DryRunStepPlanModel struct {
+	ID               string                 `json:"id"`
+	Library          string                 `json:"library,omitempty"`
+	RequestedVersion string                 `json:"requested_version,omitempty"`
+	Version          string                 `json:"version,omitempty"`
+	Title            string                 `json:"title,omitempty"`
+	Inputs           []DryRunStepInputModel `json:"inputs,omitempty"`
+	RunIf            string                 `json:"run_if,omitempty"`
+	// WillRun is nil if the run_if expression can't be evaluated before the build runs.
+	WillRun      *bool  `json:"will_run,omitempty"`
+	RunIfError   string `json:"run_if_error,omitempty"`
+	IsAlwaysRun  bool   `json:"is_always_run"`
+	ResolveError string `json:"resolve_error,omitempty"`
+	// StepBundle is the ID of the step bundle the step is expanded from
+	StepBundle string `json:"step_bundle,omitempty"`
+	// Steps are the steps of a parallel step group
+	Steps []DryRunStepPlanModel `json:"steps,omitempty"`
+}
+
+// DryRunStepInputModel ...
+type DryRunStepInputModel struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// createDryRunPlan resolves the workflows and steps the run config would execute:
+// the before_run and after_run chains, the step versions, the step inputs merged with the step definitions
+// and the step run_if expressions.
+// The run_if expressions are evaluated as if every previous step succeeded.
+func createDryRunPlan(config RunConfig) (DryRunPlanModel, error) {
+	plan := DryRunPlanModel{
+		WorkflowID: config.Workflow,
+		PipelineID: config.Pipeline,
+		CIMode:     config.Modes.CIMode,
+		PRMode:     config.Modes.PRMode,
+		Workflows:  []DryRunWorkflowPlanModel{},
+	}
+
+	type workflowRun struct {
+		workflowID string
+		stageID    string
+	}
+
+	var workflowRuns []workflowRun
+	if config.Pipeline != "" {
+		pipeline, ok := config.Config.Pipelines[config.Pipeline]
+		if !ok {
+			return DryRunPlanModel{}, fmt.Errorf("specified Pipeline (%s) does not exist", config.Pipeline)
+		}
+
+		pipelineInfo, err := newPipelineInfo(config.Pipeline, pipeline, config.Config)
+		if err != nil {
+			return DryRunPlanModel{}, err
+		}
+
+		for _, node := range pipelineInfo.graphNodes() {
+			workflowRuns = append(workflowRuns, workflowRun{workflowID: node.workflow.ID, stageID: node.stageID})
+		}
+	} else {
+		if _, ok := config.Config.Workflows[config.Workflow]; !ok {
+			return DryRunPlanModel{}, fmt.Errorf("specified Workflow (%s) does not exist", config.Workflow)
+		}
+		workflowRuns = append(workflowRuns, workflowRun{workflowID: config.Workflow})
+	}
+
+	stepInfos := map[string]stepmanModels.StepInfoModel{}
+	for _, run := range workflowRuns {
+		targetWorkflow := config.Config.Workflows[run.workflowID]
+		title := targetWorkflow.Title
+		if title == "" {
+			title = run.workflowID
+		}
+
+		// Same envs as the ones of runWorkflowWithBeforeAndAfterRuns, except the envs set by the steps
+		environments := []envmanModels.EnvironmentItemModel{
+			{"BITRISE_TRIGGERED_WORKFLOW_ID": run.workflowID},
+			{"BITRISE_TRIGGERED_WORKFLOW_TITLE": title},
+		}
+		environments = append(environments, config.Secrets...)
+		environments = append(environments, config.Config.App.Environments...)
+		environments = append(environments, targetWorkflow.Environments...)
+
+		runPlan := createWorkflowRunPlan(config.Modes, run.workflowID, config.Config.Workflows, config.Config.StepBundles, func() string { return "" })
+		for _, workflowExecutionPlan := range runPlan.ExecutionPlan {
+			workflow := config.Config.Workflows[workflowExecutionPlan.WorkflowID]
+			environments = append(environments, workflow.Environments...)
+
+			envs, err := tools.ExpandEnvItems(environments, os.Environ())
+			if err != nil {
+				return DryRunPlanModel{}, fmt.Errorf("failed to expand the envs of workflow (%s): %s", workflowExecutionPlan.WorkflowID, err)
+			}
+
+			workflowPlan := DryRunWorkflowPlanModel{
+				WorkflowID:          workflowExecutionPlan.WorkflowID,
+				TriggeredWorkflowID: run.workflowID,
+				StageID:             run.stageID,
+				Steps:               []DryRunStepPlanModel{},
+			}
+			createStepPlan := func(stepExecutionPlan models.StepExecutionPlan) DryRunStepPlanModel {
+				stepPlan := createDryRunStepPlan(stepExecutionPlan.Step, config.Config.DefaultStepLibSource, stepInfos)
+				stepPlan.StepBundle = stepExecutionPlan.StepBundleID
+				if stepPlan.RunIf != "" {
+					stepEnvs := envs
+					if len(stepExecutionPlan.StepBundleEnvs) > 0 {
+						bundleEnvs, err := tools.ExpandEnvItems(withStepBundleEnvs(environments, stepExecutionPlan), os.Environ())
+						if err != nil {
+							stepPlan.RunIfError = fmt.Sprintf("failed to expand the envs of step bundle (%s): %s", stepExecutionPlan.StepBundleID, err)
+							return stepPlan
+						}
+						stepEnvs = bundleEnvs
+					}
+					evaluateDryRunStepRunIf(&stepPlan, config.Modes, envmanModels.EnvsJSONListModel(stepEnvs))
+				}
+				return stepPlan
+			}
+
+			for _, stepExecutionPlan := range workflowExecutionPlan.Steps {
+				if _, ok := stepExecutionPlan.Step.GetParallelSteps(); ok {
+					groupPlan := DryRunStepPlanModel{ID: models.ParallelStepGroupKey, StepBundle: stepExecutionPlan.StepBundleID}
+					for _, parallelStepPlan := range stepExecutionPlan.Steps {
+						groupPlan.Steps = append(groupPlan.Steps, createStepPlan(parallelStepPlan))
+					}
+					workflowPlan.Steps = append(workflowPlan.Steps, groupPlan)
+					continue
+				}
+
+				workflowPlan.Steps = append(workflowPlan.Steps, createStepPlan(stepExecutionPlan))
+			}
+
+			plan.Workflows = append(plan.Workflows, workflowPlan)
+		}
+	}
+
+	return plan, nil
+}
+
+// createDryRunStepPlan resolves the step's definition without activating the step.
+// The definitions of git steps can't be resolved without cloning their repository, so only their workflow inputs are listed.
+// stepInfos caches the resolved StepLib step infos by composite step ID.
+func createDryRunStepPlan(stepListItem models.StepListItemModel, defaultStepLibSource string, stepInfos map[string]stepmanModels.StepInfoModel) DryRunStepPlanModel {
+	compositeStepIDStr, workflowStep, err := models.GetStepIDStepDataPair(stepListItem)
+	if err != nil {
+		return DryRunStepPlanModel{ResolveError: err.Error()}
+	}
+
+	stepPlan := DryRunStepPlanModel{ID: compositeStepIDStr}
+
+	stepIDData, err := models.CreateStepIDDataFromString(compositeStepIDStr, defaultStepLibSource)
+	if err != nil {
+		stepPlan.ResolveError = err.Error()
+		return stepPlan
+	}
+	stepPlan.ID = stepIDData.IDorURI
+	stepPlan.Library = stepIDData.SteplibSource
+	stepPlan.RequestedVersion = stepIDData.Version
+
+	mergedStep := workflowStep
+	var specStep *stepmanModels.StepModel
+	switch stepIDData.SteplibSource {
+	case "path":
+		stepAbsLocalPth, err := pathutil.AbsPath(stepIDData.IDorURI)
+		if err != nil {
+			stepPlan.ResolveError = err.Error()
+			break
+		}
+		step, err := bitrise.ReadSpecStep(filepath.Join(stepAbsLocalPth, "step.yml"))
+		if err != nil {
+			stepPlan.ResolveError = fmt.Sprintf("failed to parse step definition: %s", err)
+			break
+		}
+		specStep = &step
+	case "git":
+		stepPlan.Version = stepIDData.Version
+	case "_":
+		if err := mergedStep.FillMissingDefaults(); err != nil {
+			stepPlan.ResolveError = err.Error()
+		}
+		stepPlan.Version = stepIDData.Version
+	default:
+		stepInfo, ok := stepInfos[compositeStepIDStr]
+		if !ok {
+			stepInfo, err = queryStepLibStepInfo(stepIDData)
+			if err != nil {
+				stepPlan.ResolveError = err.Error()
+				break
+			}
+			stepInfos[compositeStepIDStr] = stepInfo
+		}
+		stepPlan.Version = stepInfo.Version
+		specStep = &stepInfo.Step
+	}
+
+	if specStep != nil {
+		mergedStep, err = models.MergeStepWith(*specStep, workflowStep)
+		if err != nil {
+			stepPlan.ResolveError = err.Error()
+			mergedStep = workflowStep
+		}
+	}
+
+	if mergedStep.Title != nil {
+		stepPlan.Title = *mergedStep.Title
+	}
+	if mergedStep.RunIf != nil {
+		stepPlan.RunIf = *mergedStep.RunIf
+	}
+	stepPlan.IsAlwaysRun = stepmanModels.DefaultIsAlwaysRun
+	if mergedStep.IsAlwaysRun != nil {
+		stepPlan.IsAlwaysRun = *mergedStep.IsAlwaysRun
+	}
+
+	for _, input := range mergedStep.Inputs {
+		key, value, err := input.GetKeyValuePair()
+		if err != nil {
+			continue
+		}
+		stepPlan.Inputs = append(stepPlan.Inputs, DryRunStepInputModel{Key: key, Value: value})
+	}
+
+	return stepPlan
+}
+
+// queryStepLibStepInfo resolves the step version from the StepLib, without activating the step.
+// The StepLib is only updated if the step version is not found in the local StepLib cache.
+func queryStepLibStepInfo(stepIDData models.StepIDData) (stepmanModels.StepInfoModel, error) {
+	if err := tools.StepmanSetup(stepIDData.SteplibSource); err != nil {
+		return stepmanModels.StepInfoModel{}, err
+	}
+
+	info, err := tools.StepmanStepInfo(stepIDData.SteplibSource, stepIDData.IDorURI, stepIDData.Version)
+	if err == nil {
+		return info, nil
+	}
+
+	log.Debugf("Step info not found in StepLib (%s) -- Updating ...", stepIDData.SteplibSource)
+	if err := tools.StepmanUpdate(stepIDData.SteplibSource); err != nil {
+		return stepmanModels.StepInfoModel{}, err
+	}
+
+	info, err = tools.StepmanStepInfo(stepIDData.SteplibSource, stepIDData.IDorURI, stepIDData.Version)
+	if err != nil {
+		return stepmanModels.StepInfoModel{}, fmt.Errorf("stepman JSON steplib step info failed: %s", err)
+	}
+	return info, nil
+}
+
+func evaluateDryRunStepRunIf(stepPlan *DryRunStepPlanModel, modes models.WorkflowRunModes, envList envmanModels.EnvsJSONListModel) {
+	buildRunResults := models.BuildRunResultsModel{StepmanUpdates: map[string]int{}}
+	isRun, err := bitrise.EvaluateTemplateToBool(stepPlan.RunIf, modes.CIMode, modes.PRMode, buildRunResults, envList)
+	if err != nil {
+		stepPlan.RunIfError = err.Error()
+		return
+	}
+	stepPlan.WillRun = &isRun
+}
+
+// JSON ...
+func (plan DryRunPlanModel) JSON() string {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Sprintf(`{"error":"%s"}`, err.Error())
+	}
+	return string(data) + "\n"
+}
+
+// String prints the plan as a table of steps per workflow.
+func (plan DryRunPlanModel) String() string {
+	var sb strings.Builder
+
+	if plan.PipelineID != "" {
+		fmt.Fprintf(&sb, "Dry run of pipeline: %s\n", plan.PipelineID)
+	} else {
+		fmt.Fprintf(&sb, "Dry run of workflow: %s\n", plan.WorkflowID)
+	}
+	fmt.Fprintf(&sb, "CI mode: %v, PR mode: %v\n", plan.CIMode, plan.PRMode)
+
+	for _, workflow := range plan.Workflows {
+		sb.WriteString("\n")
+		switch {
+		case workflow.StageID != "":
+			fmt.Fprintf(&sb, "Workflow: %s (stage: %s, triggered workflow: %s)\n", workflow.WorkflowID, workflow.StageID, workflow.TriggeredWorkflowID)
+		case workflow.WorkflowID != workflow.TriggeredWorkflowID:
+			fmt.Fprintf(&sb, "Workflow: %s (triggered workflow: %s)\n", workflow.WorkflowID, workflow.TriggeredWorkflowID)
+		default:
+			fmt.Fprintf(&sb, "Workflow: %s\n", workflow.WorkflowID)
+		}
+
+		if len(workflow.Steps) == 0 {
+			sb.WriteString("  no steps\n")
+			continue
+		}
+
+		writeDryRunStepTable(&sb, workflow.Steps)
+	}
+
+	return sb.String()
+}
+
+// dryRunStepRow is a row of the dry run step table, the steps of a parallel step group are numbered after the group (e.g. 2.1).
+type dryRunStepRow struct {
+	position string
+	step     DryRunStepPlanModel
+}
+
+func dryRunStepRows(steps []DryRunStepPlanModel, positionPrefix string) []dryRunStepRow {
+	var rows []dryRunStepRow
+	for i, step := range steps {
+		position := fmt.Sprintf("%s%d", positionPrefix, i+1)
+		rows = append(rows, dryRunStepRow{position: position, step: step})
+		rows = append(rows, dryRunStepRows(step.Steps, position+".")...)
+	}
+	return rows
+}
+
+func writeDryRunStepTable(w io.Writer, steps []DryRunStepPlanModel) {
+	rows := dryRunStepRows(steps, "")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  #\tSTEP\tVERSION\tRUN IF\tWILL RUN")
+	for _, row := range rows {
+		step := row.step
+		stepName := step.ID
+		if step.StepBundle != "" {
+			stepName = fmt.Sprintf("%s (%s%s)", step.ID, models.StepBundleIDPrefix, step.StepBundle)
+		}
+
+		if len(step.Steps) > 0 {
+			fmt.Fprintf(tw, "  %s\t%s (%d steps)\t\t\t\n", row.position, stepName, len(step.Steps))
+			continue
+		}
+
+		version := step.Version
+		if step.RequestedVersion != "" && step.RequestedVersion != step.Version {
+			version = fmt.Sprintf("%s (requested: %s)", step.Version, step.RequestedVersion)
+		}
+
+		willRun := "yes"
+		switch {
+		case step.ResolveError != "":
+			willRun = "unknown (" + step.ResolveError + ")"
+		case step.RunIfError != "":
+			willRun = "unknown (" + step.RunIfError + ")"
+		case step.WillRun != nil && !*step.WillRun:
+			willRun = "no"
+		}
+		if step.IsAlwaysRun {
+			willRun += ", always run"
+		}
+
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", row.position, stepName, version, firstLine(step.RunIf), willRun)
+	}
+	if err := tw.Flush(); err != nil {
+		log.Warnf("Failed to print dry run plan: %s", err)
+	}
+
+	for _, row := range rows {
+		if len(row.step.Inputs) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "  Inputs of step %s (%s):\n", row.position, row.step.ID)
+		for _, input := range row.step.Inputs {
+			fmt.Fprintf(w, "    %s: %s\n", input.Key, firstLine(input.Value))
+		}
+	}
+}
+
+func firstLine(s string) string {
+	lines := strings.SplitN(s, "\n", 2)
+	if len(lines) > 1 {
+		return lines[0] + " ..."
+	}
+	return s
+}
+
+func printDryRunPlan(config RunConfig, format string) error {
+	plan, err := createDryRunPlan(config)
+	if err != nil {
+		return err
+	}
+
+	if format == dryRunFormatJSON {
+		fmt.Print(plan.JSON())
+	} else {
+		fmt.Print(plan.String())
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateDryRunPlan(t *testing.T) {
+	stepDir := t.TempDir()
+	stepYML := `
+title: Local step
+run_if: .IsCI
+inputs:
+- greeting: hello
+- name: world
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.yml"), []byte(stepYML), 0600))
+
+	configStr := fmt.Sprintf(`
+format_version: "13"
+default_step_lib_source: "https://github.com/bitrise-io/bitrise-steplib.git"
+
+pipelines:
+  staged:
+    stages:
+    - build: {}
+
+stages:
+  build:
+    workflows:
+    - primary: {}
+
+workflows:
+  _setup:
+    steps:
+    - path::%[1]s:
+        run_if: '{{ enveq "NAME" "bitrise" }}'
+  primary:
+    before_run: [ _setup ]
+    envs:
+    - NAME: bitrise
+    steps:
+    - path::%[1]s:
+        inputs:
+        - name: $NAME
+    - git::https://github.com/bitrise-steplib/steps-script.git@master: {}
+`, stepDir)
+
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	plan, err := createDryRunPlan(RunConfig{Config: config, Workflow: "primary", Modes: models.WorkflowRunModes{CIMode: false}})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(plan.Workflows))
+
+	setup := plan.Workflows[0]
+	require.Equal(t, "_setup", setup.WorkflowID)
+	require.Equal(t, "primary", setup.TriggeredWorkflowID)
+	require.Equal(t, 1, len(setup.Steps))
+	// the run_if of the workflow overrides the step's default and sees the envs of the triggered workflow
+	require.Equal(t, `{{ enveq "NAME" "bitrise" }}`, setup.Steps[0].RunIf)
+	require.True(t, *setup.Steps[0].WillRun)
+
+	primary := plan.Workflows[1]
+	require.Equal(t, 2, len(primary.Steps))
+
+	localStep := primary.Steps[0]
+	require.Equal(t, "path", localStep.Library)
+	require.Equal(t, "Local step", localStep.Title)
+	require.Equal(t, []DryRunStepInputModel{{Key: "greeting", Value: "hello"}, {Key: "name", Value: "$NAME"}}, localStep.Inputs)
+	require.Equal(t, ".IsCI", localStep.RunIf)
+	require.False(t, *localStep.WillRun)
+
+	gitStep := primary.Steps[1]
+	require.Equal(t, "git", gitStep.Library)
+	require.Equal(t, "master", gitStep.Version)
+	require.Nil(t, gitStep.WillRun)
+	require.Equal(t, "", gitStep.ResolveError)
+
+	plan, err = createDryRunPlan(RunConfig{Config: config, Pipeline: "staged", Modes: models.WorkflowRunModes{CIMode: true}})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(plan.Workflows))
+	require.Equal(t, "build", plan.Workflows[1].StageID)
+	require.True(t, *plan.Workflows[1].Steps[0].WillRun)
+}
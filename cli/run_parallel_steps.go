@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/bitrise/analytics"
+	"github.com/bitrise-io/bitrise/log"
+	"github.com/bitrise-io/bitrise/models"
+	envmanModels "github.com/bitrise-io/envman/models"
+	coreanalytics "github.com/bitrise-io/go-utils/v2/analytics"
+	stepmanModels "github.com/bitrise-io/stepman/models"
+)
+
+// parallelStepRunResult is the outcome of a step of a parallel step group.
+type parallelStepRunResult struct {
+	buildRunResults models.BuildRunResultsModel
+	outputs         []envmanModels.EnvironmentItemModel
+}
+
+// runParallelStepGroup runs the steps of a parallel step group at the same time, the steps are positioned from the group's position (idx).
+// Every step runs with its own envstores and step work dir, and its log lines are prefixed with the step's position and title.
+// The results and the outputs of the steps are merged in the order the steps are defined in the group,
+// so that the build run results and the env overrides (a later step's output wins) don't depend on which step finished first.
+func (r WorkflowRunner) runParallelStepGroup(
+	parallelSteps []models.StepListItemModel,
+	idx int,
+	groupPlan models.StepExecutionPlan,
+	workflow models.WorkflowModel,
+	defaultStepLibSource string,
+	buildRunResults models.BuildRunResultsModel,
+	environments []envmanModels.EnvironmentItemModel,
+	secrets []envmanModels.EnvironmentItemModel,
+	isLastStep bool,
+	tracker analytics.Tracker,
+	workflowIDProperties coreanalytics.Properties,
+	executionContext *workflowExecutionContext,
+) (models.BuildRunResultsModel, []envmanModels.EnvironmentItemModel) {
+	log.Infof("Running %d steps in parallel", len(parallelSteps))
+
+	results := make([]parallelStepRunResult, len(parallelSteps))
+
+	var wg sync.WaitGroup
+	for i, stepListItem := range parallelSteps {
+		stepID, step := stepListItem.GetStepIDAndStep()
+		stepName := stepID
+		if step.Title != nil && *step.Title != "" {
+			stepName = *step.Title
+		}
+
+		stepPlan := models.StepExecutionPlan{StepID: stepID}
+		if i < len(groupPlan.Steps) {
+			stepPlan = groupPlan.Steps[i]
+		}
+
+		// every step records its results into its own copy, the copies are merged once all the steps finished
+		stepBuildRunResults := copyBuildRunResults(buildRunResults)
+		isLastParallelStep := isLastStep && i == len(parallelSteps)-1
+
+		stepExecutionContext, err := executionContext.newParallelStepExecutionContext(fmt.Sprintf("[%d:%s] ", i, stepName))
+		if err != nil {
+			runResultCollector := newBuildRunResultCollector(tracker, executionContext.logger())
+			runResultCollector.registerStepRunResults(&stepBuildRunResults, stepPlan.UUID, time.Now(), step, stepmanModels.StepInfoModel{ID: stepID}, idx+i,
+				models.StepRunStatusCodePreparationFailed, 1, err, isLastParallelStep, true, map[string]string{}, workflowIDProperties)
+			results[i] = parallelStepRunResult{buildRunResults: stepBuildRunResults}
+			continue
+		}
+
+		// every step gets its own copy of the env items, as the items are normalized (their maps are written) while the step is prepared
+		stepEnvironments := copyEnvironmentItems(withStepBundleEnvs(environments, stepPlan))
+		stepSecrets := copyEnvironmentItems(secrets)
+
+		wg.Add(1)
+		go func(i int, stepListItem models.StepListItemModel, stepPlan models.StepExecutionPlan) {
+			defer wg.Done()
+			defer func() {
+				if err := stepExecutionContext.cleanup(); err != nil {
+					log.Warnf("Failed to remove the work dir of parallel step (%s): %s", stepPlan.StepID, err)
+				}
+			}()
+
+			runResults, outputs := r.activateAndRunStep(stepListItem, idx+i, stepPlan, workflow, defaultStepLibSource,
+				stepBuildRunResults, stepEnvironments, stepSecrets, isLastParallelStep, tracker, workflowIDProperties, stepExecutionContext)
+			results[i] = parallelStepRunResult{buildRunResults: runResults, outputs: outputs}
+		}(i, stepListItem, stepPlan)
+	}
+	wg.Wait()
+
+	groupResultsIdx := buildRunResults.ResultsCount()
+	baseStepmanUpdates := copyBuildRunResults(buildRunResults).StepmanUpdates
+	var outputs []envmanModels.EnvironmentItemModel
+	for _, result := range results {
+		buildRunResults = mergeParallelStepRunResults(buildRunResults, result.buildRunResults, baseStepmanUpdates, groupResultsIdx)
+		outputs = append(outputs, result.outputs...)
+	}
+
+	return buildRunResults, outputs
+}
+
+func copyBuildRunResults(buildRunResults models.BuildRunResultsModel) models.BuildRunResultsModel {
+	resultsCopy := buildRunResults
+	resultsCopy.StepmanUpdates = map[string]int{}
+	for stepLib, updates := range buildRunResults.StepmanUpdates {
+		resultsCopy.StepmanUpdates[stepLib] = updates
+	}
+	resultsCopy.SuccessSteps = append([]models.StepRunResultsModel{}, buildRunResults.SuccessSteps...)
+	resultsCopy.FailedSteps = append([]models.StepRunResultsModel{}, buildRunResults.FailedSteps...)
+	resultsCopy.FailedSkippableSteps = append([]models.StepRunResultsModel{}, buildRunResults.FailedSkippableSteps...)
+	resultsCopy.SkippedSteps = append([]models.StepRunResultsModel{}, buildRunResults.SkippedSteps...)
+	return resultsCopy
+}
+
+// mergeParallelStepRunResults adds the step results registered in the copy of the build run results (from groupResultsIdx),
+// the results are re-indexed to follow the results already in the build run results.
+// The steplib updates of the step are added as the difference to the updates before the group (baseStepmanUpdates).
+func mergeParallelStepRunResults(buildRunResults, stepBuildRunResults models.BuildRunResultsModel, baseStepmanUpdates map[string]int, groupResultsIdx int) models.BuildRunResultsModel {
+	if buildRunResults.StepmanUpdates == nil {
+		buildRunResults.StepmanUpdates = map[string]int{}
+	}
+	for stepLib, updates := range stepBuildRunResults.StepmanUpdates {
+		buildRunResults.StepmanUpdates[stepLib] += updates - baseStepmanUpdates[stepLib]
+	}
+
+	for _, stepResults := range stepBuildRunResults.OrderedResults() {
+		if stepResults.Idx < groupResultsIdx {
+			continue
+		}
+		stepResults.Idx = buildRunResults.ResultsCount()
+		appendStepRunResults(&buildRunResults, stepResults)
+	}
+
+	return buildRunResults
+}
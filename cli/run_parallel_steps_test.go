@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeParallelStepRunResults(t *testing.T) {
+	buildRunResults := models.BuildRunResultsModel{
+		StepmanUpdates: map[string]int{"steplib": 1},
+		SuccessSteps:   []models.StepRunResultsModel{{Idx: 0, Status: models.StepRunStatusCodeSuccess}},
+	}
+	baseStepmanUpdates := copyBuildRunResults(buildRunResults).StepmanUpdates
+
+	// both steps of the group update the same steplib
+	first := copyBuildRunResults(buildRunResults)
+	first.StepmanUpdates["steplib"]++
+	first.SuccessSteps = append(first.SuccessSteps, models.StepRunResultsModel{Idx: 1, Status: models.StepRunStatusCodeSuccess})
+
+	second := copyBuildRunResults(buildRunResults)
+	second.StepmanUpdates["steplib"]++
+	second.StepmanUpdates["other-steplib"]++
+	second.FailedSteps = append(second.FailedSteps, models.StepRunResultsModel{Idx: 1, Status: models.StepRunStatusCodeFailed})
+
+	buildRunResults = mergeParallelStepRunResults(buildRunResults, first, baseStepmanUpdates, 1)
+	buildRunResults = mergeParallelStepRunResults(buildRunResults, second, baseStepmanUpdates, 1)
+
+	require.Equal(t, map[string]int{"steplib": 3, "other-steplib": 1}, buildRunResults.StepmanUpdates)
+	require.Equal(t, 2, len(buildRunResults.SuccessSteps))
+	require.Equal(t, 1, buildRunResults.SuccessSteps[1].Idx)
+	require.Equal(t, 1, len(buildRunResults.FailedSteps))
+	require.Equal(t, 2, buildRunResults.FailedSteps[0].Idx)
+}
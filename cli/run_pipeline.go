@@ -0,0 +1,267 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/bitrise/analytics"
+	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/log"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/bitrise-io/bitrise/plugins"
+	"github.com/bitrise-io/bitrise/tools"
+	envmanModels "github.com/bitrise-io/envman/models"
+	coreanalytics "github.com/bitrise-io/go-utils/v2/analytics"
+)
+
+// runPipeline runs the stages of the pipeline one after the other,
+// the workflows of a stage are started in parallel.
+// Graph pipelines (defined by workflows and their dependencies) are run by runWorkflowGraph.
+func (r WorkflowRunner) runPipeline(tracker analytics.Tracker) (models.PipelineRunResultsModel, error) {
+	startTime := time.Now()
+
+	if err := r.prepareBuild(); err != nil {
+		return models.PipelineRunResultsModel{}, err
+	}
+
+	// Trigger WillStartRun
+	buildRunStartModel := models.BuildRunStartModel{
+		EventName:   string(plugins.WillStartRun),
+		StartTime:   startTime,
+		ProjectType: r.config.Config.ProjectType,
+	}
+	if err := plugins.TriggerEvent(plugins.WillStartRun, buildRunStartModel); err != nil {
+		log.Warnf("Failed to trigger WillStartRun, error: %s", err)
+	}
+
+	buildIDProperties := coreanalytics.Properties{analytics.BuildExecutionID: r.buildExecutionID}
+
+	artifactStore, err := newPipelineArtifactStore(r.buildExecutionID)
+	if err != nil {
+		return models.PipelineRunResultsModel{}, err
+	}
+	defer func() {
+		if err := artifactStore.cleanup(); err != nil {
+			log.Warnf("Failed to clean up the pipeline artifact store: %s", err)
+		}
+	}()
+	r.artifactStore = artifactStore
+
+	pipelineRunResults := models.PipelineRunResultsModel{
+		PipelineID: r.config.Pipeline,
+		StartTime:  startTime,
+	}
+
+	pipeline := r.config.Config.Pipelines[r.config.Pipeline]
+	if len(pipeline.Workflows) > 0 {
+		workflowResults, skippedWorkflows, err := r.runWorkflowGraph(pipeline, tracker, buildIDProperties)
+		pipelineRunResults.WorkflowResults = workflowResults
+		pipelineRunResults.SkippedWorkflows = skippedWorkflows
+		if err != nil {
+			// the summary lists the finished and the skipped workflows of the run, which stopped on the error
+			pipelineRunResults.RunTime = time.Since(startTime)
+			bitrise.PrintPipelineSummary(pipelineRunResults)
+			return pipelineRunResults, err
+		}
+	}
+
+	// the workflows of the finished stages, in the order they are defined, their exports are passed to the later stages
+	var finishedWorkflowIDs []string
+	for _, stageListItem := range pipeline.Stages {
+		stageID, err := models.GetStageIDFromListItemModel(stageListItem)
+		if err != nil {
+			return models.PipelineRunResultsModel{}, err
+		}
+
+		stage, ok := r.config.Config.Stages[stageID]
+		if !ok {
+			return models.PipelineRunResultsModel{}, fmt.Errorf("stage (%s) defined in pipeline (%s), but does not exist", stageID, r.config.Pipeline)
+		}
+
+		stageRunResults := r.runStage(stageID, stage, pipelineRunResults, r.artifactStore.environments(finishedWorkflowIDs), tracker, buildIDProperties)
+		pipelineRunResults.StageResults = append(pipelineRunResults.StageResults, stageRunResults)
+
+		for _, workflowRunResults := range stageRunResults.WorkflowResults {
+			finishedWorkflowIDs = append(finishedWorkflowIDs, workflowRunResults.WorkflowID)
+		}
+	}
+
+	pipelineRunResults.RunTime = time.Since(startTime)
+
+	// Build finished
+	bitrise.PrintPipelineSummary(pipelineRunResults)
+
+	buildRunResults := pipelineRunResults.BuildRunResults()
+	// The build status env is only set once the build finished, as the workflows of a pipeline stage run concurrently,
+	// the steps get the status of their own workflow by their envs.
+	if err := bitrise.SetBuildFailedEnv(buildRunResults.IsBuildFailed()); err != nil {
+		log.Error("Failed to set Build Status envs")
+	}
+
+	// Trigger WorkflowRunDidFinish
+	buildRunResults.EventName = string(plugins.DidFinishRun)
+	buildRunResults.ProjectType = r.config.Config.ProjectType
+	if err := plugins.TriggerEvent(plugins.DidFinishRun, buildRunResults); err != nil {
+		log.Warnf("Failed to trigger WorkflowRunDidFinish, error: %s", err)
+	}
+
+	return pipelineRunResults, nil
+}
+
+// runStage runs the workflows of the stage in parallel and collects their results in the order they are defined.
+// The workflows receive the inheritedEnvironments: the envs exported by the workflows of the previous stages.
+// If the stage is marked abort_on_fail, a failing workflow aborts the rest of the stage's workflows:
+// their remaining steps are not run.
+func (r WorkflowRunner) runStage(stageID string, stage models.StageModel, pipelineRunResults models.PipelineRunResultsModel, inheritedEnvironments []envmanModels.EnvironmentItemModel, tracker analytics.Tracker, buildIDProperties coreanalytics.Properties) models.StageRunResultsModel {
+	stageTitle := stage.Title
+	if stageTitle == "" {
+		stageTitle = stageID
+	}
+	bitrise.PrintRunningStage(stageTitle)
+
+	stageRunResults := models.StageRunResultsModel{
+		StageID:   stageID,
+		StartTime: time.Now(),
+	}
+
+	if pipelineRunResults.IsBuildFailed() && !stage.ShouldAlwaysRun {
+		log.Warnf("A previous stage failed, and this stage (%s) was not marked as should_always_run, skipping...", stageID)
+		stageRunResults.Status = models.StageRunStatusCodeSkipped
+		return stageRunResults
+	}
+
+	if stage.RunIf != "" {
+		isRun, err := r.evaluateStageRunIf(stage.RunIf, pipelineRunResults)
+		if err != nil {
+			log.Errorf("Failed to evaluate run_if expression of stage (%s): %s", stageID, err)
+			stageRunResults.Status = models.StageRunStatusCodeFailed
+			return stageRunResults
+		}
+		if !isRun {
+			log.Warnf("The run_if expression of stage (%s) evaluated to false, skipping...", stageID)
+			stageRunResults.Status = models.StageRunStatusCodeSkippedWithRunIf
+			return stageRunResults
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	workflowResults := make([]*models.BuildRunResultsModel, len(stage.Workflows))
+	workflowFailed := make([]bool, len(stage.Workflows))
+	workflowIDs := make([]string, len(stage.Workflows))
+
+	var wg sync.WaitGroup
+	for i, workflowListItem := range stage.Workflows {
+		workflowID, err := models.GetWorkflowIDFromListItemModel(workflowListItem)
+		if err != nil {
+			log.Errorf("Invalid workflow in stage (%s): %s", stageID, err)
+			workflowFailed[i] = true
+			continue
+		}
+		workflowIDs[i] = workflowID
+
+		wg.Add(1)
+		go func(idx int, workflowID string) {
+			defer wg.Done()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			buildRunResults, aborted, err := r.runIsolatedWorkflow(workflowID, inheritedEnvironments, tracker, buildIDProperties, ctx.Done())
+			if err != nil {
+				log.Errorf("Failed to run workflow (%s): %s", workflowID, err)
+				workflowFailed[idx] = true
+			} else if !aborted {
+				workflowResults[idx] = &buildRunResults
+				workflowFailed[idx] = buildRunResults.IsBuildFailed()
+			}
+
+			if workflowFailed[idx] && stage.AbortOnFail {
+				cancel()
+			}
+		}(i, workflowID)
+	}
+	wg.Wait()
+
+	for i := range stage.Workflows {
+		if workflowFailed[i] {
+			stageRunResults.Status = models.StageRunStatusCodeFailed
+		}
+
+		if workflowResults[i] != nil {
+			stageRunResults.WorkflowResults = append(stageRunResults.WorkflowResults, *workflowResults[i])
+		} else if !workflowFailed[i] && workflowIDs[i] != "" {
+			stageRunResults.AbortedWorkflows = append(stageRunResults.AbortedWorkflows, workflowIDs[i])
+		}
+	}
+
+	stageRunResults.RunTime = time.Since(stageRunResults.StartTime)
+
+	return stageRunResults
+}
+
+// runIsolatedWorkflow runs the workflow in its own execution context, so that it doesn't interfere with the concurrently running workflows.
+// It returns true if the workflow was aborted before running all of its steps.
+func (r WorkflowRunner) runIsolatedWorkflow(workflowID string, inheritedEnvironments []envmanModels.EnvironmentItemModel, tracker analytics.Tracker, buildIDProperties coreanalytics.Properties, abort <-chan struct{}) (models.BuildRunResultsModel, bool, error) {
+	executionContext, err := newIsolatedWorkflowExecutionContext(workflowID, r.config.Modes, abort)
+	if err != nil {
+		return models.BuildRunResultsModel{}, false, fmt.Errorf("failed to create execution context: %s", err)
+	}
+	executionContext.inheritedEnvironments = inheritedEnvironments
+	defer func() {
+		if err := executionContext.cleanup(); err != nil {
+			log.Warnf("Failed to clean up the execution context of workflow (%s): %s", workflowID, err)
+		}
+	}()
+
+	buildRunResults, err := r.runWorkflowWithBeforeAndAfterRuns(workflowID, time.Now(), tracker, buildIDProperties, executionContext)
+	return buildRunResults, executionContext.aborted, err
+}
+
+// runPlanExports returns the exports of every workflow of the run plan (the before_run, the triggered and the after_run workflows),
+// in the order the workflows run.
+func runPlanExports(plan models.WorkflowRunPlan, workflows map[string]models.WorkflowModel) models.WorkflowExportsModel {
+	var exports models.WorkflowExportsModel
+	for _, workflowRunPlan := range plan.ExecutionPlan {
+		workflowExports := workflows[workflowRunPlan.WorkflowID].Exports
+		exports.Envs = append(exports.Envs, workflowExports.Envs...)
+		exports.Files = append(exports.Files, workflowExports.Files...)
+	}
+	return exports
+}
+
+// exportWorkflowArtifacts saves the exported files and envs of the finished workflow into the pipeline's artifact store,
+// environments are the envs of the workflow run, including the step outputs.
+func (r WorkflowRunner) exportWorkflowArtifacts(workflowID string, exports models.WorkflowExportsModel, environments []envmanModels.EnvironmentItemModel) {
+	if len(exports.Envs) == 0 && len(exports.Files) == 0 {
+		return
+	}
+
+	envs, err := tools.ExpandEnvItems(environments, os.Environ())
+	if err != nil {
+		log.Warnf("Failed to expand the envs of workflow (%s), its exports are not available for the later workflows: %s", workflowID, err)
+		return
+	}
+
+	if err := r.artifactStore.export(workflowID, exports, envs); err != nil {
+		log.Warnf("Failed to export the artifacts of workflow (%s): %s", workflowID, err)
+	}
+}
+
+func (r WorkflowRunner) evaluateStageRunIf(runIf string, pipelineRunResults models.PipelineRunResultsModel) (bool, error) {
+	environments := append([]envmanModels.EnvironmentItemModel{}, r.config.Secrets...)
+	environments = append(environments, r.config.Config.App.Environments...)
+
+	envs, err := tools.ExpandEnvItems(environments, os.Environ())
+	if err != nil {
+		return false, fmt.Errorf("failed to expand envs: %s", err)
+	}
+
+	return bitrise.EvaluateTemplateToBool(runIf, configs.IsCIMode, configs.IsPullRequestMode, pipelineRunResults.BuildRunResults(), envmanModels.EnvsJSONListModel(envs))
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bitrise-io/bitrise/analytics"
+	"github.com/bitrise-io/bitrise/log"
+	"github.com/bitrise-io/bitrise/models"
+	coreanalytics "github.com/bitrise-io/go-utils/v2/analytics"
+)
+
+type graphWorkflowRunResult struct {
+	workflowID      string
+	buildRunResults models.BuildRunResultsModel
+	err             error
+}
+
+// runWorkflowGraph runs the workflows of a graph pipeline: a workflow starts as soon as all of its dependencies succeeded,
+// while at most max_parallel workflows run at the same time.
+// Workflows depending on a failed (or skipped) workflow are skipped.
+// A workflow receives the exports of its direct and transitive dependencies.
+// The results and the skipped workflows are returned in topological order.
+// If a workflow can't be run, no more workflows are started (these are skipped) and the error is returned once the running workflows finished.
+func (r WorkflowRunner) runWorkflowGraph(pipeline models.PipelineModel, tracker analytics.Tracker, buildIDProperties coreanalytics.Properties) ([]models.BuildRunResultsModel, []string, error) {
+	workflowIDs := topologicalGraphWorkflowOrder(pipeline.Workflows)
+
+	maxParallel := pipeline.MaxParallel
+	if maxParallel <= 0 {
+		maxParallel = len(workflowIDs)
+	}
+
+	results := map[string]models.BuildRunResultsModel{}
+	failed := map[string]bool{}
+	skipped := map[string]bool{}
+	started := map[string]bool{}
+
+	finished := make(chan graphWorkflowRunResult)
+	running := 0
+	var runErr error
+
+	for {
+		// workflowIDs is topologically ordered, so the dependencies are always handled before the dependent workflows
+		for _, workflowID := range workflowIDs {
+			if runErr != nil {
+				break
+			}
+			if started[workflowID] || skipped[workflowID] {
+				continue
+			}
+
+			isReady := true
+			for _, dependencyID := range pipeline.Workflows[workflowID].DependsOn {
+				if failed[dependencyID] || skipped[dependencyID] {
+					log.Warnf("Workflow (%s) depends on a failed or skipped workflow (%s), skipping...", workflowID, dependencyID)
+					skipped[workflowID] = true
+					isReady = false
+					break
+				}
+				if _, ok := results[dependencyID]; !ok {
+					isReady = false
+				}
+			}
+
+			if !isReady || running >= maxParallel {
+				continue
+			}
+
+			started[workflowID] = true
+			running++
+			inheritedEnvironments := r.artifactStore.environments(graphWorkflowDependencies(pipeline.Workflows, workflowID, workflowIDs))
+			go func(workflowID string) {
+				buildRunResults, _, err := r.runIsolatedWorkflow(workflowID, inheritedEnvironments, tracker, buildIDProperties, nil)
+				finished <- graphWorkflowRunResult{
+					workflowID:      workflowID,
+					buildRunResults: buildRunResults,
+					err:             err,
+				}
+			}(workflowID)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		result := <-finished
+		running--
+		if result.err != nil {
+			if runErr == nil {
+				runErr = fmt.Errorf("failed to run workflow (%s): %s", result.workflowID, result.err)
+			}
+			failed[result.workflowID] = true
+			continue
+		}
+		results[result.workflowID] = result.buildRunResults
+		if result.buildRunResults.IsBuildFailed() {
+			failed[result.workflowID] = true
+		}
+	}
+
+	var orderedResults []models.BuildRunResultsModel
+	var skippedWorkflows []string
+	for _, workflowID := range workflowIDs {
+		// the workflows not started because of a run error are skipped as well
+		if skipped[workflowID] || !started[workflowID] {
+			skippedWorkflows = append(skippedWorkflows, workflowID)
+		} else if result, ok := results[workflowID]; ok {
+			orderedResults = append(orderedResults, result)
+		}
+	}
+
+	return orderedResults, skippedWorkflows, runErr
+}
+
+// topologicalGraphWorkflowOrder orders the workflows of a graph pipeline so that every workflow comes after its dependencies,
+// workflows with no ordering constraint between them are sorted by their IDs.
+func topologicalGraphWorkflowOrder(pipelineWorkflows models.GraphPipelineWorkflowListItemModel) []string {
+	remainingDependencies := map[string]int{}
+	dependents := map[string][]string{}
+	for workflowID, pipelineWorkflow := range pipelineWorkflows {
+		remainingDependencies[workflowID] = len(pipelineWorkflow.DependsOn)
+		for _, dependencyID := range pipelineWorkflow.DependsOn {
+			dependents[dependencyID] = append(dependents[dependencyID], workflowID)
+		}
+	}
+
+	var ready []string
+	for workflowID, count := range remainingDependencies {
+		if count == 0 {
+			ready = append(ready, workflowID)
+		}
+	}
+
+	var ordered []string
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		workflowID := ready[0]
+		ready = ready[1:]
+		ordered = append(ordered, workflowID)
+
+		for _, dependentID := range dependents[workflowID] {
+			remainingDependencies[dependentID]--
+			if remainingDependencies[dependentID] == 0 {
+				ready = append(ready, dependentID)
+			}
+		}
+	}
+
+	return ordered
+}
+
+// graphWorkflowDependencies returns the direct and transitive dependencies of the workflow,
+// in the order of the given (topologically ordered) workflow IDs.
+func graphWorkflowDependencies(pipelineWorkflows models.GraphPipelineWorkflowListItemModel, workflowID string, orderedWorkflowIDs []string) []string {
+	dependencies := map[string]bool{}
+	toVisit := append([]string{}, pipelineWorkflows[workflowID].DependsOn...)
+	for len(toVisit) > 0 {
+		dependencyID := toVisit[0]
+		toVisit = toVisit[1:]
+		if dependencies[dependencyID] {
+			continue
+		}
+		dependencies[dependencyID] = true
+		toVisit = append(toVisit, pipelineWorkflows[dependencyID].DependsOn...)
+	}
+
+	var ordered []string
+	for _, id := range orderedWorkflowIDs {
+		if dependencies[id] {
+			ordered = append(ordered, id)
+		}
+	}
+	return ordered
+}
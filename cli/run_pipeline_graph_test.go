@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunPipeline_WorkflowGraph(t *testing.T) {
+	configStr := `
+format_version: "13"
+default_step_lib_source: "https://github.com/bitrise-io/bitrise-steplib.git"
+
+pipelines:
+  graph:
+    max_parallel: 1
+    workflows:
+      build: {}
+      fail: {}
+      test:
+        depends_on: [ build ]
+      deploy:
+        depends_on: [ build, fail ]
+      notify:
+        depends_on: [ deploy ]
+
+workflows:
+  build: {}
+  test: {}
+  deploy: {}
+  notify: {}
+  fail:
+    steps:
+    - path::./this/step/does/not/exist: {}
+`
+
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	require.NoError(t, configs.InitPaths())
+
+	runner := NewWorkflowRunner(RunConfig{Config: config, Pipeline: "graph"}, nil)
+	pipelineRunResults, err := runner.runPipeline(noOpTracker{})
+	require.NoError(t, err)
+
+	require.True(t, pipelineRunResults.IsBuildFailed())
+	require.Equal(t, 0, len(pipelineRunResults.StageResults))
+
+	var workflowIDs []string
+	for _, workflowResult := range pipelineRunResults.WorkflowResults {
+		workflowIDs = append(workflowIDs, workflowResult.WorkflowID)
+	}
+	require.Equal(t, []string{"build", "fail", "test"}, workflowIDs)
+	require.Equal(t, []string{"deploy", "notify"}, pipelineRunResults.SkippedWorkflows)
+}
+
+func TestTopologicalGraphWorkflowOrder(t *testing.T) {
+	pipelineWorkflows := models.GraphPipelineWorkflowListItemModel{
+		"deploy":   {DependsOn: []string{"test", "build"}},
+		"test":     {DependsOn: []string{"build"}},
+		"build":    {},
+		"analyze":  {},
+		"archive":  {DependsOn: []string{"analyze"}},
+		"announce": {DependsOn: []string{"deploy", "archive"}},
+	}
+
+	require.Equal(t, []string{"analyze", "archive", "build", "test", "deploy", "announce"}, topologicalGraphWorkflowOrder(pipelineWorkflows))
+}
+
+func TestGraphWorkflowDependencies(t *testing.T) {
+	pipelineWorkflows := models.GraphPipelineWorkflowListItemModel{
+		"build":   {},
+		"analyze": {},
+		"test":    {DependsOn: []string{"build"}},
+		"deploy":  {DependsOn: []string{"test"}},
+	}
+	orderedWorkflowIDs := topologicalGraphWorkflowOrder(pipelineWorkflows)
+
+	require.Equal(t, []string{"build", "test"}, graphWorkflowDependencies(pipelineWorkflows, "deploy", orderedWorkflowIDs))
+	require.Equal(t, []string(nil), graphWorkflowDependencies(pipelineWorkflows, "analyze", orderedWorkflowIDs))
+}
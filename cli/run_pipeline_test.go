@@ -0,0 +1,273 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/stretchr/testify/require"
+)
+
+const pipelineTestConfig = `
+format_version: "13"
+default_step_lib_source: "https://github.com/bitrise-io/bitrise-steplib.git"
+
+pipelines:
+  skipping:
+    stages:
+    - failing: {}
+    - not_always_run: {}
+    - always_run: {}
+  run_if:
+    stages:
+    - run_if_false: {}
+    - succeeding: {}
+  abort:
+    stages:
+    - abort_on_fail: {}
+
+stages:
+  failing:
+    workflows:
+    - fail: {}
+    - success: {}
+  not_always_run:
+    workflows:
+    - success: {}
+  always_run:
+    should_always_run: true
+    workflows:
+    - success: {}
+  run_if_false:
+    run_if: '{{ "false" | eq "true" }}'
+    workflows:
+    - success: {}
+  succeeding:
+    workflows:
+    - success: {}
+    - success_2: {}
+  abort_on_fail:
+    abort_on_fail: true
+    workflows:
+    - fail: {}
+    - success: {}
+
+workflows:
+  success: {}
+  success_2: {}
+  fail:
+    steps:
+    - path::./this/step/does/not/exist: {}
+`
+
+func runTestPipeline(t *testing.T, pipelineID string) models.PipelineRunResultsModel {
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(pipelineTestConfig))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	require.NoError(t, configs.InitPaths())
+
+	runConfig := RunConfig{Config: config, Pipeline: pipelineID}
+	runner := NewWorkflowRunner(runConfig, nil)
+	pipelineRunResults, err := runner.runPipeline(noOpTracker{})
+	require.NoError(t, err)
+
+	return pipelineRunResults
+}
+
+func TestRunPipeline_SkipsStagesAfterFailure(t *testing.T) {
+	pipelineRunResults := runTestPipeline(t, "skipping")
+
+	require.True(t, pipelineRunResults.IsBuildFailed())
+	require.Equal(t, 3, len(pipelineRunResults.StageResults))
+
+	failing := pipelineRunResults.StageResults[0]
+	require.Equal(t, models.StageRunStatusCodeFailed, failing.Status)
+	require.Equal(t, 2, len(failing.WorkflowResults))
+	require.Equal(t, "fail", failing.WorkflowResults[0].WorkflowID)
+	require.Equal(t, "success", failing.WorkflowResults[1].WorkflowID)
+
+	require.Equal(t, models.StageRunStatusCodeSkipped, pipelineRunResults.StageResults[1].Status)
+	require.Equal(t, 0, len(pipelineRunResults.StageResults[1].WorkflowResults))
+
+	require.Equal(t, models.StageRunStatusCodeSuccess, pipelineRunResults.StageResults[2].Status)
+	require.Equal(t, 1, len(pipelineRunResults.StageResults[2].WorkflowResults))
+}
+
+func TestRunPipeline_StageRunIf(t *testing.T) {
+	pipelineRunResults := runTestPipeline(t, "run_if")
+
+	require.False(t, pipelineRunResults.IsBuildFailed())
+	require.Equal(t, 2, len(pipelineRunResults.StageResults))
+	require.Equal(t, models.StageRunStatusCodeSkippedWithRunIf, pipelineRunResults.StageResults[0].Status)
+
+	succeeding := pipelineRunResults.StageResults[1]
+	require.Equal(t, models.StageRunStatusCodeSuccess, succeeding.Status)
+	require.Equal(t, 2, len(succeeding.WorkflowResults))
+	require.Equal(t, "success", succeeding.WorkflowResults[0].WorkflowID)
+	require.Equal(t, "success_2", succeeding.WorkflowResults[1].WorkflowID)
+}
+
+func TestRunPipeline_AbortOnFail(t *testing.T) {
+	pipelineRunResults := runTestPipeline(t, "abort")
+
+	require.True(t, pipelineRunResults.IsBuildFailed())
+	require.Equal(t, 1, len(pipelineRunResults.StageResults))
+
+	stageRunResults := pipelineRunResults.StageResults[0]
+	require.Equal(t, models.StageRunStatusCodeFailed, stageRunResults.Status)
+	// the workflows of the stage start in parallel, so the succeeding workflow either finished before the failure or got aborted
+	require.Equal(t, 2, len(stageRunResults.WorkflowResults)+len(stageRunResults.AbortedWorkflows))
+	require.Equal(t, "fail", stageRunResults.WorkflowResults[0].WorkflowID)
+}
+
+func TestRunPipeline_IsolatesStageWorkflows(t *testing.T) {
+	stepDir := t.TempDir()
+	stepYML := `
+title: Check workflow environment
+toolkit:
+  bash:
+    entry_file: step.sh
+`
+	// Each workflow has to see its own triggered workflow ID and envstore, even when the workflows run in parallel.
+	stepSH := `#!/bin/bash
+set -e
+sleep 1
+if [ "$BITRISE_TRIGGERED_WORKFLOW_ID" != "$EXPECTED_WORKFLOW_ID" ] ; then
+  echo "Triggered workflow ID: $BITRISE_TRIGGERED_WORKFLOW_ID, expected: $EXPECTED_WORKFLOW_ID"
+  exit 1
+fi
+if [[ "$ENVMAN_ENVSTORE_PATH" != *"workflow-${EXPECTED_WORKFLOW_ID}-"* ]] ; then
+  echo "Envstore path: $ENVMAN_ENVSTORE_PATH is not specific to workflow: $EXPECTED_WORKFLOW_ID"
+  exit 1
+fi
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.yml"), []byte(stepYML), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.sh"), []byte(stepSH), 0700))
+
+	configStr := fmt.Sprintf(`
+format_version: "13"
+default_step_lib_source: "https://github.com/bitrise-io/bitrise-steplib.git"
+
+pipelines:
+  parallel:
+    stages:
+    - parallel: {}
+
+stages:
+  parallel:
+    workflows:
+    - first: {}
+    - second: {}
+
+workflows:
+  first:
+    envs:
+    - EXPECTED_WORKFLOW_ID: first
+    steps:
+    - path::%[1]s: {}
+  second:
+    envs:
+    - EXPECTED_WORKFLOW_ID: second
+    steps:
+    - path::%[1]s: {}
+`, stepDir)
+
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	require.NoError(t, configs.InitPaths())
+
+	runner := NewWorkflowRunner(RunConfig{Config: config, Pipeline: "parallel"}, nil)
+	pipelineRunResults, err := runner.runPipeline(noOpTracker{})
+	require.NoError(t, err)
+
+	require.False(t, pipelineRunResults.IsBuildFailed())
+	require.Equal(t, 2, len(pipelineRunResults.StageResults[0].WorkflowResults))
+	for _, workflowResult := range pipelineRunResults.StageResults[0].WorkflowResults {
+		require.Equal(t, 1, len(workflowResult.SuccessSteps))
+	}
+}
+
+func TestRunPipeline_PassesExportsToLaterStages(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	stepDir := t.TempDir()
+	stepYML := `
+title: Run script
+toolkit:
+  bash:
+    entry_file: step.sh
+`
+	stepSH := `#!/bin/bash
+set -e
+eval "$SCRIPT"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.yml"), []byte(stepYML), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.sh"), []byte(stepSH), 0700))
+
+	configStr := fmt.Sprintf(`
+format_version: "13"
+default_step_lib_source: "https://github.com/bitrise-io/bitrise-steplib.git"
+
+pipelines:
+  exports:
+    stages:
+    - build: {}
+    - test: {}
+
+stages:
+  build:
+    workflows:
+    - build: {}
+  test:
+    workflows:
+    - test: {}
+
+workflows:
+  _version:
+    envs:
+    - APP_VERSION: 1.0.0
+    # the exports of the before_run and after_run workflows are passed as well
+    exports:
+      envs:
+      - APP_VERSION
+  build:
+    before_run:
+    - _version
+    envs:
+    - SCRIPT: echo "ipa" > "$BITRISE_DEPLOY_DIR/exported.ipa"
+    exports:
+      files:
+      - path: $BITRISE_DEPLOY_DIR/exported.ipa
+        env_key: BITRISE_IPA_PATH
+    steps:
+    - path::%[1]s: {}
+  test:
+    envs:
+    - SCRIPT: |-
+        [[ "$BITRISE_IPA_PATH" == *pipeline_artifacts* ]]
+        [ "$(cat "$BITRISE_IPA_PATH")" == "ipa" ]
+        [ "$APP_VERSION" == "1.0.0" ]
+    steps:
+    - path::%[1]s: {}
+`, stepDir)
+
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	require.NoError(t, configs.InitPaths())
+
+	runner := NewWorkflowRunner(RunConfig{Config: config, Pipeline: "exports"}, nil)
+	pipelineRunResults, err := runner.runPipeline(noOpTracker{})
+	require.NoError(t, err)
+
+	require.False(t, pipelineRunResults.IsBuildFailed())
+	require.Equal(t, 1, len(pipelineRunResults.StageResults[1].WorkflowResults[0].SuccessSteps))
+}
@@ -993,6 +993,63 @@ workflows:
 	require.Equal(t, "1", os.Getenv("STEPLIB_BUILD_STATUS"))
 }
 
+// Checks if run_if sees the build status of the current build
+func TestBuildStatusEnvInRunIf(t *testing.T) {
+	t.Setenv("BITRISE_BUILD_STATUS", "0")
+
+	stepDir := t.TempDir()
+	stepYML := `
+title: Run script
+toolkit:
+  bash:
+    entry_file: step.sh
+inputs:
+- script: ""
+`
+	stepSH := `#!/bin/bash
+set -e
+eval "$script"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.yml"), []byte(stepYML), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.sh"), []byte(stepSH), 0700))
+
+	configStr := fmt.Sprintf(`
+format_version: "13"
+default_step_lib_source: "https://github.com/bitrise-io/bitrise-steplib.git"
+
+workflows:
+  target:
+    steps:
+    - path::%[1]s:
+        title: Should fail
+        inputs:
+        - script: exit 1
+    - path::%[1]s:
+        title: Should run on failed build
+        is_always_run: true
+        run_if: '{{enveq "BITRISE_BUILD_STATUS" "1"}}'
+    - path::%[1]s:
+        title: Should skip on failed build
+        is_always_run: true
+        run_if: '{{enveq "BITRISE_BUILD_STATUS" "0"}}'
+`, stepDir)
+
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	require.NoError(t, configs.InitPaths())
+
+	runner := NewWorkflowRunner(RunConfig{Config: config, Workflow: "target"}, nil)
+	buildRunResults, err := runner.runWorkflows(noOpTracker{})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(buildRunResults.FailedSteps))
+	require.Equal(t, 1, len(buildRunResults.SuccessSteps))
+	require.Equal(t, "Should run on failed build", *buildRunResults.SuccessSteps[0].StepInfo.Step.Title)
+	require.Equal(t, 1, len(buildRunResults.SkippedSteps))
+	require.Equal(t, "Should skip on failed build", *buildRunResults.SkippedSteps[0].StepInfo.Step.Title)
+}
+
 // Trivial fail test
 func TestFail(t *testing.T) {
 	configStr := `
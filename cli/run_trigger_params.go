@@ -15,6 +15,7 @@ import (
 type RunAndTriggerParamsModel struct {
 	// Run Params
 	WorkflowToRunID string `json:"workflow"`
+	PipelineToRunID string `json:"pipeline"`
 
 	// Trigger Params
 	TriggerPattern string `json:"pattern"`
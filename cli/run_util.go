@@ -390,13 +390,30 @@ func (r WorkflowRunner) executeStep(
 	stepAbsDirPath, bitriseSourceDir string,
 	secrets []string,
 	workflow models.WorkflowModel,
-	workflowID string,
+	stepContainer *docker.RunningContainer,
+	executionContext *workflowExecutionContext,
 ) (int, error) {
 
-	toolkitForStep := toolkits.ToolkitForStep(step)
+	// the step's own container overrides the workflow's container
+	container := executionContext.workflowContainer
+	if stepContainer != nil {
+		container = stepContainer
+	}
+
+	// steps running in a container are prepared (e.g. compiled) for the platform of the container,
+	// the workflow container is kept for the whole run, but only the steps of a workflow with a container run in it
+	var platform string
+	if container != nil && (workflow.Container.IsDefined() || stepContainer != nil) {
+		platform = container.Platform
+	}
+	toolkitForStep := toolkits.ToolkitForStepOnPlatform(step, platform)
 	toolkitName := toolkitForStep.ToolkitName()
 
-	if err := toolkitForStep.PrepareForStepRun(step, sIDData, stepAbsDirPath); err != nil {
+	// toolkits cache the prepared steps and tools for the whole host, so the preparation can't run concurrently
+	r.stepPreparationLock.Lock()
+	err := toolkitForStep.PrepareForStepRun(step, sIDData, stepAbsDirPath)
+	r.stepPreparationLock.Unlock()
+	if err != nil {
 		return 1, fmt.Errorf("Failed to prepare the step for execution through the required toolkit (%s), error: %s",
 			toolkitName, err)
 	}
@@ -428,36 +445,43 @@ func (r WorkflowRunner) executeStep(
 	opts.ProducerID = stepUUID
 	opts.DebugLogEnabled = true
 	logger := log.NewLogger(opts)
+	if executionContext.logPrefix != "" {
+		logger = log.NewPrefixedLogger(logger, executionContext.logPrefix)
+	}
 	stdout := logwriter.NewLogWriter(logger)
 
 	var name string
 	var args []string
 	var envs []string
 
-	if workflow.Container.Image != "" {
-		envs, err = envman.ReadAndEvaluateEnvs(configs.InputEnvstorePath, &docker.DockerEnvironmentSource{
+	if workflow.Container.IsDefined() || stepContainer != nil {
+		envs, err = envman.ReadAndEvaluateEnvs(executionContext.inputEnvstorePath, &docker.DockerEnvironmentSource{
 			Logger: logger,
 		})
 		if err != nil {
 			return 1, fmt.Errorf("failed to read command environment: %w", err)
 		}
 
-		name = "docker"
-		container := r.dockerManager.GetWorkflowContainer(workflowID)
 		if container == nil {
 			return 1, fmt.Errorf("Docker container does not exist")
 		}
 
-		args = container.ExecuteCommandArgs(envs)
+		name, args = container.ExecuteCommand(envs)
 		args = append(args, cmdArgs...)
 
 		cmd := stepruncmd.New(name, args, bitriseSourceDir, envs, stepSecrets, timeout, noOutputTimeout, stdout, logV2.NewLogger())
 
-		logger.Infof("Step is running in container: %s", workflow.Container.Image)
-		return cmd.Run()
+		logger.Infof("Step is running in container: %s", container.Image)
+		exitCode, err := cmd.Run()
+		if err != nil && r.config.KeepContainers {
+			if err := saveContainerSession(executionContext.workflowID, name, container.Name, envs, r.config.Secrets); err != nil {
+				log.Warnf("Failed to save the container session of the step: %s", err)
+			}
+		}
+		return exitCode, err
 	}
 
-	envs, err = envman.ReadAndEvaluateEnvs(configs.InputEnvstorePath, &envmanEnv.DefaultEnvironmentSource{})
+	envs, err = envman.ReadAndEvaluateEnvs(executionContext.inputEnvstorePath, &envmanEnv.DefaultEnvironmentSource{})
 	if err != nil {
 		return 1, fmt.Errorf("failed to read command environment: %w", err)
 	}
@@ -480,7 +504,8 @@ func (r WorkflowRunner) runStep(
 	environments []envmanModels.EnvironmentItemModel,
 	secrets []string,
 	workflow models.WorkflowModel,
-	workflowID string,
+	stepContainer *docker.RunningContainer,
+	executionContext *workflowExecutionContext,
 ) (int, []envmanModels.EnvironmentItemModel, error) {
 	log.Debugf("[BITRISE_CLI] - Try running step: %s (%s)", stepIDData.IDorURI, stepIDData.Version)
 
@@ -501,11 +526,11 @@ func (r WorkflowRunner) runStep(
 			fmt.Errorf("Failed to install Step dependency, error: %s", err)
 	}
 
-	if err := tools.EnvmanInit(configs.InputEnvstorePath, true); err != nil {
+	if err := tools.EnvmanInit(executionContext.inputEnvstorePath, true); err != nil {
 		return 1, []envmanModels.EnvironmentItemModel{}, err
 	}
 
-	if err := tools.EnvmanAddEnvs(configs.InputEnvstorePath, environments); err != nil {
+	if err := tools.EnvmanAddEnvs(executionContext.inputEnvstorePath, environments); err != nil {
 		return 1, []envmanModels.EnvironmentItemModel{}, err
 	}
 
@@ -518,15 +543,15 @@ func (r WorkflowRunner) runStep(
 		bitriseSourceDir = configs.CurrentDir
 	}
 
-	if exit, err := r.executeStep(stepUUID, step, stepIDData, stepDir, bitriseSourceDir, secrets, workflow, workflowID); err != nil {
-		stepOutputs, envErr := bitrise.CollectEnvironmentsFromFile(configs.OutputEnvstorePath)
+	if exit, err := r.executeStep(stepUUID, step, stepIDData, stepDir, bitriseSourceDir, secrets, workflow, stepContainer, executionContext); err != nil {
+		stepOutputs, envErr := bitrise.CollectEnvironmentsFromFile(executionContext.outputEnvstorePath)
 		if envErr != nil {
 			return 1, []envmanModels.EnvironmentItemModel{}, envErr
 		}
 
 		updatedStepOutputs, updateErr := stepOutputs, error(nil)
 
-		if configs.IsSecretEnvsFiltering {
+		if executionContext.modes.SecretEnvsFilteringMode {
 			updatedStepOutputs, updateErr = bitrise.ApplySensitiveOutputs(updatedStepOutputs, step.Outputs)
 			if updateErr != nil {
 				return 1, []envmanModels.EnvironmentItemModel{}, updateErr
@@ -541,14 +566,14 @@ func (r WorkflowRunner) runStep(
 		return exit, updatedStepOutputs, err
 	}
 
-	stepOutputs, err := bitrise.CollectEnvironmentsFromFile(configs.OutputEnvstorePath)
+	stepOutputs, err := bitrise.CollectEnvironmentsFromFile(executionContext.outputEnvstorePath)
 	if err != nil {
 		return 1, []envmanModels.EnvironmentItemModel{}, err
 	}
 
 	updatedStepOutputs, updateErr := stepOutputs, error(nil)
 
-	if configs.IsSecretEnvsFiltering {
+	if executionContext.modes.SecretEnvsFilteringMode {
 		updatedStepOutputs, updateErr = bitrise.ApplySensitiveOutputs(updatedStepOutputs, step.Outputs)
 		if updateErr != nil {
 			return 1, []envmanModels.EnvironmentItemModel{}, updateErr
@@ -570,7 +595,14 @@ type DockerManager interface {
 	StartWorkflowContainer(models.Container, string, map[string]string) (*docker.RunningContainer, error)
 	StartServiceContainers(services map[string]models.Container, workflowID string, envs map[string]string) ([]*docker.RunningContainer, error)
 	GetWorkflowContainer(string) *docker.RunningContainer
+	StartStepContainer(container models.Container, workflowID, stepExecutionID string, envs map[string]string) (*docker.RunningContainer, error)
 	GetServiceContainers(string) []*docker.RunningContainer
+	SaveServiceLogs(workflowID string, envs map[string]string) error
+	DestroyStepContainer(stepExecutionID string) error
+	StartComposeServices(composeFile string, workflowID string, envs map[string]string) ([]*docker.RunningContainer, error)
+	StopComposeServices(workflowID string) error
+	RemoveWorkflowNetwork(workflowID string) error
+	RemoveRegistryConfig() error
 	DestroyAllContainers() error
 }
 
@@ -585,44 +617,96 @@ func (r WorkflowRunner) activateAndRunSteps(
 	tracker analytics.Tracker,
 	workflowIDProperties coreanalytics.Properties,
 	workflowID string,
+	executionContext *workflowExecutionContext,
 ) models.BuildRunResultsModel {
 	log.Debug("[BITRISE_CLI] - Activating and running steps")
 
-	if len(workflow.Steps) == 0 {
+	if len(plan.Steps) == 0 {
 		log.Warnf("%s workflow has no steps to run, moving on to the next workflow...", workflow.Title)
 		return buildRunResults
 	}
 
 	envList := envmanModels.EnvsJSONListModel{}
-	if workflow.Container.Image != "" || len(workflow.Services) > 0 {
-		if err := tools.EnvmanInit(configs.InputEnvstorePath, true); err != nil {
+	if workflow.Container.IsDefined() || len(workflow.Services) > 0 || workflow.ServicesCompose != "" {
+		if err := tools.EnvmanInit(executionContext.inputEnvstorePath, true); err != nil {
 			log.Debugf("Couldn't initialize envman.")
 		}
-		if err := tools.EnvmanAddEnvs(configs.InputEnvstorePath, *environments); err != nil {
+		if err := tools.EnvmanAddEnvs(executionContext.inputEnvstorePath, *environments); err != nil {
 			log.Debugf("Couldn't add envs.")
 		}
 
 		var err error
-		if envList, err = tools.EnvmanReadEnvList(configs.InputEnvstorePath); err != nil {
+		if envList, err = tools.EnvmanReadEnvList(executionContext.inputEnvstorePath); err != nil {
 			log.Debugf("Couldn't read envs from envman.")
 		}
 	}
 
+	// the containers of a failed workflow are kept for debugging, if requested
+	keepContainers := func() bool {
+		return r.config.KeepContainers && buildRunResults.IsBuildFailed()
+	}
+	var keptContainers []*docker.RunningContainer
+	defer func() {
+		if len(keptContainers) > 0 {
+			printKeptContainers(executionContext.workflowID, keptContainers)
+		}
+	}()
+
+	// the network is removed once every container of the workflow is removed, these deferred calls run before this one
+	defer func() {
+		if keepContainers() {
+			return
+		}
+		if err := r.dockerManager.RemoveWorkflowNetwork(workflowID); err != nil {
+			log.Warnf("Failed to remove the docker network of workflow (%s): %s", workflowID, err)
+		}
+	}()
+
+	executionContext.runningWorkflowID = workflowID
+	// the services are started per workflow, the failure of a previous workflow's services doesn't affect this workflow
+	executionContext.servicesErr = nil
 	serviceContainers, err := r.dockerManager.StartServiceContainers(workflow.Services, workflowID, envList)
 	if err != nil {
 		log.Errorf("❌ Some services failed to start properly!")
+		executionContext.servicesErr = err
 	}
+	if workflow.ServicesCompose != "" {
+		composeContainers, err := r.dockerManager.StartComposeServices(workflow.ServicesCompose, workflowID, envList)
+		if err != nil {
+			log.Errorf("❌ Some services of the compose file failed to start properly!")
+			if executionContext.servicesErr == nil {
+				executionContext.servicesErr = err
+			}
+		}
+		serviceContainers = append(serviceContainers, composeContainers...)
+	}
+	executionContext.serviceContainers = serviceContainers
 
 	defer func() {
+		if len(serviceContainers) > 0 {
+			if err := r.dockerManager.SaveServiceLogs(workflowID, envList); err != nil {
+				log.Warnf("Failed to save service logs: %s", err)
+			}
+		}
+
+		if keepContainers() {
+			keptContainers = append(keptContainers, serviceContainers...)
+			return
+		}
 		for _, container := range serviceContainers {
 			if err := container.Destroy(); err != nil {
 				log.Errorf("Attempted to stop the docker container for service: %s: %w", container.Name, err.Error())
 			}
 		}
+		if workflow.ServicesCompose != "" {
+			if err := r.dockerManager.StopComposeServices(workflowID); err != nil {
+				log.Errorf("Attempted to stop the compose services of workflow: %s: %s", workflow.Title, err)
+			}
+		}
 	}()
 
-	if workflow.Container.Image != "" {
-		log.Infof("ℹ️ Running workflow in docker container: %s", workflow.Container.Image)
+	if workflow.Container.IsDefined() {
+		log.Infof("ℹ️ Running workflow in docker container: %s", workflow.Container.ImageRef())
 
 		if err := r.dockerManager.Login(workflow.Container, envList); err != nil {
 			log.Errorf("%s workflow has docker credentials provided, but the authentication failed.", workflow.Title)
@@ -632,308 +716,500 @@ func (r WorkflowRunner) activateAndRunSteps(
 		if err != nil {
 			log.Errorf("Could not start the specified docker image for workflow: %s", workflow.Title)
 		}
+		executionContext.workflowContainer = runningContainer
 
 		defer func() {
 			if runningContainer == nil {
 				return
 			}
 
-			// TODO: Feature idea, make this configurable, so that we can keep the container for debugging purposes.
+			if keepContainers() {
+				keptContainers = append(keptContainers, runningContainer)
+				return
+			}
 			if err := runningContainer.Destroy(); err != nil {
 				log.Errorf("Attempted to stop the docker container for workflow: %s: %w", workflow.Title, err.Error())
 			}
 		}()
 	}
 
-	// ------------------------------------------
-	// In function global variables - These are global for easy use in local register step run result methods.
-	var stepStartTime time.Time
-	runResultCollector := newBuildRunResultCollector(tracker)
-
 	// ------------------------------------------
 	// Main - Preparing & running the steps
-	for idx, stepListItm := range workflow.Steps {
-		stepPlan := plan.Steps[idx]
-		stepExecutionID := stepPlan.UUID
-		stepIDProperties := coreanalytics.Properties{analytics.StepExecutionID: stepExecutionID}
-		stepStartedProperties := workflowIDProperties.Merge(stepIDProperties)
-		// Per step variables
-		stepStartTime = time.Now()
-		isLastStep := isLastWorkflow && (idx == len(workflow.Steps)-1)
-		// TODO: stepInfoPtr.Step is not a real step, only stores presentation properties (printed in the step boxes)
-		stepInfoPtr := stepmanModels.StepInfoModel{}
-		stepIdxPtr := idx
-
-		// Per step cleanup
-		if err := bitrise.SetBuildFailedEnv(buildRunResults.IsBuildFailed()); err != nil {
-			log.Error("Failed to set Build Status envs")
-		}
-
-		if err := bitrise.CleanupStepWorkDir(); err != nil {
-			runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
-				models.StepRunStatusCodePreparationFailed, 1, err, isLastStep, true, map[string]string{}, stepStartedProperties)
-			continue
+	stepPosition := 0
+	for idx, stepPlan := range plan.Steps {
+		if executionContext.isAborted() {
+			log.Warnf("%s workflow was aborted, skipping the remaining steps...", workflow.Title)
+			executionContext.aborted = true
+			break
 		}
 
-		//
-		// Preparing the step
-		if err := tools.EnvmanInit(configs.InputEnvstorePath, true); err != nil {
-			runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
-				models.StepRunStatusCodePreparationFailed, 1, err, isLastStep, true, map[string]string{}, stepStartedProperties)
-			continue
+		// every step of a parallel step group has its own position, the positions of the following steps are shifted
+		position := stepPosition
+		parallelSteps, isParallelStepGroup := stepPlan.Step.GetParallelSteps()
+		if isParallelStepGroup {
+			stepPosition += len(parallelSteps)
+		} else {
+			stepPosition++
 		}
 
-		if err := tools.EnvmanAddEnvs(configs.InputEnvstorePath, *environments); err != nil {
-			runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
-				models.StepRunStatusCodePreparationFailed, 1, err, isLastStep, true, map[string]string{}, stepStartedProperties)
+		if executionContext.resumeFrom != nil && idx < executionContext.resumeFrom.StepIdx {
 			continue
 		}
 
-		// Get step id & version data
-		compositeStepIDStr, workflowStep, err := models.GetStepIDStepDataPair(stepListItm)
-		if err != nil {
-			runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
-				models.StepRunStatusCodePreparationFailed, 1, err, isLastStep, true, map[string]string{}, stepStartedProperties)
-			continue
+		if executionContext.checkpoints != nil {
+			if err := executionContext.checkpoints.save(executionContext.workflowIdx, workflowID, idx, *environments); err != nil {
+				log.Warnf("Failed to save checkpoint: %s", err)
+			}
 		}
-		stepInfoPtr.ID = compositeStepIDStr
-		if workflowStep.Title != nil && *workflowStep.Title != "" {
-			stepInfoPtr.Step.Title = pointers.NewStringPtr(*workflowStep.Title)
+
+		isLastStep := isLastWorkflow && (idx == len(plan.Steps)-1)
+
+		var outEnvironments []envmanModels.EnvironmentItemModel
+		if isParallelStepGroup {
+			buildRunResults, outEnvironments = r.runParallelStepGroup(parallelSteps, position, stepPlan, workflow, defaultStepLibSource,
+				buildRunResults, *environments, secrets, isLastStep, tracker, workflowIDProperties, executionContext)
 		} else {
-			stepInfoPtr.Step.Title = pointers.NewStringPtr(compositeStepIDStr)
+			buildRunResults, outEnvironments = r.activateAndRunStep(stepPlan.Step, position, stepPlan, workflow, defaultStepLibSource,
+				buildRunResults, withStepBundleEnvs(*environments, stepPlan), secrets, isLastStep, tracker, workflowIDProperties, executionContext)
 		}
 
-		stepIDData, err := models.CreateStepIDDataFromString(compositeStepIDStr, defaultStepLibSource)
+		*environments = append(*environments, outEnvironments...)
+		executionContext.stepOutputs = append(executionContext.stepOutputs, outEnvironments...)
+	}
+
+	return buildRunResults
+}
+
+// printKeptContainers prints how to open a shell in the kept containers and how to remove them.
+func printKeptContainers(workflowID string, containers []*docker.RunningContainer) {
+	var names []string
+	for _, container := range containers {
+		names = append(names, container.Name)
+	}
+
+	log.Print()
+	log.Warnf("Kept the docker containers of the failed workflow: %s", strings.Join(names, ", "))
+	log.Printf("Open a shell with the env of the failed step: bitrise container shell %s", workflowID)
+	log.Printf("Remove the containers: %s rm --force %s", containers[0].Runtime.Binary(), strings.Join(names, " "))
+}
+
+// startStepContainer logs in to the registry of the step's container and starts the container,
+// envs are used to resolve the env var references of the container's credentials, envs and volumes.
+func (r WorkflowRunner) startStepContainer(container models.Container, workflowID, stepExecutionID string, envs map[string]string) (*docker.RunningContainer, error) {
+	log.Infof("ℹ️ Running step in docker container: %s", container.ImageRef())
+
+	if err := r.dockerManager.Login(container, envs); err != nil {
+		return nil, fmt.Errorf("docker credentials provided, but the authentication failed: %w", err)
+	}
+
+	return r.dockerManager.StartStepContainer(container, workflowID, stepExecutionID, envs)
+}
+
+// withStepBundleEnvs adds the inputs and envs of the step bundles the step is expanded from to the envs of the step,
+// the returned list is a copy, so the bundle envs are not available for the steps after the bundle.
+func withStepBundleEnvs(environments []envmanModels.EnvironmentItemModel, stepPlan models.StepExecutionPlan) []envmanModels.EnvironmentItemModel {
+	if len(stepPlan.StepBundleEnvs) == 0 {
+		return environments
+	}
+	stepEnvironments := append([]envmanModels.EnvironmentItemModel{}, environments...)
+	return append(stepEnvironments, copyEnvironmentItems(stepPlan.StepBundleEnvs)...)
+}
+
+// activateAndRunStep prepares and runs a single step of the workflow and registers its result,
+// it returns the outputs of the step.
+func (r WorkflowRunner) activateAndRunStep(
+	stepListItm models.StepListItemModel,
+	idx int,
+	stepPlan models.StepExecutionPlan,
+	workflow models.WorkflowModel,
+	defaultStepLibSource string,
+	buildRunResults models.BuildRunResultsModel,
+	environments []envmanModels.EnvironmentItemModel,
+	secrets []envmanModels.EnvironmentItemModel,
+	isLastStep bool,
+	tracker analytics.Tracker,
+	workflowIDProperties coreanalytics.Properties,
+	executionContext *workflowExecutionContext,
+) (models.BuildRunResultsModel, []envmanModels.EnvironmentItemModel) {
+	runResultCollector := newBuildRunResultCollector(tracker, executionContext.logger())
+
+	stepExecutionID := stepPlan.UUID
+	stepIDProperties := coreanalytics.Properties{analytics.StepExecutionID: stepExecutionID}
+	stepStartedProperties := workflowIDProperties.Merge(stepIDProperties)
+	stepStartTime := time.Now()
+	// TODO: stepInfoPtr.Step is not a real step, only stores presentation properties (printed in the step boxes)
+	stepInfoPtr := stepmanModels.StepInfoModel{}
+	stepIdxPtr := idx
+
+	// Per step cleanup
+	// The build status is passed to the step by the execution context's step envs, the process env is not changed,
+	// as the workflows of a pipeline stage run concurrently
+	if err := bitrise.CleanupStepWorkDir(executionContext.workDirPath, executionContext.stepsDirPath); err != nil {
+		runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
+			models.StepRunStatusCodePreparationFailed, 1, err, isLastStep, true, map[string]string{}, stepStartedProperties)
+		return buildRunResults, nil
+	}
+
+	//
+	// Preparing the step
+	if err := tools.EnvmanInit(executionContext.inputEnvstorePath, true); err != nil {
+		runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
+			models.StepRunStatusCodePreparationFailed, 1, err, isLastStep, true, map[string]string{}, stepStartedProperties)
+		return buildRunResults, nil
+	}
+
+	if err := tools.EnvmanAddEnvs(executionContext.inputEnvstorePath, environments); err != nil {
+		runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
+			models.StepRunStatusCodePreparationFailed, 1, err, isLastStep, true, map[string]string{}, stepStartedProperties)
+		return buildRunResults, nil
+	}
+
+	// Get step id & version data
+	compositeStepIDStr, workflowStep, err := models.GetStepIDStepDataPair(stepListItm)
+	if err != nil {
+		runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
+			models.StepRunStatusCodePreparationFailed, 1, err, isLastStep, true, map[string]string{}, stepStartedProperties)
+		return buildRunResults, nil
+	}
+	// the step's inputs and outputs are filled with their defaults while the step is prepared,
+	// the same step of the config might be prepared concurrently (e.g. in a before_run workflow shared by the workflows of a stage)
+	workflowStep.Inputs = copyEnvironmentItems(workflowStep.Inputs)
+	workflowStep.Outputs = copyEnvironmentItems(workflowStep.Outputs)
+	stepInfoPtr.ID = compositeStepIDStr
+	if workflowStep.Title != nil && *workflowStep.Title != "" {
+		stepInfoPtr.Step.Title = pointers.NewStringPtr(*workflowStep.Title)
+	} else {
+		stepInfoPtr.Step.Title = pointers.NewStringPtr(compositeStepIDStr)
+	}
+
+	stepIDData, err := models.CreateStepIDDataFromString(compositeStepIDStr, defaultStepLibSource)
+	if err != nil {
+		runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
+			models.StepRunStatusCodePreparationFailed, 1, err, isLastStep, true, map[string]string{}, stepStartedProperties)
+		return buildRunResults, nil
+	}
+	stepInfoPtr.ID = stepIDData.IDorURI
+	if stepInfoPtr.Step.Title == nil || *stepInfoPtr.Step.Title == "" {
+		stepInfoPtr.Step.Title = pointers.NewStringPtr(stepIDData.IDorURI)
+	}
+	stepInfoPtr.Version = stepIDData.Version
+	stepInfoPtr.Library = stepIDData.SteplibSource
+
+	//
+	// Activating the step
+	stepDir := executionContext.stepsDirPath
+
+	// step activation updates the host wide steplib caches, so it can't run concurrently
+	activator := newStepActivator()
+	r.stepPreparationLock.Lock()
+	stepYMLPth, origStepYMLPth, err := activator.activateStep(stepIDData, &buildRunResults, stepDir, executionContext.workDirPath, &workflowStep, &stepInfoPtr)
+	r.stepPreparationLock.Unlock()
+	if err != nil {
+		runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
+			models.StepRunStatusCodePreparationFailed, 1, err, isLastStep, true, map[string]string{}, stepStartedProperties)
+		return buildRunResults, nil
+	}
+
+	// Fill step info with default step info, if exist
+	mergedStep := workflowStep
+	if stepYMLPth != "" {
+		specStep, err := bitrise.ReadSpecStep(stepYMLPth)
+		log.Debugf("Spec read from YML: %#v", specStep)
 		if err != nil {
+			ymlPth := stepYMLPth
+			if origStepYMLPth != "" {
+				// in case of local step (path:./) we use the original step definition path,
+				// instead of the activated step's one.
+				ymlPth = origStepYMLPth
+			}
 			runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
-				models.StepRunStatusCodePreparationFailed, 1, err, isLastStep, true, map[string]string{}, stepStartedProperties)
-			continue
-		}
-		stepInfoPtr.ID = stepIDData.IDorURI
-		if stepInfoPtr.Step.Title == nil || *stepInfoPtr.Step.Title == "" {
-			stepInfoPtr.Step.Title = pointers.NewStringPtr(stepIDData.IDorURI)
+				models.StepRunStatusCodePreparationFailed, 1, fmt.Errorf("failed to parse step definition (%s): %s", ymlPth, err),
+				isLastStep, true, map[string]string{}, stepStartedProperties)
+			return buildRunResults, nil
 		}
-		stepInfoPtr.Version = stepIDData.Version
-		stepInfoPtr.Library = stepIDData.SteplibSource
 
-		//
-		// Activating the step
-		stepDir := configs.BitriseWorkStepsDirPath
-
-		activator := newStepActivator()
-		stepYMLPth, origStepYMLPth, err := activator.activateStep(stepIDData, &buildRunResults, stepDir, configs.BitriseWorkDirPath, &workflowStep, &stepInfoPtr)
+		mergedStep, err = models.MergeStepWith(specStep, workflowStep)
 		if err != nil {
 			runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
 				models.StepRunStatusCodePreparationFailed, 1, err, isLastStep, true, map[string]string{}, stepStartedProperties)
-			continue
+			return buildRunResults, nil
 		}
+	}
 
-		// Fill step info with default step info, if exist
-		mergedStep := workflowStep
-		if stepYMLPth != "" {
-			specStep, err := bitrise.ReadSpecStep(stepYMLPth)
-			log.Debugf("Spec read from YML: %#v", specStep)
-			if err != nil {
-				ymlPth := stepYMLPth
-				if origStepYMLPth != "" {
-					// in case of local step (path:./) we use the original step definition path,
-					// instead of the activated step's one.
-					ymlPth = origStepYMLPth
-				}
-				runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
-					models.StepRunStatusCodePreparationFailed, 1, fmt.Errorf("failed to parse step definition (%s): %s", ymlPth, err),
-					isLastStep, true, map[string]string{}, stepStartedProperties)
-				continue
-			}
+	if mergedStep.SupportURL != nil {
+		stepInfoPtr.Step.SupportURL = pointers.NewStringPtr(*mergedStep.SupportURL)
+	}
+	if mergedStep.SourceCodeURL != nil {
+		stepInfoPtr.Step.SourceCodeURL = pointers.NewStringPtr(*mergedStep.SourceCodeURL)
+	}
 
-			mergedStep, err = models.MergeStepWith(specStep, workflowStep)
-			if err != nil {
-				runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
-					models.StepRunStatusCodePreparationFailed, 1, err, isLastStep, true, map[string]string{}, stepStartedProperties)
-				continue
-			}
-		}
+	if mergedStep.RunIf != nil {
+		stepInfoPtr.Step.RunIf = pointers.NewStringPtr(*mergedStep.RunIf)
+	}
 
-		if mergedStep.SupportURL != nil {
-			stepInfoPtr.Step.SupportURL = pointers.NewStringPtr(*mergedStep.SupportURL)
-		}
-		if mergedStep.SourceCodeURL != nil {
-			stepInfoPtr.Step.SourceCodeURL = pointers.NewStringPtr(*mergedStep.SourceCodeURL)
-		}
+	if mergedStep.Timeout != nil {
+		stepInfoPtr.Step.Timeout = pointers.NewIntPtr(*mergedStep.Timeout)
+	}
 
-		if mergedStep.RunIf != nil {
-			stepInfoPtr.Step.RunIf = pointers.NewStringPtr(*mergedStep.RunIf)
-		}
+	if mergedStep.NoOutputTimeout != nil {
+		stepInfoPtr.Step.NoOutputTimeout = pointers.NewIntPtr(*mergedStep.NoOutputTimeout)
+	}
 
-		if mergedStep.Timeout != nil {
-			stepInfoPtr.Step.Timeout = pointers.NewIntPtr(*mergedStep.Timeout)
+	// At this point we have a filled up step info model and also have a step model which is contains the merged step
+	// data from the bitrise.yml and the steps step.yml.
+	// If the step title contains the step id or the step library as a prefix then we will take the original steps
+	// title instead.
+	// Here are a couple of before and after examples:
+	// git::https://github.com/bitrise-steplib/bitrise-step-simple-git-clone.git -> Simple Git Clone
+	// certificate-and-profile-installer@1 -> Certificate and profile installer
+	if stepInfoPtr.Step.Title != nil && (strings.HasPrefix(*stepInfoPtr.Step.Title, stepInfoPtr.ID) || strings.HasPrefix(*stepInfoPtr.Step.Title, stepInfoPtr.Library)) {
+		if mergedStep.Title != nil && *mergedStep.Title != "" {
+			*stepInfoPtr.Step.Title = *mergedStep.Title
 		}
+	}
 
-		if mergedStep.NoOutputTimeout != nil {
-			stepInfoPtr.Step.NoOutputTimeout = pointers.NewIntPtr(*mergedStep.NoOutputTimeout)
-		}
+	//
+	// Run step
+	logStepStarted(runResultCollector.logger, stepInfoPtr, mergedStep, idx, stepExecutionID, stepStartTime)
 
-		// At this point we have a filled up step info model and also have a step model which is contains the merged step
-		// data from the bitrise.yml and the steps step.yml.
-		// If the step title contains the step id or the step library as a prefix then we will take the original steps
-		// title instead.
-		// Here are a couple of before and after examples:
-		// git::https://github.com/bitrise-steplib/bitrise-step-simple-git-clone.git -> Simple Git Clone
-		// certificate-and-profile-installer@1 -> Certificate and profile installer
-		if stepInfoPtr.Step.Title != nil && (strings.HasPrefix(*stepInfoPtr.Step.Title, stepInfoPtr.ID) || strings.HasPrefix(*stepInfoPtr.Step.Title, stepInfoPtr.Library)) {
-			if mergedStep.Title != nil && *mergedStep.Title != "" {
-				*stepInfoPtr.Step.Title = *mergedStep.Title
-			}
+	if mergedStep.RunIf != nil && *mergedStep.RunIf != "" {
+		envList, err := tools.EnvmanReadEnvList(executionContext.inputEnvstorePath)
+		if err != nil {
+			runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
+				models.StepRunStatusCodePreparationFailed, 1, fmt.Errorf("EnvmanReadEnvList failed, err: %s", err),
+				isLastStep, false, map[string]string{}, stepStartedProperties)
+			return buildRunResults, nil
 		}
 
-		//
-		// Run step
-		logStepStarted(stepInfoPtr, mergedStep, idx, stepExecutionID, stepStartTime)
-
-		if mergedStep.RunIf != nil && *mergedStep.RunIf != "" {
-			envList, err := tools.EnvmanReadEnvList(configs.InputEnvstorePath)
+		// the build status envs are only set for the step processes, not in the process wide envs
+		for _, envItem := range executionContext.stepEnvironments(buildRunResults.IsBuildFailed()) {
+			key, value, err := envItem.GetKeyValuePair()
 			if err != nil {
-				runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
-					models.StepRunStatusCodePreparationFailed, 1, fmt.Errorf("EnvmanReadEnvList failed, err: %s", err),
-					isLastStep, false, map[string]string{}, stepStartedProperties)
-				continue
-			}
-
-			isRun, err := bitrise.EvaluateTemplateToBool(*mergedStep.RunIf, configs.IsCIMode, configs.IsPullRequestMode, buildRunResults, envList)
-			if err != nil {
-				runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
-					models.StepRunStatusCodePreparationFailed, 1, err, isLastStep, false, map[string]string{}, stepStartedProperties)
-				continue
-			}
-			if !isRun {
-				runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
-					models.StepRunStatusCodeSkippedWithRunIf, 0, err, isLastStep, false, map[string]string{}, stepStartedProperties)
 				continue
 			}
+			envList[key] = value
 		}
 
-		isAlwaysRun := stepmanModels.DefaultIsAlwaysRun
-		if mergedStep.IsAlwaysRun != nil {
-			isAlwaysRun = *mergedStep.IsAlwaysRun
-		} else {
-			log.Warnf("Step (%s) mergedStep.IsAlwaysRun is nil, should not!", stepIDData.IDorURI)
+		isRun, err := bitrise.EvaluateTemplateToBool(*mergedStep.RunIf, executionContext.modes.CIMode, executionContext.modes.PRMode, buildRunResults, envList)
+		if err != nil {
+			runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
+				models.StepRunStatusCodePreparationFailed, 1, err, isLastStep, false, map[string]string{}, stepStartedProperties)
+			return buildRunResults, nil
 		}
-
-		if buildRunResults.IsBuildFailed() && !isAlwaysRun {
+		if !isRun {
 			runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
-				models.StepRunStatusCodeSkipped, 0, err, isLastStep, false, map[string]string{}, stepStartedProperties)
-		} else {
-			// beside of the envs coming from the current parent process these will be added as an extra
-			var additionalEnvironments []envmanModels.EnvironmentItemModel
+				models.StepRunStatusCodeSkippedWithRunIf, 0, err, isLastStep, false, map[string]string{}, stepStartedProperties)
+			return buildRunResults, nil
+		}
+	}
 
-			// add this environment variable so all child processes can connect their events to their step lifecycle events
-			additionalEnvironments = append(additionalEnvironments, envmanModels.EnvironmentItemModel{
-				analytics.StepExecutionIDEnvKey: stepExecutionID,
-			})
+	isAlwaysRun := stepmanModels.DefaultIsAlwaysRun
+	if mergedStep.IsAlwaysRun != nil {
+		isAlwaysRun = *mergedStep.IsAlwaysRun
+	} else {
+		log.Warnf("Step (%s) mergedStep.IsAlwaysRun is nil, should not!", stepIDData.IDorURI)
+	}
+
+	if buildRunResults.IsBuildFailed() && !isAlwaysRun {
+		runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
+			models.StepRunStatusCodeSkipped, 0, err, isLastStep, false, map[string]string{}, stepStartedProperties)
+	} else {
+		// beside of the envs coming from the current parent process these will be added as an extra
+		var additionalEnvironments []envmanModels.EnvironmentItemModel
+
+		// add this environment variable so all child processes can connect their events to their step lifecycle events
+		additionalEnvironments = append(additionalEnvironments, envmanModels.EnvironmentItemModel{
+			analytics.StepExecutionIDEnvKey: stepExecutionID,
+		})
+
+		// add an extra env for the next step run to be able to access the step's source location
+		additionalEnvironments = append(additionalEnvironments, envmanModels.EnvironmentItemModel{
+			"BITRISE_STEP_SOURCE_DIR": stepDir,
+		})
 
-			// add an extra env for the next step run to be able to access the step's source location
+		// point the step to the envstores of the workflow, instead of the process wide ones
+		additionalEnvironments = append(additionalEnvironments, executionContext.stepEnvironments(buildRunResults.IsBuildFailed())...)
+
+		// ensure a new testDirPath and if created successfuly then attach it to the step process by and env
+		testDirPath, err := ioutil.TempDir(executionContext.testDeployDirPath, "test_result")
+		if err != nil {
+			log.Errorf("Failed to create test result dir, error: %s", err)
+		}
+
+		if testDirPath != "" {
+			// managed to create the test dir, set the env for it for the next step run
 			additionalEnvironments = append(additionalEnvironments, envmanModels.EnvironmentItemModel{
-				"BITRISE_STEP_SOURCE_DIR": stepDir,
+				configs.BitrisePerStepTestResultDirEnvKey: testDirPath,
 			})
+		}
 
-			// ensure a new testDirPath and if created successfuly then attach it to the step process by and env
-			testDirPath, err := ioutil.TempDir(os.Getenv(configs.BitriseTestDeployDirEnvKey), "test_result")
-			if err != nil {
-				log.Errorf("Failed to create test result dir, error: %s", err)
-			}
-
-			if testDirPath != "" {
-				// managed to create the test dir, set the env for it for the next step run
-				additionalEnvironments = append(additionalEnvironments, envmanModels.EnvironmentItemModel{
-					configs.BitrisePerStepTestResultDirEnvKey: testDirPath,
-				})
-			}
+		environmentItemModels := append(environments, additionalEnvironments...)
+		envSource := &env.DefaultEnvironmentSource{}
+		stepDeclaredEnvironments, expandedStepEnvironment, redactedInputsWithType, err := prepareStepEnvironment(prepareStepInputParams{
+			environment:       environmentItemModels,
+			inputs:            mergedStep.Inputs,
+			buildRunResults:   buildRunResults,
+			isCIMode:          executionContext.modes.CIMode,
+			isPullRequestMode: executionContext.modes.PRMode,
+		}, envSource)
+		if err != nil {
+			runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
+				models.StepRunStatusCodePreparationFailed, 1,
+				fmt.Errorf("failed to prepare step environment variables: %s", err),
+				isLastStep, false, map[string]string{}, stepStartedProperties)
+			return buildRunResults, nil
+		}
 
-			environmentItemModels := append(*environments, additionalEnvironments...)
-			envSource := &env.DefaultEnvironmentSource{}
-			stepDeclaredEnvironments, expandedStepEnvironment, redactedInputsWithType, err := prepareStepEnvironment(prepareStepInputParams{
-				environment:       environmentItemModels,
-				inputs:            mergedStep.Inputs,
-				buildRunResults:   buildRunResults,
-				isCIMode:          configs.IsCIMode,
-				isPullRequestMode: configs.IsPullRequestMode,
-			}, envSource)
+		stepSecretKeys, stepSecretValues := tools.GetSecretKeysAndValues(secrets)
+		if executionContext.modes.SecretEnvsFilteringMode {
+			sensitiveEnvs, err := getSensitiveEnvs(stepDeclaredEnvironments, expandedStepEnvironment)
 			if err != nil {
 				runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
 					models.StepRunStatusCodePreparationFailed, 1,
-					fmt.Errorf("failed to prepare step environment variables: %s", err),
+					fmt.Errorf("failed to get sensitive inputs: %s", err),
 					isLastStep, false, map[string]string{}, stepStartedProperties)
-				continue
+				return buildRunResults, nil
 			}
 
-			stepSecretKeys, stepSecretValues := tools.GetSecretKeysAndValues(secrets)
-			if configs.IsSecretEnvsFiltering {
-				sensitiveEnvs, err := getSensitiveEnvs(stepDeclaredEnvironments, expandedStepEnvironment)
-				if err != nil {
-					runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
-						models.StepRunStatusCodePreparationFailed, 1,
-						fmt.Errorf("failed to get sensitive inputs: %s", err),
-						isLastStep, false, map[string]string{}, stepStartedProperties)
-					continue
-				}
+			sensitiveEnvKeys, sensitiveEnvValues := tools.GetSecretKeysAndValues(sensitiveEnvs)
+			stepSecretKeys = append(stepSecretKeys, sensitiveEnvKeys...)
+			stepSecretValues = append(stepSecretValues, sensitiveEnvValues...)
+		}
+
+		redactedStepInputs, redactedOriginalInputs, err := redactStepInputs(expandedStepEnvironment, mergedStep.Inputs, stepSecretValues)
+		if err != nil {
+			runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
+				models.StepRunStatusCodePreparationFailed, 1,
+				fmt.Errorf("failed to redact step inputs: %s", err),
+				isLastStep, false, map[string]string{}, stepStartedProperties)
+			return buildRunResults, nil
+		}
 
-				sensitiveEnvKeys, sensitiveEnvValues := tools.GetSecretKeysAndValues(sensitiveEnvs)
-				stepSecretKeys = append(stepSecretKeys, sensitiveEnvKeys...)
-				stepSecretValues = append(stepSecretValues, sensitiveEnvValues...)
+		for key, value := range redactedStepInputs {
+			if _, ok := redactedInputsWithType[key]; !ok {
+				redactedInputsWithType[key] = value
 			}
+		}
+
+		secretKeysEnv := secretEnvKeysEnvironment(stepSecretKeys)
+		stepDeclaredEnvironments = append(stepDeclaredEnvironments, secretKeysEnv)
 
-			redactedStepInputs, redactedOriginalInputs, err := redactStepInputs(expandedStepEnvironment, mergedStep.Inputs, stepSecretValues)
+		if executionContext.servicesErr != nil {
+			runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
+				models.StepRunStatusCodePreparationFailed, 1, fmt.Errorf("the services of the workflow are not ready: %s", executionContext.servicesErr),
+				isLastStep, false, map[string]string{}, stepStartedProperties)
+			return buildRunResults, nil
+		}
+
+		var stepContainer *docker.RunningContainer
+		var stepFailed bool
+		if container := stepListItm.GetContainer(); container != nil {
+			stepContainer, err = r.startStepContainer(*container, executionContext.runningWorkflowID, stepExecutionID, expandedStepEnvironment)
+			defer func() {
+				if r.config.KeepContainers && stepFailed {
+					printKeptContainers(executionContext.workflowID, []*docker.RunningContainer{stepContainer})
+					return
+				}
+				if err := r.dockerManager.DestroyStepContainer(stepExecutionID); err != nil {
+					log.Errorf("Attempted to stop the docker container for step: %s: %s", stepIDData.IDorURI, err)
+				}
+			}()
 			if err != nil {
 				runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
-					models.StepRunStatusCodePreparationFailed, 1,
-					fmt.Errorf("failed to redact step inputs: %s", err),
+					models.StepRunStatusCodePreparationFailed, 1, fmt.Errorf("failed to start the step's docker container: %s", err),
 					isLastStep, false, map[string]string{}, stepStartedProperties)
-				continue
+				return buildRunResults, nil
 			}
+		}
 
-			for key, value := range redactedStepInputs {
-				if _, ok := redactedInputsWithType[key]; !ok {
-					redactedInputsWithType[key] = value
-				}
+		tracker.SendStepStartedEvent(stepStartedProperties, prepareAnalyticsStepInfo(mergedStep, stepInfoPtr), redactedInputsWithType, redactedOriginalInputs)
+
+		retryPolicy := stepListItm.GetRetry()
+		var attempts []models.StepRunAttemptModel
+		retryCancelled := false
+		attemptStartTime := time.Now()
+		exit, outEnvironments, err := r.runStep(stepExecutionID, mergedStep, stepIDData, stepDir, stepDeclaredEnvironments, stepSecretValues, workflow, stepContainer, executionContext)
+		for err != nil && retryPolicy != nil && len(attempts)+1 < retryPolicy.MaxAttempts && !executionContext.isAborted() {
+			attempt := len(attempts) + 1
+			if status, _, _ := failedStepRunStatus(exit, err); !retryPolicy.ShouldRetry(status, exit) {
+				break
 			}
 
-			secretKeysEnv := secretEnvKeysEnvironment(stepSecretKeys)
-			stepDeclaredEnvironments = append(stepDeclaredEnvironments, secretKeysEnv)
+			attempts = append(attempts, runResultCollector.registerStepRunAttempt(attempt, attemptStartTime, mergedStep, stepInfoPtr, exit, err, stepIDProperties))
 
-			tracker.SendStepStartedEvent(stepStartedProperties, prepareAnalyticsStepInfo(mergedStep, stepInfoPtr), redactedInputsWithType, redactedOriginalInputs)
+			// the outputs of the failed attempt are dropped
+			if err := tools.EnvmanClear(executionContext.outputEnvstorePath); err != nil {
+				log.Errorf("Failed to clear output envstore, error: %s", err)
+			}
 
-			exit, outEnvironments, err := r.runStep(stepExecutionID, mergedStep, stepIDData, stepDir, stepDeclaredEnvironments, stepSecretValues, workflow, workflowID)
+			backoff := retryPolicy.BackoffBefore(attempt + 1)
+			log.Warnf("Step (%s) failed (attempt %d/%d), retrying in %s...", stepIDData.IDorURI, attempt, retryPolicy.MaxAttempts, backoff)
+			if !r.waitForRetry(backoff, executionContext) {
+				log.Warnf("Step (%s) retry cancelled", stepIDData.IDorURI)
+				// the failed run is already recorded as the last attempt
+				retryCancelled = true
+				break
+			}
 
-			if testDirPath != "" {
-				if err := addTestMetadata(testDirPath, models.TestResultStepInfo{Number: idx, Title: *mergedStep.Title, ID: stepIDData.IDorURI, Version: stepIDData.Version}); err != nil {
-					log.Errorf("Failed to normalize test result dir, error: %s", err)
-				}
+			attemptStartTime = time.Now()
+			exit, outEnvironments, err = r.runStep(stepExecutionID, mergedStep, stepIDData, stepDir, stepDeclaredEnvironments, stepSecretValues, workflow, stepContainer, executionContext)
+		}
+		stepFailed = err != nil
+
+		if len(attempts) > 0 && !retryCancelled {
+			finalStatus := models.StepRunStatusCodeSuccess
+			if err != nil {
+				finalStatus = models.StepRunStatusCodeFailed
 			}
+			attempts = append(attempts, newStepRunAttempt(len(attempts)+1, attemptStartTime, finalStatus, exit, err))
+		}
 
-			if err := tools.EnvmanClear(configs.OutputEnvstorePath); err != nil {
-				log.Errorf("Failed to clear output envstore, error: %s", err)
+		if testDirPath != "" {
+			if err := addTestMetadata(testDirPath, models.TestResultStepInfo{Number: idx, Title: *mergedStep.Title, ID: stepIDData.IDorURI, Version: stepIDData.Version}); err != nil {
+				log.Errorf("Failed to normalize test result dir, error: %s", err)
 			}
+		}
 
-			*environments = append(*environments, outEnvironments...)
-			if err != nil {
-				if *mergedStep.IsSkippable {
-					runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
-						models.StepRunStatusCodeFailedSkippable, exit, err, isLastStep, false, redactedStepInputs, stepIDProperties)
-				} else {
-					runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
-						models.StepRunStatusCodeFailed, exit, err, isLastStep, false, redactedStepInputs, stepIDProperties)
-				}
+		if err := tools.EnvmanClear(executionContext.outputEnvstorePath); err != nil {
+			log.Errorf("Failed to clear output envstore, error: %s", err)
+		}
+
+		if err != nil {
+			if *mergedStep.IsSkippable {
+				runResultCollector.registerRetriedStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
+					models.StepRunStatusCodeFailedSkippable, exit, err, isLastStep, false, redactedStepInputs, stepIDProperties, attempts)
 			} else {
-				runResultCollector.registerStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
-					models.StepRunStatusCodeSuccess, 0, nil, isLastStep, false, redactedStepInputs, stepIDProperties)
+				runResultCollector.registerRetriedStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
+					models.StepRunStatusCodeFailed, exit, err, isLastStep, false, redactedStepInputs, stepIDProperties, attempts)
 			}
+		} else {
+			runResultCollector.registerRetriedStepRunResults(&buildRunResults, stepExecutionID, stepStartTime, mergedStep, stepInfoPtr, stepIdxPtr,
+				models.StepRunStatusCodeSuccess, 0, nil, isLastStep, false, redactedStepInputs, stepIDProperties, attempts)
 		}
+
+		return buildRunResults, outEnvironments
 	}
 
-	return buildRunResults
+	return buildRunResults, nil
+}
+
+// waitForRetry waits for the backoff before the next attempt of a step,
+// it returns false if the workflow was aborted or the build was cancelled in the meantime.
+func (r WorkflowRunner) waitForRetry(backoff time.Duration, executionContext *workflowExecutionContext) bool {
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-executionContext.abort:
+		return false
+	case <-r.buildContext.Done():
+		return false
+	}
 }
 
-func logStepStarted(stepInfo stepmanModels.StepInfoModel, step stepmanModels.StepModel, idx int, stepExcutionId string, stepStartTime time.Time) {
+func logStepStarted(logger log.Logger, stepInfo stepmanModels.StepInfoModel, step stepmanModels.StepModel, idx int, stepExcutionId string, stepStartTime time.Time) {
 	title := ""
 	if stepInfo.Step.Title != nil && *stepInfo.Step.Title != "" {
 		title = *stepInfo.Step.Title
@@ -949,7 +1225,7 @@ func logStepStarted(stepInfo stepmanModels.StepInfoModel, step stepmanModels.Ste
 		Toolkit:     toolkits.ToolkitForStep(step).ToolkitName(),
 		StartTime:   stepStartTime.Format(time.RFC3339),
 	}
-	log.PrintStepStartedEvent(params)
+	logger.PrintStepStartedEvent(params)
 }
 
 func prepareAnalyticsStepInfo(step stepmanModels.StepModel, stepInfoPtr stepmanModels.StepInfoModel) analytics.StepInfo {
@@ -969,13 +1245,18 @@ func (r WorkflowRunner) runWorkflow(
 	steplibSource string,
 	buildRunResults models.BuildRunResultsModel,
 	environments *[]envmanModels.EnvironmentItemModel, secrets []envmanModels.EnvironmentItemModel,
-	isLastWorkflow bool, tracker analytics.Tracker, buildIDProperties coreanalytics.Properties) models.BuildRunResultsModel {
+	isLastWorkflow bool, tracker analytics.Tracker, buildIDProperties coreanalytics.Properties,
+	executionContext *workflowExecutionContext) models.BuildRunResultsModel {
 
 	workflowIDProperties := coreanalytics.Properties{analytics.WorkflowExecutionID: plan.UUID}
 	bitrise.PrintRunningWorkflow(workflow.Title)
 	tracker.SendWorkflowStarted(buildIDProperties.Merge(workflowIDProperties), workflowID, workflow.Title)
-	*environments = append(*environments, workflow.Environments...)
-	results := r.activateAndRunSteps(plan, workflow, steplibSource, buildRunResults, environments, secrets, isLastWorkflow, tracker, workflowIDProperties, workflowID)
+	if executionContext.resumeFrom == nil {
+		*environments = append(*environments, copyEnvironmentItems(workflow.Environments)...)
+	}
+	results := r.activateAndRunSteps(plan, workflow, steplibSource, buildRunResults, environments, secrets, isLastWorkflow, tracker, workflowIDProperties, workflowID, executionContext)
+	// the resumed workflow is finished, the later workflows run from their first step
+	executionContext.resumeFrom = nil
 	tracker.SendWorkflowFinished(workflowIDProperties, results.IsBuildFailed())
 	collectToolVersions(tracker)
 	return results
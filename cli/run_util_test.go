@@ -2,12 +2,18 @@ package cli
 
 import (
 	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/bitrise/cli/docker"
 	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/log"
 	"github.com/bitrise-io/bitrise/models"
 	envmanModels "github.com/bitrise-io/envman/models"
 	"github.com/bitrise-io/go-utils/fileutil"
@@ -572,3 +578,504 @@ func Test_activateStepLibStep(t *testing.T) {
 		})
 	}
 }
+
+func TestRunWorkflows_RetriesFailedStep(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	markerDir := t.TempDir()
+	stepDir := t.TempDir()
+	stepYML := `
+title: Run script
+toolkit:
+  bash:
+    entry_file: step.sh
+inputs:
+- script: ""
+`
+	stepSH := `#!/bin/bash
+set -e
+eval "$script"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.yml"), []byte(stepYML), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.sh"), []byte(stepSH), 0700))
+
+	configStr := fmt.Sprintf(`
+format_version: "13"
+default_step_lib_source: "https://github.com/bitrise-io/bitrise-steplib.git"
+
+workflows:
+  primary:
+    steps:
+    - path::%[1]s:
+        title: Flaky
+        retry:
+          max_attempts: 3
+          exit_codes: [ 3 ]
+        inputs:
+        - script: |-
+            echo "run" >> %[2]s/flaky
+            [ $(wc -l < %[2]s/flaky) -ge 3 ] || exit 3
+    - path::%[1]s:
+        title: Broken
+        is_always_run: true
+        retry:
+          max_attempts: 3
+          exit_codes: [ 3 ]
+        inputs:
+        - script: |-
+            echo "run" >> %[2]s/broken
+            exit 2
+`, stepDir, markerDir)
+
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	require.NoError(t, configs.InitPaths())
+
+	buildRunResults, err := NewWorkflowRunner(RunConfig{Config: config, Workflow: "primary"}, nil).runWorkflows(noOpTracker{})
+	require.NoError(t, err)
+
+	require.Equal(t, 1, len(buildRunResults.SuccessSteps))
+	flakyResult := buildRunResults.SuccessSteps[0]
+	require.Equal(t, 3, len(flakyResult.Attempts))
+	require.Equal(t, models.StepRunStatusCodeFailed, flakyResult.Attempts[0].Status)
+	require.Equal(t, 3, flakyResult.Attempts[0].ExitCode)
+	require.Equal(t, models.StepRunStatusCodeSuccess, flakyResult.Attempts[2].Status)
+
+	// exit code 2 is not retried
+	require.Equal(t, 1, len(buildRunResults.FailedSteps))
+	require.Equal(t, 0, len(buildRunResults.FailedSteps[0].Attempts))
+	brokenRuns, err := os.ReadFile(filepath.Join(markerDir, "broken"))
+	require.NoError(t, err)
+	require.Equal(t, "run\n", string(brokenRuns))
+}
+
+func TestWaitForRetry(t *testing.T) {
+	t.Run("waits for the backoff", func(t *testing.T) {
+		runner := NewWorkflowRunner(RunConfig{}, nil)
+		executionContext := newWorkflowExecutionContext("primary", models.WorkflowRunModes{})
+
+		require.True(t, runner.waitForRetry(time.Millisecond, executionContext))
+	})
+
+	t.Run("stops waiting when the build is cancelled", func(t *testing.T) {
+		runner := NewWorkflowRunner(RunConfig{}, nil)
+		executionContext := newWorkflowExecutionContext("primary", models.WorkflowRunModes{})
+		runner.cancelBuild()
+
+		require.False(t, runner.waitForRetry(time.Hour, executionContext))
+	})
+
+	t.Run("stops waiting when the workflow is aborted", func(t *testing.T) {
+		runner := NewWorkflowRunner(RunConfig{}, nil)
+		abort := make(chan struct{})
+		executionContext := newWorkflowExecutionContext("primary", models.WorkflowRunModes{})
+		executionContext.abort = abort
+		close(abort)
+
+		require.False(t, runner.waitForRetry(time.Hour, executionContext))
+	})
+}
+
+func TestRunWorkflows_RunsParallelStepGroup(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	stepDir := t.TempDir()
+	stepYML := `
+title: Run script
+toolkit:
+  bash:
+    entry_file: step.sh
+inputs:
+- script: ""
+`
+	stepSH := `#!/bin/bash
+set -e
+eval "$script"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.yml"), []byte(stepYML), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.sh"), []byte(stepSH), 0700))
+
+	configStr := fmt.Sprintf(`
+format_version: "13"
+default_step_lib_source: "https://github.com/bitrise-io/bitrise-steplib.git"
+
+workflows:
+  primary:
+    steps:
+    - parallel:
+        steps:
+        - path::%[1]s:
+            title: Slow
+            inputs:
+            - script: |-
+                sleep 1
+                envman add --key SLOW_OUTPUT --value slow
+                touch "$BITRISE_TEST_RESULT_DIR/result.xml"
+        - path::%[1]s:
+            title: Fast
+            inputs:
+            - script: |-
+                envman add --key FAST_OUTPUT --value fast
+                touch "$BITRISE_TEST_RESULT_DIR/result.xml"
+    - path::%[1]s:
+        title: Check outputs
+        inputs:
+        - script: |-
+            [ "$SLOW_OUTPUT" = "slow" ]
+            [ "$FAST_OUTPUT" = "fast" ]
+            touch "$BITRISE_TEST_RESULT_DIR/result.xml"
+`, stepDir)
+
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	require.NoError(t, configs.InitPaths())
+	testDeployDir := t.TempDir()
+	t.Setenv(configs.BitriseTestDeployDirEnvKey, testDeployDir)
+
+	buildRunResults, err := NewWorkflowRunner(RunConfig{Config: config, Workflow: "primary"}, nil).runWorkflows(noOpTracker{})
+	require.NoError(t, err)
+
+	require.Equal(t, 0, len(buildRunResults.FailedSteps))
+	require.Equal(t, 3, len(buildRunResults.SuccessSteps))
+	// the results follow the order of the steps in the group, not the order they finished
+	for i, title := range []string{"Slow", "Fast", "Check outputs"} {
+		require.Equal(t, i, buildRunResults.SuccessSteps[i].Idx)
+		require.Equal(t, title, *buildRunResults.SuccessSteps[i].StepInfo.Step.Title)
+	}
+
+	// every step of the group has its own position, the step after the group follows them
+	stepInfoPths, err := filepath.Glob(filepath.Join(testDeployDir, "*", "step-info.json"))
+	require.NoError(t, err)
+	positions := map[string]int{}
+	for _, stepInfoPth := range stepInfoPths {
+		content, err := os.ReadFile(stepInfoPth)
+		require.NoError(t, err)
+		var stepInfo models.TestResultStepInfo
+		require.NoError(t, json.Unmarshal(content, &stepInfo))
+		positions[stepInfo.Title] = stepInfo.Number
+	}
+	require.Equal(t, map[string]int{"Slow": 0, "Fast": 1, "Check outputs": 2}, positions)
+}
+
+func TestRunWorkflows_ExpandsStepBundles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	stepDir := t.TempDir()
+	stepYML := `
+title: Run script
+toolkit:
+  bash:
+    entry_file: step.sh
+inputs:
+- script: ""
+`
+	stepSH := `#!/bin/bash
+set -e
+eval "$script"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.yml"), []byte(stepYML), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.sh"), []byte(stepSH), 0700))
+
+	configStr := fmt.Sprintf(`
+format_version: "13"
+default_step_lib_source: "https://github.com/bitrise-io/bitrise-steplib.git"
+
+step_bundles:
+  install_deps:
+    inputs:
+    - cache_key: default
+    envs:
+    - LOCKFILE: package-lock.json
+    steps:
+    - path::%[1]s:
+        title: Install
+        inputs:
+        - script: |-
+            [ "$cache_key" = "deps" ]
+            [ "$LOCKFILE" = "package-lock.json" ]
+            envman add --key INSTALLED --value "$cache_key"
+
+workflows:
+  primary:
+    steps:
+    - bundle::install_deps:
+        inputs:
+        - cache_key: deps
+    - path::%[1]s:
+        title: After bundle
+        inputs:
+        - script: |-
+            [ "$INSTALLED" = "deps" ]
+            [ -z "$cache_key" ]
+            [ -z "$LOCKFILE" ]
+`, stepDir)
+
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	require.NoError(t, configs.InitPaths())
+
+	buildRunResults, err := NewWorkflowRunner(RunConfig{Config: config, Workflow: "primary"}, nil).runWorkflows(noOpTracker{})
+	require.NoError(t, err)
+
+	require.Equal(t, 0, len(buildRunResults.FailedSteps))
+	require.Equal(t, 2, len(buildRunResults.SuccessSteps))
+	require.Equal(t, "Install", *buildRunResults.SuccessSteps[0].StepInfo.Step.Title)
+	require.Equal(t, "After bundle", *buildRunResults.SuccessSteps[1].StepInfo.Step.Title)
+}
+
+// fakeDockerManager records the step containers, the containers are not started.
+type fakeDockerManager struct {
+	DockerManager
+
+	startedStepContainers   []string
+	destroyedStepContainers []string
+	servicesErr             error
+}
+
+func (m *fakeDockerManager) Login(models.Container, map[string]string) error {
+	return nil
+}
+
+func (m *fakeDockerManager) StartServiceContainers(services map[string]models.Container, _ string, _ map[string]string) ([]*docker.RunningContainer, error) {
+	if len(services) == 0 {
+		return nil, nil
+	}
+	return nil, m.servicesErr
+}
+
+func (m *fakeDockerManager) SaveServiceLogs(string, map[string]string) error {
+	return nil
+}
+
+func (m *fakeDockerManager) StartComposeServices(string, string, map[string]string) ([]*docker.RunningContainer, error) {
+	return nil, nil
+}
+
+func (m *fakeDockerManager) StopComposeServices(string) error {
+	return nil
+}
+
+func (m *fakeDockerManager) RemoveWorkflowNetwork(string) error {
+	return nil
+}
+
+func (m *fakeDockerManager) RemoveRegistryConfig() error {
+	return nil
+}
+
+func (m *fakeDockerManager) StartStepContainer(container models.Container, _, stepExecutionID string, _ map[string]string) (*docker.RunningContainer, error) {
+	m.startedStepContainers = append(m.startedStepContainers, container.Image)
+	runtime, err := docker.NewRuntime(docker.RuntimeDocker, docker.NewDockerLogger(log.NewLogger(log.GetGlobalLoggerOpts()), nil))
+	if err != nil {
+		return nil, err
+	}
+	return &docker.RunningContainer{Name: "bitrise-step-" + stepExecutionID, Image: container.Image, Runtime: runtime}, nil
+}
+
+func (m *fakeDockerManager) DestroyStepContainer(stepExecutionID string) error {
+	m.destroyedStepContainers = append(m.destroyedStepContainers, stepExecutionID)
+	return nil
+}
+
+func TestRunWorkflows_RunsStepInItsOwnContainer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// the fake docker CLI runs the command of docker exec on the host
+	dockerDir := t.TempDir()
+	dockerSH := `#!/bin/bash
+[ "$1" = "exec" ] || exit 1
+shift
+while [ "$1" = "-e" ]; do shift 2; done
+echo "$1" >> "$(dirname "$0")/containers"
+shift
+exec "$@"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dockerDir, "docker"), []byte(dockerSH), 0700))
+	t.Setenv("PATH", dockerDir+":"+os.Getenv("PATH"))
+
+	stepDir := t.TempDir()
+	stepYML := `
+title: Run script
+toolkit:
+  bash:
+    entry_file: step.sh
+inputs:
+- script: ""
+`
+	stepSH := `#!/bin/bash
+set -e
+eval "$script"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.yml"), []byte(stepYML), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.sh"), []byte(stepSH), 0700))
+
+	configStr := fmt.Sprintf(`
+format_version: "13"
+default_step_lib_source: "https://github.com/bitrise-io/bitrise-steplib.git"
+
+workflows:
+  primary:
+    steps:
+    - path::%[1]s:
+        title: In container
+        container:
+          image: node:20
+        inputs:
+        - script: envman add --key CONTAINER_OUTPUT --value node
+    - path::%[1]s:
+        title: On host
+        inputs:
+        - script: '[ "$CONTAINER_OUTPUT" = "node" ]'
+`, stepDir)
+
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	require.NoError(t, configs.InitPaths())
+
+	dockerManager := &fakeDockerManager{}
+	runner := NewWorkflowRunner(RunConfig{Config: config, Workflow: "primary"}, nil)
+	runner.dockerManager = dockerManager
+	buildRunResults, err := runner.runWorkflows(noOpTracker{})
+	require.NoError(t, err)
+
+	require.Equal(t, 0, len(buildRunResults.FailedSteps))
+	require.Equal(t, 2, len(buildRunResults.SuccessSteps))
+	require.Equal(t, []string{"node:20"}, dockerManager.startedStepContainers)
+	require.Equal(t, 1, len(dockerManager.destroyedStepContainers))
+
+	// only the first step was run with docker exec
+	containers, err := os.ReadFile(filepath.Join(dockerDir, "containers"))
+	require.NoError(t, err)
+	require.Equal(t, "bitrise-step-"+dockerManager.destroyedStepContainers[0]+"\n", string(containers))
+}
+
+func TestRunWorkflows_DoesNotRunStepsIfServicesAreNotReady(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	stepDir := t.TempDir()
+	stepYML := `
+title: Run script
+toolkit:
+  bash:
+    entry_file: step.sh
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.yml"), []byte(stepYML), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.sh"), []byte("#!/bin/bash\nexit 0\n"), 0700))
+
+	configStr := fmt.Sprintf(`
+format_version: "13"
+default_step_lib_source: "https://github.com/bitrise-io/bitrise-steplib.git"
+
+workflows:
+  primary:
+    after_run:
+    - cleanup
+    services:
+      postgres:
+        image: postgres:16
+        readiness:
+          command: pg_isready
+    steps:
+    - path::%[1]s: {}
+    - path::%[1]s: {}
+
+  cleanup:
+    steps:
+    - path::%[1]s:
+        is_always_run: true
+`, stepDir)
+
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	require.NoError(t, configs.InitPaths())
+
+	dockerManager := &fakeDockerManager{servicesErr: fmt.Errorf("service readiness: service (postgres) is not ready after 1m0s")}
+	runner := NewWorkflowRunner(RunConfig{Config: config, Workflow: "primary"}, nil)
+	runner.dockerManager = dockerManager
+	buildRunResults, err := runner.runWorkflows(noOpTracker{})
+	require.NoError(t, err)
+
+	// the after_run workflow has no services, its steps are run
+	require.Equal(t, 1, len(buildRunResults.SuccessSteps))
+	require.Equal(t, 1, len(buildRunResults.FailedSteps))
+	require.Equal(t, models.StepRunStatusCodePreparationFailed, buildRunResults.FailedSteps[0].Status)
+	require.Equal(t, "Preparing Step (Run script) failed: the services of the workflow are not ready: service readiness: service (postgres) is not ready after 1m0s", buildRunResults.FailedSteps[0].ErrorStr)
+	require.Equal(t, 1, len(buildRunResults.SkippedSteps))
+}
+
+func TestRunWorkflows_KeepsContainerOfFailedStep(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	dockerDir := t.TempDir()
+	dockerSH := `#!/bin/bash
+[ "$1" = "exec" ] || exit 1
+shift
+while [ "$1" = "-e" ]; do shift 2; done
+shift
+exec "$@"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dockerDir, "docker"), []byte(dockerSH), 0700))
+	t.Setenv("PATH", dockerDir+":"+os.Getenv("PATH"))
+
+	stepDir := t.TempDir()
+	stepYML := `
+title: Run script
+toolkit:
+  bash:
+    entry_file: step.sh
+inputs:
+- script: ""
+`
+	stepSH := `#!/bin/bash
+set -e
+eval "$script"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.yml"), []byte(stepYML), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.sh"), []byte(stepSH), 0700))
+
+	configStr := fmt.Sprintf(`
+format_version: "13"
+default_step_lib_source: "https://github.com/bitrise-io/bitrise-steplib.git"
+
+workflows:
+  primary:
+    steps:
+    - path::%[1]s:
+        title: Failing in container
+        container:
+          image: node:20
+        inputs:
+        - script: exit 1
+`, stepDir)
+
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	require.NoError(t, configs.InitPaths())
+
+	dockerManager := &fakeDockerManager{}
+	runner := NewWorkflowRunner(RunConfig{Config: config, Workflow: "primary", KeepContainers: true}, nil)
+	runner.dockerManager = dockerManager
+	buildRunResults, err := runner.runWorkflows(noOpTracker{})
+	require.NoError(t, err)
+
+	require.Equal(t, 1, len(buildRunResults.FailedSteps))
+	require.Equal(t, []string{"node:20"}, dockerManager.startedStepContainers)
+	require.Equal(t, 0, len(dockerManager.destroyedStepContainers))
+
+	session, err := loadContainerSession("primary")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(session.ContainerName, "bitrise-step-"))
+	require.Contains(t, session.Envs, "script=exit 1")
+}
@@ -112,3 +112,46 @@ func getSensitiveEnvs(envList []envmanModels.EnvironmentItemModel, expandedStepE
 
 	return sensitiveValues, nil
 }
+
+// copyEnvironmentItems returns a deep copy of the env items. The items are normalized (their maps are written) while a step
+// is prepared, so the workflows and steps running concurrently need their own copies of the shared items.
+func copyEnvironmentItems(items []envmanModels.EnvironmentItemModel) []envmanModels.EnvironmentItemModel {
+	if items == nil {
+		return nil
+	}
+
+	itemsCopy := make([]envmanModels.EnvironmentItemModel, len(items))
+	for i, item := range items {
+		itemCopy := envmanModels.EnvironmentItemModel{}
+		for key, value := range item {
+			if key == envmanModels.OptionsKey {
+				value = copyEnvironmentItemOptions(value)
+			}
+			itemCopy[key] = value
+		}
+		itemsCopy[i] = itemCopy
+	}
+	return itemsCopy
+}
+
+func copyEnvironmentItemOptions(options interface{}) interface{} {
+	switch options := options.(type) {
+	case envmanModels.EnvironmentItemOptionsModel:
+		// the options struct is copied by value, its fields are replaced (not written through) when the defaults are filled
+		return options
+	case map[string]interface{}:
+		optionsCopy := map[string]interface{}{}
+		for key, value := range options {
+			optionsCopy[key] = value
+		}
+		return optionsCopy
+	case map[interface{}]interface{}:
+		optionsCopy := map[interface{}]interface{}{}
+		for key, value := range options {
+			optionsCopy[key] = value
+		}
+		return optionsCopy
+	default:
+		return options
+	}
+}
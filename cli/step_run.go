@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/bitrise/analytics"
+	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/log"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/bitrise-io/bitrise/tools"
+	"github.com/bitrise-io/bitrise/version"
+	envmanModels "github.com/bitrise-io/envman/models"
+	coreanalytics "github.com/bitrise-io/go-utils/v2/analytics"
+	"github.com/bitrise-io/go-utils/v2/redactwriter"
+	stepmanModels "github.com/bitrise-io/stepman/models"
+	"github.com/urfave/cli"
+)
+
+const (
+	// StepInputKey ...
+	StepInputKey = "input"
+
+	stepRunWorkflowID = "step-run"
+)
+
+var stepCommand = cli.Command{
+	Name:  "step",
+	Usage: "Step related commands.",
+	Subcommands: []cli.Command{
+		{
+			Name:      "run",
+			Usage:     "Runs a single step outside of a workflow and prints its outputs.",
+			ArgsUsage: "<step-id@version>",
+			Action: func(c *cli.Context) error {
+				if err := stepRun(c); err != nil {
+					failf("Step run failed: %s", err)
+				}
+				return nil
+			},
+			Flags: []cli.Flag{
+				flCollection,
+				flConfig,
+				flConfigBase64,
+				flInventory,
+				cli.StringSliceFlag{
+					Name:  StepInputKey,
+					Usage: "Step input in key=value format, can be specified multiple times.",
+				},
+			},
+		},
+	},
+}
+
+func stepRun(c *cli.Context) error {
+	if len(c.Args()) < 1 {
+		return fmt.Errorf("no step specified")
+	}
+	stepID := c.Args()[0]
+
+	inputs, err := parseStepRunInputs(c.StringSlice(StepInputKey))
+	if err != nil {
+		return err
+	}
+
+	// the step is resolved from the default step lib of the bitrise config, unless the collection is given
+	collectionURI := c.String(CollectionKey)
+	if collectionURI == "" {
+		bitriseConfig, warnings, err := CreateBitriseConfigFromCLIParams(c.String(ConfigBase64Key), c.String(ConfigKey))
+		for _, warning := range warnings {
+			log.Warnf("warning: %s", warning)
+		}
+		if err != nil {
+			return fmt.Errorf("no collection defined and failed to read bitrise config: %s", err)
+		}
+		if bitriseConfig.DefaultStepLibSource == "" {
+			return fmt.Errorf("no collection defined and no default collection found in bitrise config")
+		}
+		collectionURI = bitriseConfig.DefaultStepLibSource
+	}
+
+	secrets, err := CreateInventoryFromCLIParams("", c.String(InventoryKey))
+	if err != nil {
+		return fmt.Errorf("failed to create inventory: %s", err)
+	}
+
+	if err := bitrise.RunSetupIfNeeded(version.VERSION, false); err != nil {
+		return fmt.Errorf("setup failed: %s", err)
+	}
+
+	tracker := analytics.NewDefaultTracker()
+	defer tracker.Wait()
+
+	buildRunResults, outputs, err := runSingleStep(stepID, inputs, collectionURI, secrets, tracker)
+	if err != nil {
+		return err
+	}
+
+	printStepRunOutputs(outputs, secrets)
+
+	if buildRunResults.IsBuildFailed() {
+		os.Exit(buildRunResults.ExitCode())
+	}
+	return nil
+}
+
+func parseStepRunInputs(inputArgs []string) ([]envmanModels.EnvironmentItemModel, error) {
+	var inputs []envmanModels.EnvironmentItemModel
+	for _, inputArg := range inputArgs {
+		split := strings.SplitN(inputArg, "=", 2)
+		if len(split) != 2 || split[0] == "" {
+			return nil, fmt.Errorf("invalid input (%s), should be in key=value format", inputArg)
+		}
+		inputs = append(inputs, envmanModels.EnvironmentItemModel{split[0]: split[1]})
+	}
+	return inputs, nil
+}
+
+// runSingleStep runs the step as the only step of an in-memory workflow,
+// so that it goes through the same activation, toolkit preparation, environment preparation,
+// secret filtering and output collection as the steps of a real workflow.
+// It returns the outputs produced by the step.
+func runSingleStep(stepID string, inputs []envmanModels.EnvironmentItemModel, collectionURI string, secrets []envmanModels.EnvironmentItemModel, tracker analytics.Tracker) (models.BuildRunResultsModel, []envmanModels.EnvironmentItemModel, error) {
+	config := models.BitriseDataModel{
+		FormatVersion:        models.FormatVersion,
+		DefaultStepLibSource: collectionURI,
+		Workflows: map[string]models.WorkflowModel{
+			stepRunWorkflowID: {
+				Title: stepID,
+				Steps: []models.StepListItemModel{
+					{stepID: models.WorkflowStepModel{StepModel: stepmanModels.StepModel{Inputs: inputs}}},
+				},
+			},
+		},
+	}
+	if err := config.Normalize(); err != nil {
+		return models.BuildRunResultsModel{}, nil, fmt.Errorf("failed to normalize step: %s", err)
+	}
+	if _, err := config.Validate(); err != nil {
+		return models.BuildRunResultsModel{}, nil, fmt.Errorf("invalid step: %s", err)
+	}
+
+	secretFiltering, err := isSecretFiltering(nil, secrets)
+	if err != nil {
+		return models.BuildRunResultsModel{}, nil, fmt.Errorf("failed to check Secret Filtering mode: %s", err)
+	}
+	secretEnvsFiltering, err := isSecretEnvsFiltering(nil, secrets)
+	if err != nil {
+		return models.BuildRunResultsModel{}, nil, fmt.Errorf("failed to check Secret Envs Filtering mode: %s", err)
+	}
+	isCI, err := isCIMode(nil, secrets)
+	if err != nil {
+		return models.BuildRunResultsModel{}, nil, fmt.Errorf("failed to check CI mode: %s", err)
+	}
+	isPR, err := isPRMode(nil, secrets)
+	if err != nil {
+		return models.BuildRunResultsModel{}, nil, fmt.Errorf("failed to check PR mode: %s", err)
+	}
+
+	runner := NewWorkflowRunner(RunConfig{
+		Modes: models.WorkflowRunModes{
+			CIMode:                  isCI,
+			PRMode:                  isPR,
+			DebugMode:               configs.IsDebugMode,
+			SecretFilteringMode:     secretFiltering,
+			SecretEnvsFilteringMode: secretEnvsFiltering,
+		},
+		Config:   config,
+		Workflow: stepRunWorkflowID,
+		Secrets:  secrets,
+	}, nil)
+
+	if err := runner.prepareBuild(); err != nil {
+		return models.BuildRunResultsModel{}, nil, err
+	}
+	defer runner.removeRegistryConfig()
+
+	buildIDProperties := coreanalytics.Properties{analytics.BuildExecutionID: runner.buildExecutionID}
+	executionContext := newWorkflowExecutionContext(stepRunWorkflowID, runner.config.Modes)
+	buildRunResults, err := runner.runWorkflowWithBeforeAndAfterRuns(stepRunWorkflowID, time.Now(), tracker, buildIDProperties, executionContext)
+	if err != nil {
+		return models.BuildRunResultsModel{}, nil, err
+	}
+
+	bitrise.PrintSummary(buildRunResults)
+
+	return buildRunResults, executionContext.stepOutputs, nil
+}
+
+// printStepRunOutputs prints the outputs of the step, sensitive outputs and secret values are redacted.
+func printStepRunOutputs(outputs []envmanModels.EnvironmentItemModel, secrets []envmanModels.EnvironmentItemModel) {
+	_, secretValues := tools.GetSecretKeysAndValues(secrets)
+
+	log.Print()
+	if len(outputs) == 0 {
+		log.Print("The step did not produce any output")
+		return
+	}
+
+	log.Print("Step outputs:")
+	for _, output := range outputs {
+		key, value, err := output.GetKeyValuePair()
+		if err != nil {
+			log.Warnf("Invalid step output: %s", err)
+			continue
+		}
+
+		opts, err := output.GetOptions()
+		if err == nil && opts.IsSensitive != nil && *opts.IsSensitive {
+			value = redactwriter.RedactStr
+		} else if redacted, err := redactWithSecrets(value, secretValues); err == nil {
+			value = redacted
+		}
+
+		log.Printf("%s: %s", key, value)
+	}
+}
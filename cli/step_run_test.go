@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/configs"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStepRunInputs(t *testing.T) {
+	inputs, err := parseStepRunInputs([]string{"content=echo a=b", "empty="})
+	require.NoError(t, err)
+	require.Equal(t, []envmanModels.EnvironmentItemModel{{"content": "echo a=b"}, {"empty": ""}}, inputs)
+
+	_, err = parseStepRunInputs([]string{"=value"})
+	require.EqualError(t, err, "invalid input (=value), should be in key=value format")
+}
+
+func TestRunSingleStep(t *testing.T) {
+	stepDir := t.TempDir()
+	stepYML := `
+title: Greeter
+toolkit:
+  bash:
+    entry_file: step.sh
+inputs:
+- name: world
+outputs:
+- GREETING:
+`
+	// the envman binary is not required for the test: the step writes the output envstore directly
+	stepSH := `#!/bin/bash
+set -e
+printf "envs:\n- GREETING: Hello %s\n" "$name" > "$ENVMAN_ENVSTORE_PATH"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.yml"), []byte(stepYML), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(stepDir, "step.sh"), []byte(stepSH), 0700))
+
+	require.NoError(t, configs.InitPaths())
+
+	buildRunResults, outputs, err := runSingleStep(fmt.Sprintf("path::%s", stepDir), []envmanModels.EnvironmentItemModel{{"name": "bitrise"}}, "https://github.com/bitrise-io/bitrise-steplib.git", nil, noOpTracker{})
+	require.NoError(t, err)
+	require.False(t, buildRunResults.IsBuildFailed())
+	require.Equal(t, 1, len(buildRunResults.SuccessSteps))
+
+	require.Equal(t, 1, len(outputs))
+	key, value, err := outputs[0].GetKeyValuePair()
+	require.NoError(t, err)
+	require.Equal(t, "GREETING", key)
+	require.Equal(t, "Hello bitrise", value)
+}
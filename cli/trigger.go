@@ -9,6 +9,7 @@ import (
 	"github.com/bitrise-io/bitrise/configs"
 	"github.com/bitrise-io/bitrise/log"
 	"github.com/bitrise-io/bitrise/models"
+	envmanModels "github.com/bitrise-io/envman/models"
 	"github.com/bitrise-io/go-utils/pointers"
 	"github.com/urfave/cli"
 )
@@ -16,7 +17,7 @@ import (
 var triggerCommand = cli.Command{
 	Name:    "trigger",
 	Aliases: []string{"t"},
-	Usage:   "Triggers a specified Workflow.",
+	Usage:   "Triggers a specified Workflow or Pipeline.",
 	Action:  trigger,
 	Flags: []cli.Flag{
 		// cli params
@@ -48,23 +49,56 @@ func printAvailableTriggerFilters(triggerMap []models.TriggerMapItemModel) {
 		if triggerItem.Pattern != "" {
 			log.Infof(" * pattern: %s", triggerItem.Pattern)
 			log.Infof("   is_pull_request_allowed: %v", triggerItem.IsPullRequestAllowed)
-			log.Infof("   workflow: %s", triggerItem.WorkflowID)
+			printTriggerTarget(triggerItem)
 		} else {
 			if triggerItem.PushBranch != "" {
 				log.Infof(" * push_branch: %s", triggerItem.PushBranch)
-				log.Infof("   workflow: %s", triggerItem.WorkflowID)
+				printTriggerTarget(triggerItem)
 			} else if triggerItem.PullRequestSourceBranch != "" || triggerItem.PullRequestTargetBranch != "" {
 				log.Infof(" * pull_request_source_branch: %s", triggerItem.PullRequestSourceBranch)
 				log.Infof("   pull_request_target_branch: %s", triggerItem.PullRequestTargetBranch)
-				log.Infof("   workflow: %s", triggerItem.WorkflowID)
+				printTriggerTarget(triggerItem)
 			} else if triggerItem.Tag != "" {
 				log.Infof(" * tag: %s", triggerItem.Tag)
-				log.Infof("   workflow: %s", triggerItem.WorkflowID)
+				printTriggerTarget(triggerItem)
 			}
 		}
 	}
 }
 
+func printTriggerTarget(triggerItem models.TriggerMapItemModel) {
+	if triggerItem.PipelineID != "" {
+		log.Infof("   pipeline: %s", triggerItem.PipelineID)
+	} else {
+		log.Infof("   workflow: %s", triggerItem.WorkflowID)
+	}
+}
+
+// triggerRunConfig returns the run config of the first trigger map item matching the trigger params,
+// which runs either the item's pipeline or its workflow.
+func triggerRunConfig(
+	bitriseConfig models.BitriseDataModel,
+	triggerParams RunAndTriggerParamsModel,
+	modes models.WorkflowRunModes,
+	secrets []envmanModels.EnvironmentItemModel,
+) (RunConfig, error) {
+	pipelineToRunID, workflowToRunID, err := getPipelineAndWorkflowIDByParamsInCompatibleMode(bitriseConfig.TriggerMap, triggerParams, modes.PRMode)
+	if err != nil {
+		return RunConfig{}, err
+	}
+	if pipelineToRunID != "" && workflowToRunID != "" {
+		return RunConfig{}, workflowAndPipelineSpecifiedErr
+	}
+
+	return RunConfig{
+		Modes:    modes,
+		Config:   bitriseConfig,
+		Workflow: workflowToRunID,
+		Pipeline: pipelineToRunID,
+		Secrets:  secrets,
+	}, nil
+}
+
 func trigger(c *cli.Context) error {
 	// Expand cli.Context
 	var prGlobalFlagPtr *bool
@@ -168,28 +202,22 @@ func trigger(c *cli.Context) error {
 		failf("Failed to check  CI mode, error: %s", err)
 	}
 
-	_, workflowToRunID, err := getPipelineAndWorkflowIDByParamsInCompatibleMode(bitriseConfig.TriggerMap, triggerParams, isPRMode)
+	modes := models.WorkflowRunModes{
+		CIMode:                  isCIMode,
+		PRMode:                  isPRMode,
+		DebugMode:               configs.IsDebugMode,
+		SecretFilteringMode:     isSecretFilteringMode,
+		SecretEnvsFilteringMode: isSecretEnvsFilteringMode,
+		NoOutputTimeout:         0,
+	}
+	runConfig, err := triggerRunConfig(bitriseConfig, triggerParams, modes, inventoryEnvironments)
 	if err != nil {
-		log.Errorf("Failed to get workflow id by pattern, error: %s", err)
-		if strings.Contains(err.Error(), "no matching workflow found with trigger params:") {
+		log.Errorf("Failed to get pipeline or workflow id by pattern, error: %s", err)
+		if strings.Contains(err.Error(), "no matching pipeline & workflow found with trigger params:") {
 			printAvailableTriggerFilters(bitriseConfig.TriggerMap)
 		}
 		os.Exit(1)
 	}
-
-	runConfig := RunConfig{
-		Modes: models.WorkflowRunModes{
-			CIMode:                  isCIMode,
-			PRMode:                  isPRMode,
-			DebugMode:               configs.IsDebugMode,
-			SecretFilteringMode:     isSecretFilteringMode,
-			SecretEnvsFilteringMode: isSecretEnvsFilteringMode,
-			NoOutputTimeout:         0,
-		},
-		Config:   bitriseConfig,
-		Workflow: workflowToRunID,
-		Secrets:  inventoryEnvironments,
-	}
 	agentConfig, err := setupAgentConfig()
 	if err != nil {
 		failf("Failed to process agent config: %w", err)
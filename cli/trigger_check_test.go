@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/bitrise/models"
 	"github.com/stretchr/testify/require"
 )
 
@@ -475,6 +476,64 @@ workflows:
 	}
 }
 
+func TestTriggerRunConfig(t *testing.T) {
+	configStr := `format_version: 11
+
+trigger_map:
+- push_branch: release
+  pipeline: release
+- push_branch: "*"
+  workflow: test
+
+pipelines:
+  release:
+    stages:
+    - test: {}
+stages:
+  test:
+    workflows:
+    - test: {}
+workflows:
+  test:
+`
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	modes := models.WorkflowRunModes{CIMode: true}
+
+	t.Log("pipeline target")
+	{
+		runConfig, err := triggerRunConfig(config, RunAndTriggerParamsModel{PushBranch: "release"}, modes, nil)
+		require.NoError(t, err)
+		require.Equal(t, "release", runConfig.Pipeline)
+		require.Equal(t, "", runConfig.Workflow)
+		require.Equal(t, modes, runConfig.Modes)
+	}
+
+	t.Log("workflow target")
+	{
+		runConfig, err := triggerRunConfig(config, RunAndTriggerParamsModel{TriggerPattern: "feature"}, modes, nil)
+		require.NoError(t, err)
+		require.Equal(t, "", runConfig.Pipeline)
+		require.Equal(t, "test", runConfig.Workflow)
+	}
+
+	t.Log("both pipeline and workflow targeted")
+	{
+		config.TriggerMap = models.TriggerMapModel{{PushBranch: "*", PipelineID: "release", WorkflowID: "test"}}
+		_, err := triggerRunConfig(config, RunAndTriggerParamsModel{PushBranch: "master"}, modes, nil)
+		require.Equal(t, workflowAndPipelineSpecifiedErr, err)
+	}
+
+	t.Log("no matching target")
+	{
+		config.TriggerMap = models.TriggerMapModel{{PushBranch: "release", PipelineID: "release"}}
+		_, err := triggerRunConfig(config, RunAndTriggerParamsModel{PushBranch: "master"}, modes, nil)
+		require.EqualError(t, err, "no matching pipeline & workflow found with trigger params: push-branch: master, pr-source-branch: , pr-target-branch: , tag: ")
+	}
+}
+
 func TestGetPipelineAndWorkflowIDByParamsInCompatibleMode_migration_test(t *testing.T) {
 	t.Log("deprecated code push trigger item")
 	{
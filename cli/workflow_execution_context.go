@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bitrise-io/bitrise/cli/docker"
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/log"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/bitrise-io/bitrise/tools"
+	envmanModels "github.com/bitrise-io/envman/models"
+)
+
+// workflowExecutionContext holds the state owned by a single workflow run (together with its before and after run workflows):
+// the envstores, the step work dirs, the test result dir, the run modes and the docker container handles.
+// Workflows running with separate execution contexts don't share mutable state, so they can run concurrently.
+type workflowExecutionContext struct {
+	workflowID string
+	modes      models.WorkflowRunModes
+
+	inputEnvstorePath   string
+	outputEnvstorePath  string
+	formattedOutputPath string
+	workDirPath         string
+	stepsDirPath        string
+	testDeployDirPath   string
+
+	// inheritedEnvironments are the envs exported by the previous workflows of the pipeline run
+	inheritedEnvironments []envmanModels.EnvironmentItemModel
+
+	// runningWorkflowID is the workflow of the running steps (the workflow or one of its before and after run workflows),
+	// the step containers join the docker network of this workflow
+	runningWorkflowID string
+	workflowContainer *docker.RunningContainer
+	serviceContainers []*docker.RunningContainer
+	// servicesErr is set if a service failed to start or to become ready, the steps are not run in this case
+	servicesErr error
+
+	// abort is closed when the remaining steps of the workflow should not run (e.g. a workflow failed in an abort_on_fail stage)
+	abort   <-chan struct{}
+	aborted bool
+
+	// isolated is true if the execution context owns its work dir, which is removed when the workflow finishes
+	isolated bool
+
+	// checkpoints saves the state of the run before each step,
+	// it is nil if the checkpoints are not enabled or the run can't be resumed (e.g. pipeline runs)
+	checkpoints *runCheckpointStore
+	// resumeFrom is the checkpoint of the step the run continues from, it is nil once the resumed workflow finished
+	resumeFrom *stepCheckpointModel
+	// workflowIdx is the index of the running workflow in the before_run, workflow, after_run chain
+	workflowIdx int
+
+	// stepOutputs collects the outputs of the steps run in the execution context
+	stepOutputs []envmanModels.EnvironmentItemModel
+
+	// logPrefix is added to the log lines of the steps, it is set for the steps of a parallel step group
+	logPrefix string
+}
+
+// newWorkflowExecutionContext returns an execution context which uses the build wide work dir and envstores,
+// it is used when a single workflow runs in the bitrise process.
+func newWorkflowExecutionContext(workflowID string, modes models.WorkflowRunModes) *workflowExecutionContext {
+	return &workflowExecutionContext{
+		workflowID:          workflowID,
+		modes:               modes,
+		inputEnvstorePath:   configs.InputEnvstorePath,
+		outputEnvstorePath:  configs.OutputEnvstorePath,
+		formattedOutputPath: configs.FormattedOutputPath,
+		workDirPath:         configs.BitriseWorkDirPath,
+		stepsDirPath:        configs.BitriseWorkStepsDirPath,
+		testDeployDirPath:   os.Getenv(configs.BitriseTestDeployDirEnvKey),
+	}
+}
+
+// newIsolatedWorkflowExecutionContext creates a dedicated work dir and envstores for the workflow,
+// it is used when multiple workflows (e.g. the workflows of a pipeline stage) run concurrently.
+func newIsolatedWorkflowExecutionContext(workflowID string, modes models.WorkflowRunModes, abort <-chan struct{}) (*workflowExecutionContext, error) {
+	workDirPath, err := os.MkdirTemp(configs.BitriseWorkDirPath, fmt.Sprintf("workflow-%s-", workflowID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work dir: %s", err)
+	}
+
+	stepsDirPath := filepath.Join(workDirPath, "step_src")
+	if err := os.MkdirAll(stepsDirPath, 0755); err != nil {
+		removeWorkDir(workDirPath)
+		return nil, fmt.Errorf("failed to create step source dir: %s", err)
+	}
+
+	executionContext := &workflowExecutionContext{
+		workflowID:          workflowID,
+		modes:               modes,
+		inputEnvstorePath:   filepath.Join(workDirPath, "input_envstore.yml"),
+		outputEnvstorePath:  filepath.Join(workDirPath, "output_envstore.yml"),
+		formattedOutputPath: filepath.Join(workDirPath, "formatted_output.md"),
+		workDirPath:         workDirPath,
+		stepsDirPath:        stepsDirPath,
+		testDeployDirPath:   os.Getenv(configs.BitriseTestDeployDirEnvKey),
+		abort:               abort,
+		isolated:            true,
+	}
+
+	if err := tools.EnvmanInit(executionContext.outputEnvstorePath, false); err != nil {
+		removeWorkDir(workDirPath)
+		return nil, fmt.Errorf("failed to run envman init: %s", err)
+	}
+
+	return executionContext, nil
+}
+
+// newParallelStepExecutionContext creates a dedicated work dir and envstores for a step of a parallel step group,
+// the run modes, the docker containers and the abort signal are shared with the workflow's execution context.
+func (c *workflowExecutionContext) newParallelStepExecutionContext(logPrefix string) (*workflowExecutionContext, error) {
+	workDirPath, err := os.MkdirTemp(c.workDirPath, "parallel-step-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work dir: %s", err)
+	}
+
+	stepsDirPath := filepath.Join(workDirPath, "step_src")
+	if err := os.MkdirAll(stepsDirPath, 0755); err != nil {
+		removeWorkDir(workDirPath)
+		return nil, fmt.Errorf("failed to create step source dir: %s", err)
+	}
+
+	executionContext := &workflowExecutionContext{
+		workflowID:          c.workflowID,
+		modes:               c.modes,
+		inputEnvstorePath:   filepath.Join(workDirPath, "input_envstore.yml"),
+		outputEnvstorePath:  filepath.Join(workDirPath, "output_envstore.yml"),
+		formattedOutputPath: filepath.Join(workDirPath, "formatted_output.md"),
+		workDirPath:         workDirPath,
+		stepsDirPath:        stepsDirPath,
+		testDeployDirPath:   c.testDeployDirPath,
+		runningWorkflowID:   c.runningWorkflowID,
+		workflowContainer:   c.workflowContainer,
+		serviceContainers:   c.serviceContainers,
+		servicesErr:         c.servicesErr,
+		abort:               c.abort,
+		isolated:            true,
+		logPrefix:           logPrefix,
+	}
+
+	if err := tools.EnvmanInit(executionContext.outputEnvstorePath, false); err != nil {
+		removeWorkDir(workDirPath)
+		return nil, fmt.Errorf("failed to run envman init: %s", err)
+	}
+
+	return executionContext, nil
+}
+
+// stepEnvironments returns the envs pointing the step to the envstores of the execution context,
+// these override the values set for the whole bitrise process.
+func (c *workflowExecutionContext) stepEnvironments(isBuildFailed bool) []envmanModels.EnvironmentItemModel {
+	buildStatus := "0"
+	if isBuildFailed {
+		buildStatus = "1"
+	}
+
+	return []envmanModels.EnvironmentItemModel{
+		{configs.EnvstorePathEnvKey: c.outputEnvstorePath},
+		{configs.FormattedOutputPathEnvKey: c.formattedOutputPath},
+		{"STEPLIB_BUILD_STATUS": buildStatus},
+		{"BITRISE_BUILD_STATUS": buildStatus},
+	}
+}
+
+func (c *workflowExecutionContext) isAborted() bool {
+	select {
+	case <-c.abort:
+		return true
+	default:
+		return false
+	}
+}
+
+// cleanup removes the work dir of an isolated execution context.
+func (c *workflowExecutionContext) cleanup() error {
+	if !c.isolated {
+		return nil
+	}
+	return os.RemoveAll(c.workDirPath)
+}
+
+// removeWorkDir removes the dedicated work dir of an execution context which could not be set up.
+func removeWorkDir(workDirPath string) {
+	if err := os.RemoveAll(workDirPath); err != nil {
+		log.Warnf("Failed to remove work dir (%s): %s", workDirPath, err)
+	}
+}
+
+// logger returns the logger of the step headers and footers, the lines are prefixed for the steps of a parallel step group.
+func (c *workflowExecutionContext) logger() log.Logger {
+	logger := log.NewLogger(log.GetGlobalLoggerOpts())
+	if c.logPrefix != "" {
+		return log.NewPrefixedLogger(logger, c.logPrefix)
+	}
+	return logger
+}
@@ -17,6 +17,10 @@ const defaultTestDeployDir = "$BITRISE_APP_SLUG/$BITRISE_BUILD_SLUG/test_results
 type AgentConfig struct {
 	BitriseDirs BitriseDirs `yaml:"bitrise_dirs"`
 	Hooks       AgentHooks  `yaml:"hooks"`
+
+	// ContainerRuntime is the container engine running the workflow, step and service containers (docker or podman).
+	// The BITRISE_CONTAINER_RUNTIME env overrides it.
+	ContainerRuntime string `yaml:"container_runtime"`
 }
 
 type BitriseDirs struct {
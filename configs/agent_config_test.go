@@ -38,6 +38,7 @@ func TestReadAgentConfig(t *testing.T) {
 					DoOnBuildStart:      filepath.Join(tempDir, "cleanup.sh"),
 					DoOnBuildEnd:        filepath.Join(tempDir, "cleanup.sh"),
 				},
+				"podman",
 			},
 			expectedErr: false,
 		},
@@ -52,6 +53,7 @@ func TestReadAgentConfig(t *testing.T) {
 					TestDeployDir:      "/opt/bitrise/ef7a9665e8b6408b/80b66786-d011-430f-9c68-00e9416a7325/test_results",
 				},
 				AgentHooks{},
+				"",
 			},
 			expectedErr: false,
 		},
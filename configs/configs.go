@@ -53,6 +53,12 @@ const (
 	IsSecretEnvsFilteringKey = "BITRISE_SECRET_ENVS_FILTERING"
 	// NoOutputTimeoutEnvKey ...
 	NoOutputTimeoutEnvKey = "BITRISE_NO_OUTPUT_TIMEOUT"
+	// KeepContainersEnvKey ...
+	KeepContainersEnvKey = "BITRISE_DOCKER_KEEP_CONTAINERS"
+	// CheckpointsEnvKey ...
+	CheckpointsEnvKey = "BITRISE_CHECKPOINTS"
+	// ContainerRuntimeEnvKey ...
+	ContainerRuntimeEnvKey = "BITRISE_CONTAINER_RUNTIME"
 
 	// --- Debug Options
 
@@ -0,0 +1,140 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-io/bitrise/log/corelog"
+)
+
+// prefixedLogger prefixes every line of the logged messages,
+// it is used to tell apart the output of steps running at the same time.
+type prefixedLogger struct {
+	Logger
+
+	prefix      string
+	mux         sync.Mutex
+	atLineStart bool
+}
+
+// NewPrefixedLogger ...
+func NewPrefixedLogger(logger Logger, prefix string) Logger {
+	return &prefixedLogger{
+		Logger:      logger,
+		prefix:      prefix,
+		atLineStart: true,
+	}
+}
+
+// LogMessage ...
+func (l *prefixedLogger) LogMessage(message string, level corelog.Level) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.Logger.LogMessage(l.prefixLines(message), level)
+}
+
+// Error ...
+func (l *prefixedLogger) Error(args ...interface{}) {
+	l.LogMessage(fmt.Sprint(args...)+"\n", corelog.ErrorLevel)
+}
+
+// Errorf ...
+func (l *prefixedLogger) Errorf(format string, args ...interface{}) {
+	l.LogMessage(fmt.Sprintf(format, args...)+"\n", corelog.ErrorLevel)
+}
+
+// Warn ...
+func (l *prefixedLogger) Warn(args ...interface{}) {
+	l.LogMessage(fmt.Sprint(args...)+"\n", corelog.WarnLevel)
+}
+
+// Warnf ...
+func (l *prefixedLogger) Warnf(format string, args ...interface{}) {
+	l.LogMessage(fmt.Sprintf(format, args...)+"\n", corelog.WarnLevel)
+}
+
+// Info ...
+func (l *prefixedLogger) Info(args ...interface{}) {
+	l.LogMessage(fmt.Sprint(args...)+"\n", corelog.InfoLevel)
+}
+
+// Infof ...
+func (l *prefixedLogger) Infof(format string, args ...interface{}) {
+	l.LogMessage(fmt.Sprintf(format, args...)+"\n", corelog.InfoLevel)
+}
+
+// Done ...
+func (l *prefixedLogger) Done(args ...interface{}) {
+	l.LogMessage(fmt.Sprint(args...)+"\n", corelog.DoneLevel)
+}
+
+// Donef ...
+func (l *prefixedLogger) Donef(format string, args ...interface{}) {
+	l.LogMessage(fmt.Sprintf(format, args...)+"\n", corelog.DoneLevel)
+}
+
+// Print ...
+func (l *prefixedLogger) Print(args ...interface{}) {
+	l.LogMessage(fmt.Sprint(args...)+"\n", corelog.NormalLevel)
+}
+
+// Printf ...
+func (l *prefixedLogger) Printf(format string, args ...interface{}) {
+	l.LogMessage(fmt.Sprintf(format, args...)+"\n", corelog.NormalLevel)
+}
+
+// Debug ...
+func (l *prefixedLogger) Debug(args ...interface{}) {
+	l.LogMessage(fmt.Sprint(args...)+"\n", corelog.DebugLevel)
+}
+
+// Debugf ...
+func (l *prefixedLogger) Debugf(format string, args ...interface{}) {
+	l.LogMessage(fmt.Sprintf(format, args...)+"\n", corelog.DebugLevel)
+}
+
+// PrintStepStartedEvent prints the step's header box with the prefix, JSON events are logged as they are.
+func (l *prefixedLogger) PrintStepStartedEvent(params StepStartedParams) {
+	if l.isJSONLogger() {
+		l.Logger.PrintStepStartedEvent(params)
+		return
+	}
+	for _, line := range generateStepStartedHeaderLines(params) {
+		l.Print(line)
+	}
+}
+
+// PrintStepFinishedEvent prints the step's footer box with the prefix, JSON events are logged as they are.
+func (l *prefixedLogger) PrintStepFinishedEvent(params StepFinishedParams) {
+	if l.isJSONLogger() {
+		l.Logger.PrintStepFinishedEvent(params)
+		return
+	}
+	for _, line := range generateStepFinishedFooterLines(params) {
+		l.Print(line)
+	}
+}
+
+func (l *prefixedLogger) isJSONLogger() bool {
+	logger, ok := l.Logger.(*defaultLogger)
+	return ok && logger.opts.LoggerType == JSONLogger
+}
+
+// prefixLines adds the prefix to the beginning of the lines,
+// a message might continue the last line of the previous message, which already has the prefix.
+func (l *prefixedLogger) prefixLines(message string) string {
+	var sb strings.Builder
+	for _, line := range strings.SplitAfter(message, "\n") {
+		if line == "" {
+			continue
+		}
+		if l.atLineStart {
+			sb.WriteString(l.prefix)
+		}
+		sb.WriteString(line)
+		l.atLineStart = strings.HasSuffix(line, "\n")
+	}
+	return sb.String()
+}
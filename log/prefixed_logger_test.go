@@ -0,0 +1,51 @@
+package log_test
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/log"
+	"github.com/bitrise-io/bitrise/log/corelog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixedLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewPrefixedLogger(log.NewLogger(log.LoggerOpts{
+		LoggerType:   log.ConsoleLogger,
+		Writer:       &buf,
+		TimeProvider: referenceTime,
+	}), "[lint] ")
+
+	logger.LogMessage("first line\nsecond ", corelog.NormalLevel)
+	logger.LogMessage("line continued\n", corelog.NormalLevel)
+	logger.LogMessage("third line\n", corelog.NormalLevel)
+
+	require.Equal(t, "[lint] first line\n[lint] second line continued\n[lint] third line\n", buf.String())
+}
+
+func TestPrefixedLogger_PrefixesEveryMethod(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewPrefixedLogger(log.NewLogger(log.LoggerOpts{
+		LoggerType:   log.ConsoleLogger,
+		Writer:       &buf,
+		TimeProvider: referenceTime,
+	}), "[lint] ")
+
+	logger.Infof("info %d", 1)
+	logger.Warnf("warning")
+	logger.Errorf("error")
+	logger.Debugf("debug")
+	logger.PrintStepStartedEvent(log.StepStartedParams{Position: 1, Title: "Lint", Id: "lint", Version: "1.0.0"})
+
+	// the console logger colors the messages by their level
+	output := regexp.MustCompile(`\x1b\[[0-9;]*m`).ReplaceAllString(buf.String(), "")
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	require.Greater(t, len(lines), 3)
+	for _, line := range lines {
+		require.True(t, strings.HasPrefix(line, "[lint] "), line)
+	}
+	require.NotContains(t, buf.String(), "debug")
+}
@@ -0,0 +1,212 @@
+package models
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	PullPolicyAlways       = "always"
+	PullPolicyIfNotPresent = "if-not-present"
+	PullPolicyNever        = "never"
+
+	DefaultContainerPlatform = "linux/amd64"
+
+	defaultReadinessTimeout  = 60 * time.Second
+	defaultReadinessInterval = 2 * time.Second
+)
+
+var supportedVolumeOptions = []string{"ro", "rw", "z", "Z", "cached", "delegated", "consistent"}
+
+// Validate ...
+func (container Container) Validate() error {
+	if container.Build != nil {
+		if container.Image != "" {
+			return fmt.Errorf("image and build can't be set at the same time")
+		}
+		if container.Build.Context == "" {
+			return fmt.Errorf("invalid build: missing context")
+		}
+	}
+
+	if credentials := container.Credentials; credentials.CredentialHelper != "" {
+		if credentials.Username != "" || credentials.Password != "" {
+			return fmt.Errorf("invalid credentials: credential_helper and username/password can't be set at the same time")
+		}
+		if credentials.Server == "" {
+			return fmt.Errorf("invalid credentials: credential_helper requires server")
+		}
+	}
+
+	if container.User != "" && container.MapHostUser {
+		return fmt.Errorf("user and map_host_user can't be set at the same time")
+	}
+
+	for _, volume := range container.Volumes {
+		if err := validateVolume(volume); err != nil {
+			return fmt.Errorf("invalid volume (%s): %s", volume, err)
+		}
+	}
+
+	if container.Platform != "" {
+		parts := strings.Split(container.Platform, "/")
+		if (len(parts) != 2 && len(parts) != 3) || parts[0] == "" || parts[1] == "" || (len(parts) == 3 && parts[2] == "") {
+			return fmt.Errorf("invalid platform (%s): expected format: <os>/<arch>[/<variant>]", container.Platform)
+		}
+	}
+
+	switch container.PullPolicy {
+	case "", PullPolicyAlways, PullPolicyIfNotPresent, PullPolicyNever:
+	default:
+		return fmt.Errorf("invalid pull_policy (%s), supported values: %s, %s, %s", container.PullPolicy, PullPolicyAlways, PullPolicyIfNotPresent, PullPolicyNever)
+	}
+
+	if container.Readiness != nil {
+		if err := container.Readiness.Validate(); err != nil {
+			return fmt.Errorf("invalid readiness: %s", err)
+		}
+	}
+	return nil
+}
+
+// Validate ...
+func (readiness ServiceReadiness) Validate() error {
+	if readiness.TCP == "" && readiness.HTTP == nil && readiness.Command == "" {
+		return fmt.Errorf("no probe defined, at least one of tcp, http and command is required")
+	}
+	if readiness.TCP != "" {
+		if _, _, err := net.SplitHostPort(readiness.TCP); err != nil {
+			return fmt.Errorf("tcp should be a host:port address, got: %s", readiness.TCP)
+		}
+	}
+	if readiness.HTTP != nil {
+		u, err := url.Parse(readiness.HTTP.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("http url should be an absolute http(s) url, got: %s", readiness.HTTP.URL)
+		}
+	}
+	if readiness.Timeout < 0 {
+		return fmt.Errorf("timeout should not be negative, got: %d", readiness.Timeout)
+	}
+	if readiness.Interval < 0 {
+		return fmt.Errorf("interval should not be negative, got: %d", readiness.Interval)
+	}
+	return nil
+}
+
+// GetTimeout returns the time the service has to become ready.
+func (readiness ServiceReadiness) GetTimeout() time.Duration {
+	if readiness.Timeout == 0 {
+		return defaultReadinessTimeout
+	}
+	return time.Duration(readiness.Timeout) * time.Second
+}
+
+// GetInterval returns the time between the probes.
+func (readiness ServiceReadiness) GetInterval() time.Duration {
+	if readiness.Interval == 0 {
+		return defaultReadinessInterval
+	}
+	return time.Duration(readiness.Interval) * time.Second
+}
+
+// IsDefined returns whether the container is set, either with an image or with a build.
+func (container Container) IsDefined() bool {
+	return container.Image != "" || container.Build != nil
+}
+
+// ImageRef returns the image of the container, or the Dockerfile the image is built from (for the logs).
+func (container Container) ImageRef() string {
+	if container.Build == nil {
+		return container.Image
+	}
+
+	dockerfile := container.Build.Dockerfile
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	return fmt.Sprintf("image built from %s", filepath.Join(container.Build.Context, dockerfile))
+}
+
+// GetPlatform returns the platform of the container's image, linux/amd64 if not set.
+func (container Container) GetPlatform() string {
+	if container.Platform == "" {
+		return DefaultContainerPlatform
+	}
+	return container.Platform
+}
+
+// GetPullPolicy returns the pull policy of the container's image. If not set, images with the latest tag (or without a tag)
+// are always pulled, so that a stale local image is not used, other images are only pulled if they are not present locally.
+func (container Container) GetPullPolicy() string {
+	if container.PullPolicy != "" {
+		return container.PullPolicy
+	}
+	if container.ImageDigest() == "" {
+		if tag := container.imageTag(); tag == "" || tag == "latest" {
+			return PullPolicyAlways
+		}
+	}
+	return PullPolicyIfNotPresent
+}
+
+// ImageDigest returns the digest the image is pinned to (the part after @), empty if the image is not digest-pinned.
+func (container Container) ImageDigest() string {
+	if i := strings.Index(container.Image, "@"); i != -1 {
+		return container.Image[i+1:]
+	}
+	return ""
+}
+
+func (container Container) imageTag() string {
+	name := container.Image
+	if i := strings.Index(name, "@"); i != -1 {
+		name = name[:i]
+	}
+	// the registry host might contain a port, the tag is in the last path component
+	if i := strings.LastIndex(name, "/"); i != -1 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, ":"); i != -1 {
+		return name[i+1:]
+	}
+	return ""
+}
+
+// validateVolume checks a volume in the docker `-v` format: <host path or volume name>:<container path>[:<options>].
+// Env vars are not expanded at this point, the host part is only expected to be non-empty.
+func validateVolume(volume string) error {
+	parts := strings.Split(volume, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return fmt.Errorf("expected format: <host path>:<container path>[:<options>]")
+	}
+
+	if parts[0] == "" {
+		return fmt.Errorf("missing host path")
+	}
+	if !strings.HasPrefix(parts[1], "/") {
+		return fmt.Errorf("container path should be absolute, got: %s", parts[1])
+	}
+
+	if len(parts) == 3 {
+		for _, option := range strings.Split(parts[2], ",") {
+			if !isSupportedVolumeOption(option) {
+				return fmt.Errorf("unknown option (%s), supported options: %s", option, strings.Join(supportedVolumeOptions, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+func isSupportedVolumeOption(option string) bool {
+	for _, supported := range supportedVolumeOptions {
+		if option == supported {
+			return true
+		}
+	}
+	return false
+}
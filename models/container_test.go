@@ -0,0 +1,68 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainer_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		container Container
+		wantErr   string
+	}{
+		{name: "no volumes", container: Container{Image: "ubuntu"}},
+		{name: "valid volumes", container: Container{Image: "ubuntu", Volumes: []string{"$BITRISE_SOURCE_DIR/cache:/cache", "gradle-cache:/root/.gradle:rw", "/etc/hosts:/etc/hosts:ro,z"}}},
+		{name: "missing container path", container: Container{Volumes: []string{"/cache"}}, wantErr: "invalid volume (/cache): expected format: <host path>:<container path>[:<options>]"},
+		{name: "missing host path", container: Container{Volumes: []string{":/cache"}}, wantErr: "invalid volume (:/cache): missing host path"},
+		{name: "relative container path", container: Container{Volumes: []string{"/cache:cache"}}, wantErr: "invalid volume (/cache:cache): container path should be absolute, got: cache"},
+		{name: "unknown option", container: Container{Volumes: []string{"/cache:/cache:rx"}}, wantErr: "invalid volume (/cache:/cache:rx): unknown option (rx), supported options: ro, rw, z, Z, cached, delegated, consistent"},
+		{name: "valid platform and pull policy", container: Container{Image: "ubuntu", Platform: "linux/arm64/v8", PullPolicy: "never"}},
+		{name: "invalid platform", container: Container{Image: "ubuntu", Platform: "arm64"}, wantErr: "invalid platform (arm64): expected format: <os>/<arch>[/<variant>]"},
+		{name: "build", container: Container{Build: &ContainerBuild{Context: "ci", Args: map[string]string{"A": "1"}}}},
+		{name: "build and image", container: Container{Image: "ubuntu", Build: &ContainerBuild{Context: "ci"}}, wantErr: "image and build can't be set at the same time"},
+		{name: "build without context", container: Container{Build: &ContainerBuild{Dockerfile: "Dockerfile"}}, wantErr: "invalid build: missing context"},
+		{name: "readiness", container: Container{Image: "postgres", Readiness: &ServiceReadiness{TCP: "localhost:5432", HTTP: &ServiceReadinessHTTP{URL: "http://localhost:8080/health", Status: 204}}}},
+		{name: "readiness without probe", container: Container{Image: "postgres", Readiness: &ServiceReadiness{Timeout: 30}}, wantErr: "invalid readiness: no probe defined, at least one of tcp, http and command is required"},
+		{name: "invalid tcp readiness", container: Container{Image: "postgres", Readiness: &ServiceReadiness{TCP: "5432"}}, wantErr: "invalid readiness: tcp should be a host:port address, got: 5432"},
+		{name: "negative readiness timeout", container: Container{Image: "postgres", Readiness: &ServiceReadiness{Command: "pg_isready", Timeout: -1}}, wantErr: "invalid readiness: timeout should not be negative, got: -1"},
+		{name: "credential helper", container: Container{Image: "123.dkr.ecr.us-east-1.amazonaws.com/app", Credentials: DockerCredentials{Server: "123.dkr.ecr.us-east-1.amazonaws.com", CredentialHelper: "ecr-login"}}},
+		{name: "credential helper and password", container: Container{Image: "app", Credentials: DockerCredentials{Server: "ghcr.io", Username: "bitrise", Password: "$TOKEN", CredentialHelper: "gcr"}}, wantErr: "invalid credentials: credential_helper and username/password can't be set at the same time"},
+		{name: "credential helper without server", container: Container{Image: "app", Credentials: DockerCredentials{CredentialHelper: "ecr-login"}}, wantErr: "invalid credentials: credential_helper requires server"},
+		{name: "user", container: Container{Image: "node:20", User: "1000:1000"}},
+		{name: "user and map host user", container: Container{Image: "node:20", User: "node", MapHostUser: true}, wantErr: "user and map_host_user can't be set at the same time"},
+		{name: "invalid pull policy", container: Container{Image: "ubuntu", PullPolicy: "missing"}, wantErr: "invalid pull_policy (missing), supported values: always, if-not-present, never"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.container.Validate()
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestContainer_GetPullPolicy(t *testing.T) {
+	tests := []struct {
+		image      string
+		pullPolicy string
+		want       string
+	}{
+		{image: "ubuntu", want: PullPolicyAlways},
+		{image: "ubuntu:latest", want: PullPolicyAlways},
+		{image: "localhost:5000/team/ubuntu", want: PullPolicyAlways},
+		{image: "localhost:5000/team/ubuntu:22.04", want: PullPolicyIfNotPresent},
+		{image: "ubuntu@sha256:1dbfc3c8", want: PullPolicyIfNotPresent},
+		{image: "ubuntu:latest", pullPolicy: PullPolicyNever, want: PullPolicyNever},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			container := Container{Image: tt.image, PullPolicy: tt.pullPolicy}
+			require.Equal(t, tt.want, container.GetPullPolicy())
+		})
+	}
+}
@@ -11,18 +11,74 @@ import (
 
 const (
 	// FormatVersion ...
-	FormatVersion = "13"
+	// 14: the workflow specific options of the step run (e.g. retry), StepListItemModel holds WorkflowStepModel values
+	FormatVersion = "14"
+	// ParallelStepGroupKey is the key of a step list item, which groups steps to run at the same time
+	ParallelStepGroupKey = "parallel"
+	// StepBundleIDPrefix is the prefix of a step list item key, which references a step bundle (bundle::<ID>)
+	StepBundleIDPrefix = "bundle::"
 )
 
 // StepListItemModel ...
-type StepListItemModel map[string]stepmanModels.StepModel
+// Since format version 14 its values are WorkflowStepModels (instead of stepman's StepModels), which embed the step's StepModel.
+type StepListItemModel map[string]WorkflowStepModel
+
+// WorkflowStepModel is a step as it is referenced in a workflow,
+// besides the step properties it holds the workflow specific options of the step run.
+type WorkflowStepModel struct {
+	stepmanModels.StepModel `yaml:",inline"`
+
+	Retry *StepRetryModel `json:"retry,omitempty" yaml:"retry,omitempty"`
+	// Steps are the steps of a parallel step group, these run at the same time
+	Steps []StepListItemModel `json:"steps,omitempty" yaml:"steps,omitempty"`
+	// Container runs the step in its own docker container, instead of the host or the workflow's container
+	Container *Container `json:"container,omitempty" yaml:"container,omitempty"`
+}
+
+// StepBundleModel is a reusable list of steps, defined at the top level of the config.
+// Workflows reference it in their step lists as bundle::<ID>, the reference can override the inputs of the bundle.
+// The inputs and envs of the bundle are only available for the steps of the bundle.
+type StepBundleModel struct {
+	Title        string                              `json:"title,omitempty" yaml:"title,omitempty"`
+	Summary      string                              `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description  string                              `json:"description,omitempty" yaml:"description,omitempty"`
+	Inputs       []envmanModels.EnvironmentItemModel `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+	Environments []envmanModels.EnvironmentItemModel `json:"envs,omitempty" yaml:"envs,omitempty"`
+	Steps        []StepListItemModel                 `json:"steps,omitempty" yaml:"steps,omitempty"`
+}
+
+// StepRetryModel describes when and how many times a failed step should be re-run.
+// Without exit_codes and on conditions every failure is retried.
+type StepRetryModel struct {
+	// MaxAttempts is the max number of step runs, including the first one
+	MaxAttempts int `json:"max_attempts,omitempty" yaml:"max_attempts,omitempty"`
+	// Backoff is the wait time before the first retry in seconds
+	Backoff int `json:"backoff,omitempty" yaml:"backoff,omitempty"`
+	// BackoffMultiplier is applied to the wait time before each further retry, 0 means constant backoff
+	BackoffMultiplier float64 `json:"backoff_multiplier,omitempty" yaml:"backoff_multiplier,omitempty"`
+	// ExitCodes are the exit codes of the failed step run to retry on
+	ExitCodes []int `json:"exit_codes,omitempty" yaml:"exit_codes,omitempty"`
+	// On are the abort reasons to retry on: timeout, no_output_timeout
+	On []string `json:"on,omitempty" yaml:"on,omitempty"`
+}
 
 // PipelineModel ...
 type PipelineModel struct {
-	Title       string               `json:"title,omitempty" yaml:"title,omitempty"`
-	Summary     string               `json:"summary,omitempty" yaml:"summary,omitempty"`
-	Description string               `json:"description,omitempty" yaml:"description,omitempty"`
-	Stages      []StageListItemModel `json:"stages,omitempty" yaml:"stages,omitempty"`
+	Title       string                             `json:"title,omitempty" yaml:"title,omitempty"`
+	Summary     string                             `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description string                             `json:"description,omitempty" yaml:"description,omitempty"`
+	Stages      []StageListItemModel               `json:"stages,omitempty" yaml:"stages,omitempty"`
+	Workflows   GraphPipelineWorkflowListItemModel `json:"workflows,omitempty" yaml:"workflows,omitempty"`
+	// MaxParallel limits the number of the graph pipeline's workflows running at the same time, 0 means no limit
+	MaxParallel int `json:"max_parallel,omitempty" yaml:"max_parallel,omitempty"`
+}
+
+// GraphPipelineWorkflowListItemModel ...
+type GraphPipelineWorkflowListItemModel map[string]GraphPipelineWorkflowModel
+
+// GraphPipelineWorkflowModel ...
+type GraphPipelineWorkflowModel struct {
+	DependsOn []string `json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
 }
 
 // StageListItemModel ...
@@ -44,22 +100,69 @@ type WorkflowListItemModel map[string]WorkflowModel
 
 // WorkflowModel ...
 type WorkflowModel struct {
-	Container    Container                           `json:"container,omitempty" yaml:"container,omitempty"`
-	Services     map[string]Container                `json:"services,omitempty" yaml:"services,omitempty"`
-	Title        string                              `json:"title,omitempty" yaml:"title,omitempty"`
-	Summary      string                              `json:"summary,omitempty" yaml:"summary,omitempty"`
-	Description  string                              `json:"description,omitempty" yaml:"description,omitempty"`
-	BeforeRun    []string                            `json:"before_run,omitempty" yaml:"before_run,omitempty"`
-	AfterRun     []string                            `json:"after_run,omitempty" yaml:"after_run,omitempty"`
-	Environments []envmanModels.EnvironmentItemModel `json:"envs,omitempty" yaml:"envs,omitempty"`
-	Steps        []StepListItemModel                 `json:"steps,omitempty" yaml:"steps,omitempty"`
-	Meta         map[string]interface{}              `json:"meta,omitempty" yaml:"meta,omitempty"`
+	Container       Container                           `json:"container,omitempty" yaml:"container,omitempty"`
+	Services        map[string]Container                `json:"services,omitempty" yaml:"services,omitempty"`
+	ServicesCompose string                              `json:"services_compose,omitempty" yaml:"services_compose,omitempty"`
+	Title           string                              `json:"title,omitempty" yaml:"title,omitempty"`
+	Summary         string                              `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description     string                              `json:"description,omitempty" yaml:"description,omitempty"`
+	BeforeRun       []string                            `json:"before_run,omitempty" yaml:"before_run,omitempty"`
+	AfterRun        []string                            `json:"after_run,omitempty" yaml:"after_run,omitempty"`
+	Environments    []envmanModels.EnvironmentItemModel `json:"envs,omitempty" yaml:"envs,omitempty"`
+	Steps           []StepListItemModel                 `json:"steps,omitempty" yaml:"steps,omitempty"`
+	Exports         WorkflowExportsModel                `json:"exports,omitempty" yaml:"exports,omitempty"`
+	Meta            map[string]interface{}              `json:"meta,omitempty" yaml:"meta,omitempty"`
+}
+
+// WorkflowExportsModel lists the envs and files a workflow passes to the later workflows of the same pipeline run.
+type WorkflowExportsModel struct {
+	Envs  []string            `json:"envs,omitempty" yaml:"envs,omitempty"`
+	Files []ExportedFileModel `json:"files,omitempty" yaml:"files,omitempty"`
+}
+
+// ExportedFileModel ...
+type ExportedFileModel struct {
+	// Path of the file or directory to export, envs are expanded
+	Path string `json:"path" yaml:"path"`
+	// EnvKey is the env which holds the path of the exported file in the later workflows
+	EnvKey string `json:"env_key" yaml:"env_key"`
 }
 
 type DockerCredentials struct {
 	Username string `json:"username,omitempty" yaml:"username,omitempty"`
 	Password string `json:"password,omitempty" yaml:"password,omitempty"`
 	Server   string `json:"server,omitempty" yaml:"server,omitempty"`
+	// CredentialHelper is the docker credential helper (docker-credential-<helper>) of the server, instead of username and password
+	CredentialHelper string `json:"credential_helper,omitempty" yaml:"credential_helper,omitempty"`
+}
+
+// ContainerBuild builds the image of a container from a Dockerfile, instead of using a published image.
+type ContainerBuild struct {
+	// Context is the build context dir, relative to the source dir
+	Context string `json:"context,omitempty" yaml:"context,omitempty"`
+	// Dockerfile is relative to the context, Dockerfile by default
+	Dockerfile string            `json:"dockerfile,omitempty" yaml:"dockerfile,omitempty"`
+	Args       map[string]string `json:"args,omitempty" yaml:"args,omitempty"`
+	Target     string            `json:"target,omitempty" yaml:"target,omitempty"`
+}
+
+// ServiceReadiness are the probes which should pass before the steps of the workflow start, all defined probes are checked.
+type ServiceReadiness struct {
+	// TCP is a host:port address, which should accept connections (probed from the host, the port should be published)
+	TCP  string                `json:"tcp,omitempty" yaml:"tcp,omitempty"`
+	HTTP *ServiceReadinessHTTP `json:"http,omitempty" yaml:"http,omitempty"`
+	// Command is run in the service container with sh -c, it should exit with 0
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
+	// Timeout in seconds, 60 by default
+	Timeout int `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Interval between the probes in seconds, 2 by default
+	Interval int `json:"interval,omitempty" yaml:"interval,omitempty"`
+}
+
+// ServiceReadinessHTTP is an HTTP GET probe, any 2xx status passes if Status is not set.
+type ServiceReadinessHTTP struct {
+	URL    string `json:"url,omitempty" yaml:"url,omitempty"`
+	Status int    `json:"status,omitempty" yaml:"status,omitempty"`
 }
 
 type Container struct {
@@ -68,6 +171,20 @@ type Container struct {
 	Ports       []string                            `json:"ports,omitempty" yaml:"ports,omitempty"`
 	Envs        []envmanModels.EnvironmentItemModel `json:"envs,omitempty" yaml:"envs,omitempty"`
 	Options     string                              `json:"options,omitempty" yaml:"options,omitempty"`
+	// Volumes are mounted in addition to the default mounts (source, deploy and test deploy dir), env vars are expanded
+	Volumes []string `json:"volumes,omitempty" yaml:"volumes,omitempty"`
+	// Platform of the image (os/arch[/variant]), linux/amd64 by default
+	Platform string `json:"platform,omitempty" yaml:"platform,omitempty"`
+	// PullPolicy is one of always, if-not-present and never, see GetPullPolicy for the default
+	PullPolicy string `json:"pull_policy,omitempty" yaml:"pull_policy,omitempty"`
+	// Build builds the image, it can't be used together with Image
+	Build *ContainerBuild `json:"build,omitempty" yaml:"build,omitempty"`
+	// Readiness is only supported for services
+	Readiness *ServiceReadiness `json:"readiness,omitempty" yaml:"readiness,omitempty"`
+	// User (name or uid[:gid]) runs the container, workflow and step containers run as root by default
+	User string `json:"user,omitempty" yaml:"user,omitempty"`
+	// MapHostUser runs the container as the uid:gid of the user running bitrise, it can't be used together with User
+	MapHostUser bool `json:"map_host_user,omitempty" yaml:"map_host_user,omitempty"`
 }
 
 // AppModel ...
@@ -88,12 +205,13 @@ type BitriseDataModel struct {
 	Summary     string `json:"summary,omitempty" yaml:"summary,omitempty"`
 	Description string `json:"description,omitempty" yaml:"description,omitempty"`
 	//
-	App        AppModel                 `json:"app,omitempty" yaml:"app,omitempty"`
-	Meta       map[string]interface{}   `json:"meta,omitempty" yaml:"meta,omitempty"`
-	TriggerMap TriggerMapModel          `json:"trigger_map,omitempty" yaml:"trigger_map,omitempty"`
-	Pipelines  map[string]PipelineModel `json:"pipelines,omitempty" yaml:"pipelines,omitempty"`
-	Stages     map[string]StageModel    `json:"stages,omitempty" yaml:"stages,omitempty"`
-	Workflows  map[string]WorkflowModel `json:"workflows,omitempty" yaml:"workflows,omitempty"`
+	App         AppModel                   `json:"app,omitempty" yaml:"app,omitempty"`
+	Meta        map[string]interface{}     `json:"meta,omitempty" yaml:"meta,omitempty"`
+	TriggerMap  TriggerMapModel            `json:"trigger_map,omitempty" yaml:"trigger_map,omitempty"`
+	Pipelines   map[string]PipelineModel   `json:"pipelines,omitempty" yaml:"pipelines,omitempty"`
+	Stages      map[string]StageModel      `json:"stages,omitempty" yaml:"stages,omitempty"`
+	StepBundles map[string]StepBundleModel `json:"step_bundles,omitempty" yaml:"step_bundles,omitempty"`
+	Workflows   map[string]WorkflowModel   `json:"workflows,omitempty" yaml:"workflows,omitempty"`
 }
 
 // StepIDData ...
@@ -129,6 +247,27 @@ type BuildRunResultsModel struct {
 	SkippedSteps         []StepRunResultsModel `json:"skipped_steps" yaml:"skipped_steps"`
 }
 
+// PipelineRunResultsModel ...
+type PipelineRunResultsModel struct {
+	PipelineID   string                 `json:"pipeline_id" yaml:"pipeline_id"`
+	StartTime    time.Time              `json:"start_time" yaml:"start_time"`
+	RunTime      time.Duration          `json:"run_time" yaml:"run_time"`
+	StageResults []StageRunResultsModel `json:"stage_results" yaml:"stage_results"`
+	// WorkflowResults and SkippedWorkflows are set for graph pipelines (pipelines defined by workflows with depends_on)
+	WorkflowResults  []BuildRunResultsModel `json:"workflow_results,omitempty" yaml:"workflow_results,omitempty"`
+	SkippedWorkflows []string               `json:"skipped_workflows,omitempty" yaml:"skipped_workflows,omitempty"`
+}
+
+// StageRunResultsModel ...
+type StageRunResultsModel struct {
+	StageID          string                 `json:"stage_id" yaml:"stage_id"`
+	Status           StageRunStatus         `json:"status" yaml:"status"`
+	StartTime        time.Time              `json:"start_time" yaml:"start_time"`
+	RunTime          time.Duration          `json:"run_time" yaml:"run_time"`
+	WorkflowResults  []BuildRunResultsModel `json:"workflow_results" yaml:"workflow_results"`
+	AbortedWorkflows []string               `json:"aborted_workflows,omitempty" yaml:"aborted_workflows,omitempty"`
+}
+
 // StepRunResultsModel ...
 type StepRunResultsModel struct {
 	StepInfo   stepmanModels.StepInfoModel `json:"step_info" yaml:"step_info"`
@@ -142,6 +281,19 @@ type StepRunResultsModel struct {
 
 	Timeout         time.Duration `json:"-"`
 	NoOutputTimeout time.Duration `json:"-"`
+
+	// Attempts lists every run of a retried step, the last one is the final result
+	Attempts []StepRunAttemptModel `json:"attempts,omitempty" yaml:"attempts,omitempty"`
+}
+
+// StepRunAttemptModel ...
+type StepRunAttemptModel struct {
+	Attempt   int           `json:"attempt" yaml:"attempt"`
+	Status    StepRunStatus `json:"status" yaml:"status"`
+	ExitCode  int           `json:"exit_code" yaml:"exit_code"`
+	ErrorStr  string        `json:"error_str" yaml:"error_str"`
+	StartTime time.Time     `json:"start_time" yaml:"start_time"`
+	RunTime   time.Duration `json:"run_time" yaml:"run_time"`
 }
 
 // StepError ...
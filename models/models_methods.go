@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/bitrise-io/bitrise/exitcode"
@@ -112,7 +113,18 @@ func (workflow *WorkflowModel) Normalize() error {
 		}
 	}
 
-	for _, stepListItem := range workflow.Steps {
+	return normalizeSteps(workflow.Steps)
+}
+
+func normalizeSteps(steps []StepListItemModel) error {
+	for _, stepListItem := range steps {
+		if parallelSteps, ok := stepListItem.GetParallelSteps(); ok {
+			if err := normalizeSteps(parallelSteps); err != nil {
+				return err
+			}
+			continue
+		}
+
 		stepID, step, err := GetStepIDStepDataPair(stepListItem)
 		if err != nil {
 			return err
@@ -120,12 +132,30 @@ func (workflow *WorkflowModel) Normalize() error {
 		if err := step.Normalize(); err != nil {
 			return err
 		}
-		stepListItem[stepID] = step
+		stepListItem.SetStep(stepID, step)
 	}
 
 	return nil
 }
 
+// envItems returns the inputs and the envs of the step bundle.
+func (bundle StepBundleModel) envItems() []envmanModels.EnvironmentItemModel {
+	var envs []envmanModels.EnvironmentItemModel
+	envs = append(envs, bundle.Inputs...)
+	return append(envs, bundle.Environments...)
+}
+
+// Normalize ...
+func (bundle *StepBundleModel) Normalize() error {
+	for _, env := range bundle.envItems() {
+		if err := env.Normalize(); err != nil {
+			return err
+		}
+	}
+
+	return normalizeSteps(bundle.Steps)
+}
+
 // Normalize ...
 func (app *AppModel) Normalize() error {
 	for _, env := range app.Environments {
@@ -142,6 +172,12 @@ func (config *BitriseDataModel) Normalize() error {
 		return err
 	}
 
+	for _, bundle := range config.StepBundles {
+		if err := bundle.Normalize(); err != nil {
+			return err
+		}
+	}
+
 	for _, workflow := range config.Workflows {
 		if err := workflow.Normalize(); err != nil {
 			return err
@@ -167,8 +203,63 @@ func (workflow *WorkflowModel) Validate() ([]string, error) {
 		}
 	}
 
+	if err := workflow.Container.Validate(); err != nil {
+		return []string{}, fmt.Errorf("invalid container: %s", err)
+	}
+	if workflow.Container.Readiness != nil {
+		return []string{}, fmt.Errorf("invalid container: readiness is only supported for services")
+	}
+	for name, service := range workflow.Services {
+		if err := service.Validate(); err != nil {
+			return []string{}, fmt.Errorf("invalid service (%s): %s", name, err)
+		}
+	}
+
+	warnings, err := validateSteps(workflow.Steps, false)
+	if err != nil {
+		return warnings, err
+	}
+
+	if err := workflow.Exports.Validate(); err != nil {
+		return warnings, err
+	}
+
+	return warnings, nil
+}
+
+func validateSteps(steps []StepListItemModel, inParallelGroup bool) ([]string, error) {
 	warnings := []string{}
-	for _, stepListItem := range workflow.Steps {
+	for _, stepListItem := range steps {
+		if parallelSteps, ok := stepListItem.GetParallelSteps(); ok {
+			if inParallelGroup {
+				return warnings, errors.New("invalid parallel step group: parallel groups can't be nested")
+			}
+			if len(parallelSteps) == 0 {
+				return warnings, errors.New("invalid parallel step group: no steps defined")
+			}
+
+			groupWarnings, err := validateSteps(parallelSteps, true)
+			warnings = append(warnings, groupWarnings...)
+			if err != nil {
+				return warnings, err
+			}
+			continue
+		}
+
+		if bundleID, ok := stepListItem.GetStepBundleID(); ok {
+			if inParallelGroup {
+				return warnings, fmt.Errorf("invalid parallel step group: step bundle (%s) can't be referenced in a parallel group", bundleID)
+			}
+
+			_, reference := stepListItem.GetStepIDAndStep()
+			for _, input := range reference.Inputs {
+				if err := input.Validate(); err != nil {
+					return warnings, fmt.Errorf("invalid input of step bundle (%s): %s", bundleID, err)
+				}
+			}
+			continue
+		}
+
 		stepID, step, err := GetStepIDStepDataPair(stepListItem)
 		if err != nil {
 			return warnings, err
@@ -198,12 +289,61 @@ func (workflow *WorkflowModel) Validate() ([]string, error) {
 			stepInputMap[key] = true
 		}
 
-		stepListItem[stepID] = step
+		if container := stepListItem.GetContainer(); container != nil {
+			if !container.IsDefined() {
+				return warnings, fmt.Errorf("step (%s) has invalid container: missing image", stepID)
+			}
+			if err := container.Validate(); err != nil {
+				return warnings, fmt.Errorf("step (%s) has invalid container: %s", stepID, err)
+			}
+			if container.Readiness != nil {
+				return warnings, fmt.Errorf("step (%s) has invalid container: readiness is only supported for services", stepID)
+			}
+		}
+
+		if retry := stepListItem.GetRetry(); retry != nil {
+			if err := retry.Validate(); err != nil {
+				return warnings, fmt.Errorf("step (%s) has %s", stepID, err)
+			}
+		}
+
+		stepListItem.SetStep(stepID, step)
 	}
 
 	return warnings, nil
 }
 
+// Validate ...
+func (bundle *StepBundleModel) Validate() ([]string, error) {
+	for _, env := range bundle.envItems() {
+		if err := env.Validate(); err != nil {
+			return []string{}, err
+		}
+	}
+
+	return validateSteps(bundle.Steps, false)
+}
+
+// Validate ...
+func (exports WorkflowExportsModel) Validate() error {
+	for _, envKey := range exports.Envs {
+		if envKey == "" {
+			return errors.New("invalid exports: empty env key")
+		}
+	}
+
+	for _, file := range exports.Files {
+		if file.Path == "" {
+			return fmt.Errorf("invalid exports: missing path of exported file (%s)", file.EnvKey)
+		}
+		if file.EnvKey == "" {
+			return fmt.Errorf("invalid exports: missing env_key of exported file (%s)", file.Path)
+		}
+	}
+
+	return nil
+}
+
 // Validate ...
 func (app *AppModel) Validate() error {
 	for _, env := range app.Environments {
@@ -254,6 +394,14 @@ func (config *BitriseDataModel) Validate() ([]string, error) {
 	}
 	// ---
 
+	// step bundles
+	stepBundleWarnings, err := validateStepBundles(config)
+	warnings = append(warnings, stepBundleWarnings...)
+	if err != nil {
+		return warnings, err
+	}
+	// ---
+
 	// workflows
 	workflowWarnings, err := validateWorkflows(config)
 	warnings = append(warnings, workflowWarnings...)
@@ -276,6 +424,17 @@ func validatePipelines(config *BitriseDataModel) ([]string, error) {
 			return pipelineWarnings, err
 		}
 
+		if len(pipeline.Stages) > 0 && len(pipeline.Workflows) > 0 {
+			return pipelineWarnings, fmt.Errorf("pipeline (%s) should have either stages or workflows, not both", ID)
+		}
+
+		if len(pipeline.Workflows) > 0 {
+			if err := validateGraphPipeline(ID, pipeline, config); err != nil {
+				return pipelineWarnings, err
+			}
+			continue
+		}
+
 		if len(pipeline.Stages) == 0 {
 			return pipelineWarnings, fmt.Errorf("pipeline (%s) should have at least 1 stage", ID)
 		}
@@ -301,6 +460,88 @@ func validatePipelines(config *BitriseDataModel) ([]string, error) {
 	return pipelineWarnings, nil
 }
 
+func validateGraphPipeline(pipelineID string, pipeline PipelineModel, config *BitriseDataModel) error {
+	if pipeline.MaxParallel < 0 {
+		return fmt.Errorf("pipeline (%s) has invalid max_parallel (%d), should be 0 (no limit) or greater", pipelineID, pipeline.MaxParallel)
+	}
+
+	// the workflows are validated in the order of their IDs, so the reported error is deterministic
+	workflowIDs := make([]string, 0, len(pipeline.Workflows))
+	for workflowID := range pipeline.Workflows {
+		workflowIDs = append(workflowIDs, workflowID)
+	}
+	sort.Strings(workflowIDs)
+
+	for _, workflowID := range workflowIDs {
+		pipelineWorkflow := pipeline.Workflows[workflowID]
+		if isUtilityWorkflow(workflowID) {
+			return fmt.Errorf("workflow (%s) defined in pipeline (%s), is a utility workflow", workflowID, pipelineID)
+		}
+		if _, ok := config.Workflows[workflowID]; !ok {
+			return fmt.Errorf("workflow (%s) defined in pipeline (%s), but does not exist", workflowID, pipelineID)
+		}
+
+		for _, dependencyID := range pipelineWorkflow.DependsOn {
+			if _, ok := pipeline.Workflows[dependencyID]; !ok {
+				return fmt.Errorf("workflow (%s) defined in pipeline (%s) depends on workflow (%s), which is not part of the pipeline", workflowID, pipelineID, dependencyID)
+			}
+		}
+	}
+
+	if err := checkWorkflowDependencyCycles(workflowIDs, pipeline.Workflows); err != nil {
+		return fmt.Errorf("pipeline (%s): %s", pipelineID, err)
+	}
+
+	return nil
+}
+
+// checkWorkflowDependencyCycles walks the dependencies of the workflows depth first, every workflow is walked once:
+// a workflow is in progress while its dependencies are walked, reaching a workflow in progress again means a cycle.
+func checkWorkflowDependencyCycles(workflowIDs []string, pipelineWorkflows GraphPipelineWorkflowListItemModel) error {
+	const (
+		inProgress = iota + 1
+		done
+	)
+	states := map[string]int{}
+
+	var walk func(workflowID string, workflowStack []string) error
+	walk = func(workflowID string, workflowStack []string) error {
+		switch states[workflowID] {
+		case done:
+			return nil
+		case inProgress:
+			// the cycle starts where the workflow was reached first
+			cycleStart := 0
+			for idx, stackWorkflowID := range workflowStack {
+				if stackWorkflowID == workflowID {
+					cycleStart = idx
+					break
+				}
+			}
+			cycle := append(append([]string{}, workflowStack[cycleStart:]...), workflowID)
+			return fmt.Errorf("Workflow dependency cycle found: %s", strings.Join(cycle, " -> "))
+		}
+
+		states[workflowID] = inProgress
+		workflowStack = append(workflowStack, workflowID)
+		for _, dependencyID := range pipelineWorkflows[workflowID].DependsOn {
+			if err := walk(dependencyID, workflowStack); err != nil {
+				return err
+			}
+		}
+		states[workflowID] = done
+
+		return nil
+	}
+
+	for _, workflowID := range workflowIDs {
+		if err := walk(workflowID, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func validateStages(config *BitriseDataModel) ([]string, error) {
 	stageWarnings := make([]string, 0)
 	for ID, stage := range config.Stages {
@@ -366,11 +607,84 @@ func validateWorkflows(config *BitriseDataModel) ([]string, error) {
 		if err := checkWorkflowReferenceCycle(ID, workflow, *config, []string{}); err != nil {
 			return workflowWarnings, err
 		}
+
+		if err := checkStepBundleReferences(workflow.Steps, config.StepBundles, []string{}); err != nil {
+			return workflowWarnings, fmt.Errorf("validation error in workflow: %s: %s", ID, err)
+		}
 	}
 
 	return workflowWarnings, nil
 }
 
+func validateStepBundles(config *BitriseDataModel) ([]string, error) {
+	stepBundleWarnings := make([]string, 0)
+	for ID, bundle := range config.StepBundles {
+		idWarning, err := validateID(ID, "step bundle")
+		if idWarning != "" {
+			stepBundleWarnings = append(stepBundleWarnings, idWarning)
+		}
+		if err != nil {
+			return stepBundleWarnings, err
+		}
+
+		warns, err := bundle.Validate()
+		stepBundleWarnings = append(stepBundleWarnings, warns...)
+		if err != nil {
+			return stepBundleWarnings, fmt.Errorf("validation error in step bundle: %s: %s", ID, err)
+		}
+
+		if err := checkStepBundleReferences(bundle.Steps, config.StepBundles, []string{ID}); err != nil {
+			return stepBundleWarnings, fmt.Errorf("validation error in step bundle: %s: %s", ID, err)
+		}
+	}
+
+	return stepBundleWarnings, nil
+}
+
+// checkStepBundleReferences checks that the step bundles referenced in the steps exist, only override their declared inputs,
+// and don't reference each other in a cycle. bundleStack holds the bundles the steps are expanded from.
+func checkStepBundleReferences(steps []StepListItemModel, stepBundles map[string]StepBundleModel, bundleStack []string) error {
+	for _, stepListItem := range steps {
+		if parallelSteps, ok := stepListItem.GetParallelSteps(); ok {
+			if err := checkStepBundleReferences(parallelSteps, stepBundles, bundleStack); err != nil {
+				return err
+			}
+			continue
+		}
+
+		bundleID, ok := stepListItem.GetStepBundleID()
+		if !ok {
+			continue
+		}
+
+		bundle, ok := stepBundles[bundleID]
+		if !ok {
+			return fmt.Errorf("step bundle (%s) is not defined", bundleID)
+		}
+
+		if containsWorkflowName(bundleID, bundleStack) {
+			return fmt.Errorf("step bundle reference cycle found: %s", strings.Join(append(bundleStack, bundleID), " -> "))
+		}
+
+		_, reference := stepListItem.GetStepIDAndStep()
+		for _, input := range reference.Inputs {
+			key, _, err := input.GetKeyValuePair()
+			if err != nil {
+				return err
+			}
+			if _, found := getInputByKey(stepmanModels.StepModel{Inputs: bundle.Inputs}, key); !found {
+				return fmt.Errorf("step bundle (%s) has no input: %s", bundleID, key)
+			}
+		}
+
+		if err := checkStepBundleReferences(bundle.Steps, stepBundles, append(bundleStack, bundleID)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func validateID(id, modelType string) (string, error) {
 	if id == "" {
 		return "", fmt.Errorf("invalid %s ID (%s): empty", modelType, id)
@@ -545,6 +859,15 @@ func (workflow *WorkflowModel) removeRedundantFields() error {
 	return nil
 }
 
+func (bundle *StepBundleModel) removeRedundantFields() error {
+	for _, env := range bundle.envItems() {
+		if err := removeEnvironmentRedundantFields(&env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (app *AppModel) removeRedundantFields() error {
 	for _, env := range app.Environments {
 		if err := removeEnvironmentRedundantFields(&env); err != nil {
@@ -559,6 +882,11 @@ func (config *BitriseDataModel) RemoveRedundantFields() error {
 	if err := config.App.removeRedundantFields(); err != nil {
 		return err
 	}
+	for _, bundle := range config.StepBundles {
+		if err := bundle.removeRedundantFields(); err != nil {
+			return err
+		}
+	}
 	for _, workflow := range config.Workflows {
 		if err := workflow.removeRedundantFields(); err != nil {
 			return err
@@ -810,11 +1138,60 @@ func GetStageIDFromListItemModel(stageListItem StageListItemModel) (string, erro
 // Use this on validated BitriseDataModels.
 func (stepListItem StepListItemModel) GetStepIDAndStep() (string, stepmanModels.StepModel) {
 	for key, value := range stepListItem {
-		return key, value
+		return key, value.StepModel
 	}
 	return "", stepmanModels.StepModel{}
 }
 
+// GetParallelSteps returns the steps of a parallel step group,
+// the second return value is false if the stepListItem is a single step.
+func (stepListItem StepListItemModel) GetParallelSteps() ([]StepListItemModel, bool) {
+	if len(stepListItem) != 1 {
+		return nil, false
+	}
+	group, ok := stepListItem[ParallelStepGroupKey]
+	if !ok {
+		return nil, false
+	}
+	return group.Steps, true
+}
+
+// GetStepBundleID returns the ID of the step bundle referenced by the stepListItem (bundle::<ID>),
+// the second return value is false if the stepListItem is not a step bundle reference.
+func (stepListItem StepListItemModel) GetStepBundleID() (string, bool) {
+	if len(stepListItem) != 1 {
+		return "", false
+	}
+	stepID, _ := stepListItem.GetStepIDAndStep()
+	if !strings.HasPrefix(stepID, StepBundleIDPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(stepID, StepBundleIDPrefix), true
+}
+
+// GetRetry returns the retry policy of the step, nil if the step should not be retried.
+func (stepListItem StepListItemModel) GetRetry() *StepRetryModel {
+	for _, value := range stepListItem {
+		return value.Retry
+	}
+	return nil
+}
+
+// GetContainer returns the container the step should run in, nil if the step runs where its workflow runs.
+func (stepListItem StepListItemModel) GetContainer() *Container {
+	for _, value := range stepListItem {
+		return value.Container
+	}
+	return nil
+}
+
+// SetStep updates the step properties of the stepListItem, keeping its workflow specific options.
+func (stepListItem StepListItemModel) SetStep(stepID string, step stepmanModels.StepModel) {
+	workflowStep := stepListItem[stepID]
+	workflowStep.StepModel = step
+	stepListItem[stepID] = workflowStep
+}
+
 // GetStepIDStepDataPair ...
 func GetStepIDStepDataPair(stepListItem StepListItemModel) (string, stepmanModels.StepModel, error) {
 	if len(stepListItem) == 0 {
@@ -1018,3 +1395,97 @@ func (buildRes BuildRunResultsModel) OrderedResults() []StepRunResultsModel {
 	}
 	return results
 }
+
+// ----------------------------
+// --- PipelineRunResults
+
+func (stageRes StageRunResultsModel) IsStageFailed() bool {
+	if stageRes.Status == StageRunStatusCodeFailed {
+		return true
+	}
+
+	for _, workflowResult := range stageRes.WorkflowResults {
+		if workflowResult.IsBuildFailed() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (pipelineRes PipelineRunResultsModel) IsBuildFailed() bool {
+	for _, stageResult := range pipelineRes.StageResults {
+		if stageResult.IsStageFailed() {
+			return true
+		}
+	}
+
+	for _, workflowResult := range pipelineRes.WorkflowResults {
+		if workflowResult.IsBuildFailed() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (pipelineRes PipelineRunResultsModel) ExitCode() int {
+	for _, workflowResult := range pipelineRes.allWorkflowResults() {
+		if exitCode := workflowResult.ExitCode(); exitCode != 0 {
+			return exitCode
+		}
+	}
+
+	if pipelineRes.IsBuildFailed() {
+		return exitcode.CLIFailed
+	}
+
+	return 0
+}
+
+// BuildRunResults merges the step results of every workflow run by the pipeline,
+// so that the pipeline can be evaluated in the same templates (run_if) and plugin events as a single workflow run.
+func (pipelineRes PipelineRunResultsModel) BuildRunResults() BuildRunResultsModel {
+	merged := BuildRunResultsModel{
+		WorkflowID:     pipelineRes.PipelineID,
+		StartTime:      pipelineRes.StartTime,
+		StepmanUpdates: map[string]int{},
+	}
+
+	for _, workflowResult := range pipelineRes.allWorkflowResults() {
+		if merged.EventName == "" {
+			merged.EventName = workflowResult.EventName
+		}
+		if merged.ProjectType == "" {
+			merged.ProjectType = workflowResult.ProjectType
+		}
+		for stepLib, updates := range workflowResult.StepmanUpdates {
+			merged.StepmanUpdates[stepLib] += updates
+		}
+
+		offset := merged.ResultsCount()
+		merged.SuccessSteps = append(merged.SuccessSteps, offsetStepRunResults(workflowResult.SuccessSteps, offset)...)
+		merged.FailedSteps = append(merged.FailedSteps, offsetStepRunResults(workflowResult.FailedSteps, offset)...)
+		merged.FailedSkippableSteps = append(merged.FailedSkippableSteps, offsetStepRunResults(workflowResult.FailedSkippableSteps, offset)...)
+		merged.SkippedSteps = append(merged.SkippedSteps, offsetStepRunResults(workflowResult.SkippedSteps, offset)...)
+	}
+
+	return merged
+}
+
+func (pipelineRes PipelineRunResultsModel) allWorkflowResults() []BuildRunResultsModel {
+	var workflowResults []BuildRunResultsModel
+	for _, stageResult := range pipelineRes.StageResults {
+		workflowResults = append(workflowResults, stageResult.WorkflowResults...)
+	}
+	return append(workflowResults, pipelineRes.WorkflowResults...)
+}
+
+func offsetStepRunResults(results []StepRunResultsModel, offset int) []StepRunResultsModel {
+	var offsetResults []StepRunResultsModel
+	for _, result := range results {
+		result.Idx += offset
+		offsetResults = append(offsetResults, result)
+	}
+	return offsetResults
+}
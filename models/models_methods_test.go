@@ -1,11 +1,13 @@
 package models
 
 import (
+	"fmt"
 	"os"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/bitrise-io/bitrise/exitcode"
 	envmanModels "github.com/bitrise-io/envman/models"
 	"github.com/bitrise-io/go-utils/pointers"
 	stepmanModels "github.com/bitrise-io/stepman/models"
@@ -382,6 +384,326 @@ workflows:
 		require.NoError(t, err)
 		require.Equal(t, 1, len(warnings))
 	}
+
+	t.Log("valid workflow - exports")
+	{
+		workflow := WorkflowModel{
+			Exports: WorkflowExportsModel{
+				Envs:  []string{"APP_VERSION"},
+				Files: []ExportedFileModel{{Path: "$BITRISE_DEPLOY_DIR/app.ipa", EnvKey: "BITRISE_IPA_PATH"}},
+			},
+		}
+
+		warnings, err := workflow.Validate()
+		require.NoError(t, err)
+		require.Equal(t, 0, len(warnings))
+	}
+
+	t.Log("invalid workflow - exported file without env_key")
+	{
+		workflow := WorkflowModel{
+			Exports: WorkflowExportsModel{
+				Files: []ExportedFileModel{{Path: "$BITRISE_DEPLOY_DIR/app.ipa"}},
+			},
+		}
+
+		_, err := workflow.Validate()
+		require.EqualError(t, err, "invalid exports: missing env_key of exported file ($BITRISE_DEPLOY_DIR/app.ipa)")
+	}
+
+	t.Log("parallel step groups")
+	{
+		configStr := `format_version: 1.4.0
+
+workflows:
+  valid:
+    steps:
+    - parallel:
+        steps:
+        - script@1:
+            title: Lint
+        - script@1:
+            title: Unit test
+  nested:
+    steps:
+    - parallel:
+        steps:
+        - parallel:
+            steps:
+            - script@1: {}
+  empty:
+    steps:
+    - parallel: {}
+`
+
+		config := BitriseDataModel{}
+		require.NoError(t, yaml.Unmarshal([]byte(configStr), &config))
+		require.NoError(t, config.Normalize())
+
+		parallelSteps, ok := config.Workflows["valid"].Steps[0].GetParallelSteps()
+		require.True(t, ok)
+		require.Equal(t, 2, len(parallelSteps))
+		stepID, step := parallelSteps[1].GetStepIDAndStep()
+		require.Equal(t, "script@1", stepID)
+		require.Equal(t, "Unit test", *step.Title)
+
+		validateWorkflow := func(workflow WorkflowModel) ([]string, error) {
+			return workflow.Validate()
+		}
+
+		_, err := validateWorkflow(config.Workflows["valid"])
+		require.NoError(t, err)
+
+		_, err = validateWorkflow(config.Workflows["nested"])
+		require.EqualError(t, err, "invalid parallel step group: parallel groups can't be nested")
+
+		_, err = validateWorkflow(config.Workflows["empty"])
+		require.EqualError(t, err, "invalid parallel step group: no steps defined")
+	}
+
+	t.Log("step container")
+	{
+		configStr := `format_version: 1.4.0
+
+workflows:
+  valid:
+    steps:
+    - script@1:
+        container:
+          image: node:20
+  missing_image:
+    steps:
+    - script@1:
+        container:
+          options: --cpus 2
+`
+
+		config := BitriseDataModel{}
+		require.NoError(t, yaml.Unmarshal([]byte(configStr), &config))
+		require.NoError(t, config.Normalize())
+
+		validWorkflow := config.Workflows["valid"]
+		_, err := validWorkflow.Validate()
+		require.NoError(t, err)
+		require.Equal(t, &Container{Image: "node:20"}, validWorkflow.Steps[0].GetContainer())
+
+		missingImageWorkflow := config.Workflows["missing_image"]
+		_, err = missingImageWorkflow.Validate()
+		require.EqualError(t, err, "step (script@1) has invalid container: missing image")
+	}
+
+	t.Log("container volumes")
+	{
+		configStr := `format_version: 1.4.0
+
+workflows:
+  valid:
+    container:
+      image: ubuntu
+      volumes:
+      - $BITRISE_SOURCE_DIR/.gradle:/root/.gradle
+    services:
+      postgres:
+        image: postgres
+        volumes:
+        - pgdata:/var/lib/postgresql/data
+  invalid_workflow_volume:
+    container:
+      image: ubuntu
+      volumes:
+      - /cache
+  invalid_service_volume:
+    services:
+      postgres:
+        image: postgres
+        volumes:
+        - pgdata:data
+  invalid_step_volume:
+    steps:
+    - script@1:
+        container:
+          image: node:20
+          volumes:
+          - /cache:/cache:rx
+`
+
+		config := BitriseDataModel{}
+		require.NoError(t, yaml.Unmarshal([]byte(configStr), &config))
+		require.NoError(t, config.Normalize())
+
+		validWorkflow := config.Workflows["valid"]
+		_, err := validWorkflow.Validate()
+		require.NoError(t, err)
+
+		invalidWorkflowVolume := config.Workflows["invalid_workflow_volume"]
+		_, err = invalidWorkflowVolume.Validate()
+		require.EqualError(t, err, "invalid container: invalid volume (/cache): expected format: <host path>:<container path>[:<options>]")
+
+		invalidServiceVolume := config.Workflows["invalid_service_volume"]
+		_, err = invalidServiceVolume.Validate()
+		require.EqualError(t, err, "invalid service (postgres): invalid volume (pgdata:data): container path should be absolute, got: data")
+
+		invalidStepVolume := config.Workflows["invalid_step_volume"]
+		_, err = invalidStepVolume.Validate()
+		require.EqualError(t, err, "step (script@1) has invalid container: invalid volume (/cache:/cache:rx): unknown option (rx), supported options: ro, rw, z, Z, cached, delegated, consistent")
+	}
+
+	t.Log("service readiness")
+	{
+		configStr := `format_version: 1.4.0
+
+workflows:
+  valid:
+    services:
+      postgres:
+        image: postgres
+        readiness:
+          tcp: localhost:5432
+          command: pg_isready
+          timeout: 120
+  invalid_service_readiness:
+    services:
+      api:
+        image: api
+        readiness:
+          http:
+            url: localhost:8080/health
+  workflow_container_readiness:
+    container:
+      image: ubuntu
+      readiness:
+        tcp: localhost:22
+  step_container_readiness:
+    steps:
+    - script@1:
+        container:
+          image: node:20
+          readiness:
+            command: node --version
+`
+
+		config := BitriseDataModel{}
+		require.NoError(t, yaml.Unmarshal([]byte(configStr), &config))
+		require.NoError(t, config.Normalize())
+
+		validWorkflow := config.Workflows["valid"]
+		_, err := validWorkflow.Validate()
+		require.NoError(t, err)
+
+		invalidServiceReadiness := config.Workflows["invalid_service_readiness"]
+		_, err = invalidServiceReadiness.Validate()
+		require.EqualError(t, err, "invalid service (api): invalid readiness: http url should be an absolute http(s) url, got: localhost:8080/health")
+
+		workflowContainerReadiness := config.Workflows["workflow_container_readiness"]
+		_, err = workflowContainerReadiness.Validate()
+		require.EqualError(t, err, "invalid container: readiness is only supported for services")
+
+		stepContainerReadiness := config.Workflows["step_container_readiness"]
+		_, err = stepContainerReadiness.Validate()
+		require.EqualError(t, err, "step (script@1) has invalid container: readiness is only supported for services")
+	}
+}
+
+func TestValidateStepBundles(t *testing.T) {
+	tests := []struct {
+		name      string
+		configStr string
+		wantErr   string
+	}{
+		{
+			name: "valid",
+			configStr: `
+step_bundles:
+  install_deps:
+    inputs:
+    - cache_key: deps
+    envs:
+    - LOCKFILE: package-lock.json
+    steps:
+    - script@1: {}
+  test:
+    steps:
+    - bundle::install_deps:
+        inputs:
+        - cache_key: test-deps
+    - script@1: {}
+workflows:
+  primary:
+    steps:
+    - bundle::test: {}
+`,
+		},
+		{
+			name: "missing bundle",
+			configStr: `
+workflows:
+  primary:
+    steps:
+    - bundle::install_deps: {}
+`,
+			wantErr: "validation error in workflow: primary: step bundle (install_deps) is not defined",
+		},
+		{
+			name: "reference cycle",
+			configStr: `
+step_bundles:
+  first:
+    steps:
+    - bundle::second: {}
+  second:
+    steps:
+    - bundle::first: {}
+`,
+			wantErr: "step bundle reference cycle found: ",
+		},
+		{
+			name: "undeclared input",
+			configStr: `
+step_bundles:
+  install_deps:
+    steps:
+    - script@1: {}
+workflows:
+  primary:
+    steps:
+    - bundle::install_deps:
+        inputs:
+        - cache_key: deps
+`,
+			wantErr: "validation error in workflow: primary: step bundle (install_deps) has no input: cache_key",
+		},
+		{
+			name: "bundle in parallel group",
+			configStr: `
+step_bundles:
+  install_deps:
+    steps:
+    - script@1: {}
+workflows:
+  primary:
+    steps:
+    - parallel:
+        steps:
+        - bundle::install_deps: {}
+`,
+			wantErr: "validation error in workflow: primary: invalid parallel step group: step bundle (install_deps) can't be referenced in a parallel group",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := BitriseDataModel{}
+			require.NoError(t, yaml.Unmarshal([]byte("format_version: 13\n"+tt.configStr), &config))
+			require.NoError(t, config.Normalize())
+
+			_, err := config.Validate()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
 }
 
 // Trigger map
@@ -753,7 +1075,7 @@ func Test_StepIDData_IsUniqueResourceID(t *testing.T) {
 }
 
 func TestGetStepIDStepDataPair(t *testing.T) {
-	stepData := stepmanModels.StepModel{}
+	stepData := WorkflowStepModel{}
 
 	t.Log("valid steplist item")
 	{
@@ -1171,3 +1493,189 @@ func TestBitriseDataModelValidateWorkflowsCircularDependency(t *testing.T) {
 	require.Equal(t, "0", os.Getenv("BITRISE_BUILD_STATUS"))
 	require.Equal(t, "0", os.Getenv("STEPLIB_BUILD_STATUS"))
 }
+
+func TestBitriseDataModelValidateGraphPipeline(t *testing.T) {
+	workflows := map[string]WorkflowModel{
+		"build":    {},
+		"test":     {},
+		"deploy":   {},
+		"_utility": {},
+	}
+
+	tests := []struct {
+		name     string
+		pipeline PipelineModel
+		wantErr  string
+	}{
+		{
+			name: "valid graph",
+			pipeline: PipelineModel{
+				Workflows: GraphPipelineWorkflowListItemModel{
+					"build":  {},
+					"test":   {DependsOn: []string{"build"}},
+					"deploy": {DependsOn: []string{"build", "test"}},
+				},
+				MaxParallel: 2,
+			},
+		},
+		{
+			name: "both stages and workflows",
+			pipeline: PipelineModel{
+				Stages:    []StageListItemModel{{"stage": {}}},
+				Workflows: GraphPipelineWorkflowListItemModel{"build": {}},
+			},
+			wantErr: "pipeline (pipeline1) should have either stages or workflows, not both",
+		},
+		{
+			name: "missing workflow",
+			pipeline: PipelineModel{
+				Workflows: GraphPipelineWorkflowListItemModel{"missing": {}},
+			},
+			wantErr: "workflow (missing) defined in pipeline (pipeline1), but does not exist",
+		},
+		{
+			name: "utility workflow",
+			pipeline: PipelineModel{
+				Workflows: GraphPipelineWorkflowListItemModel{"_utility": {}},
+			},
+			wantErr: "workflow (_utility) defined in pipeline (pipeline1), is a utility workflow",
+		},
+		{
+			name: "dependency outside of the pipeline",
+			pipeline: PipelineModel{
+				Workflows: GraphPipelineWorkflowListItemModel{"test": {DependsOn: []string{"build"}}},
+			},
+			wantErr: "workflow (test) defined in pipeline (pipeline1) depends on workflow (build), which is not part of the pipeline",
+		},
+		{
+			name: "dependency cycle",
+			pipeline: PipelineModel{
+				Workflows: GraphPipelineWorkflowListItemModel{
+					"build": {DependsOn: []string{"build"}},
+				},
+			},
+			wantErr: "pipeline (pipeline1): Workflow dependency cycle found: build -> build",
+		},
+		{
+			name: "negative max_parallel",
+			pipeline: PipelineModel{
+				Workflows:   GraphPipelineWorkflowListItemModel{"build": {}},
+				MaxParallel: -1,
+			},
+			wantErr: "pipeline (pipeline1) has invalid max_parallel (-1), should be 0 (no limit) or greater",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := BitriseDataModel{
+				FormatVersion: "1.4.0",
+				Pipelines:     map[string]PipelineModel{"pipeline1": tt.pipeline},
+				Workflows:     workflows,
+			}
+
+			_, err := config.Validate()
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("longer dependency cycle", func(t *testing.T) {
+		config := BitriseDataModel{
+			FormatVersion: "1.4.0",
+			Pipelines: map[string]PipelineModel{"pipeline1": {
+				Workflows: GraphPipelineWorkflowListItemModel{
+					"build":  {DependsOn: []string{"deploy"}},
+					"test":   {DependsOn: []string{"build"}},
+					"deploy": {DependsOn: []string{"test"}},
+				},
+			}},
+			Workflows: workflows,
+		}
+
+		_, err := config.Validate()
+		require.EqualError(t, err, "pipeline (pipeline1): Workflow dependency cycle found: build -> deploy -> test -> build")
+	})
+
+	t.Run("diamond dependencies", func(t *testing.T) {
+		// every layer depends on both workflows of the previous layer, walking every path would take 2^layers steps
+		pipelineWorkflows := GraphPipelineWorkflowListItemModel{}
+		testWorkflows := map[string]WorkflowModel{}
+		for layer := 0; layer < 40; layer++ {
+			for _, side := range []string{"a", "b"} {
+				workflowID := fmt.Sprintf("layer%d%s", layer, side)
+				testWorkflows[workflowID] = WorkflowModel{}
+				if layer == 0 {
+					pipelineWorkflows[workflowID] = GraphPipelineWorkflowModel{}
+					continue
+				}
+				pipelineWorkflows[workflowID] = GraphPipelineWorkflowModel{
+					DependsOn: []string{fmt.Sprintf("layer%da", layer-1), fmt.Sprintf("layer%db", layer-1)},
+				}
+			}
+		}
+		config := BitriseDataModel{
+			FormatVersion: "1.4.0",
+			Pipelines:     map[string]PipelineModel{"pipeline1": {Workflows: pipelineWorkflows}},
+			Workflows:     testWorkflows,
+		}
+
+		_, err := config.Validate()
+		require.NoError(t, err)
+	})
+}
+
+func TestPipelineRunResultsModel(t *testing.T) {
+	successWorkflow := BuildRunResultsModel{
+		WorkflowID:   "success",
+		SuccessSteps: []StepRunResultsModel{{Status: StepRunStatusCodeSuccess, Idx: 0}, {Status: StepRunStatusCodeSuccess, Idx: 1}},
+	}
+	failedWorkflow := BuildRunResultsModel{
+		WorkflowID:   "fail",
+		SuccessSteps: []StepRunResultsModel{{Status: StepRunStatusCodeSuccess, Idx: 0}},
+		FailedSteps:  []StepRunResultsModel{{Status: StepRunStatusCodeFailed, Idx: 1, ExitCode: 2}},
+	}
+
+	t.Run("succeeded pipeline", func(t *testing.T) {
+		pipelineRunResults := PipelineRunResultsModel{
+			PipelineID: "pipeline",
+			StageResults: []StageRunResultsModel{
+				{StageID: "stage1", WorkflowResults: []BuildRunResultsModel{successWorkflow}},
+				{StageID: "stage2", Status: StageRunStatusCodeSkippedWithRunIf},
+			},
+		}
+
+		require.False(t, pipelineRunResults.IsBuildFailed())
+		require.Equal(t, 0, pipelineRunResults.ExitCode())
+	})
+
+	t.Run("failed pipeline", func(t *testing.T) {
+		pipelineRunResults := PipelineRunResultsModel{
+			PipelineID: "pipeline",
+			StageResults: []StageRunResultsModel{
+				{StageID: "stage1", WorkflowResults: []BuildRunResultsModel{successWorkflow, failedWorkflow}},
+				{StageID: "stage2", Status: StageRunStatusCodeSkipped},
+			},
+		}
+
+		require.True(t, pipelineRunResults.IsBuildFailed())
+		require.Equal(t, exitcode.CLIFailed, pipelineRunResults.ExitCode())
+
+		buildRunResults := pipelineRunResults.BuildRunResults()
+		require.Equal(t, "pipeline", buildRunResults.WorkflowID)
+		require.Equal(t, 4, buildRunResults.ResultsCount())
+		require.True(t, buildRunResults.IsBuildFailed())
+		require.Equal(t, 3, buildRunResults.FailedSteps[0].Idx)
+	})
+
+	t.Run("stage failed without workflow results", func(t *testing.T) {
+		pipelineRunResults := PipelineRunResultsModel{
+			StageResults: []StageRunResultsModel{{StageID: "stage1", Status: StageRunStatusCodeFailed}},
+		}
+
+		require.True(t, pipelineRunResults.IsBuildFailed())
+		require.Equal(t, exitcode.CLIFailed, pipelineRunResults.ExitCode())
+	})
+}
@@ -0,0 +1,40 @@
+package models
+
+// StageRunStatus ...
+type StageRunStatus int
+
+const (
+	StageRunStatusCodeSuccess          StageRunStatus = 0
+	StageRunStatusCodeFailed           StageRunStatus = 1
+	StageRunStatusCodeSkipped          StageRunStatus = 2 // a previous stage failed and the stage is not marked should_always_run
+	StageRunStatusCodeSkippedWithRunIf StageRunStatus = 3 // the stage's run_if expression evaluated to false
+)
+
+func (s StageRunStatus) String() string {
+	switch s {
+	case StageRunStatusCodeSuccess:
+		return "success"
+	case StageRunStatusCodeFailed:
+		return "failed"
+	case StageRunStatusCodeSkipped:
+		return "skipped"
+	case StageRunStatusCodeSkippedWithRunIf:
+		return "skipped_with_run_if"
+	default:
+		return "unknown"
+	}
+}
+
+func (s StageRunStatus) Name() string {
+	switch s {
+	case StageRunStatusCodeSuccess:
+		return ""
+	case StageRunStatusCodeFailed:
+		return "Failed"
+	case StageRunStatusCodeSkipped,
+		StageRunStatusCodeSkippedWithRunIf:
+		return "Skipped"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	StepRetryOnTimeout         = "timeout"
+	StepRetryOnNoOutputTimeout = "no_output_timeout"
+)
+
+// Validate ...
+func (retry StepRetryModel) Validate() error {
+	if retry.MaxAttempts < 1 {
+		return fmt.Errorf("invalid retry: max_attempts should be at least 1, got: %d", retry.MaxAttempts)
+	}
+	if retry.Backoff < 0 {
+		return fmt.Errorf("invalid retry: backoff should not be negative, got: %d", retry.Backoff)
+	}
+	if retry.BackoffMultiplier < 0 {
+		return fmt.Errorf("invalid retry: backoff_multiplier should not be negative, got: %g", retry.BackoffMultiplier)
+	}
+	for _, on := range retry.On {
+		if on != StepRetryOnTimeout && on != StepRetryOnNoOutputTimeout {
+			return fmt.Errorf("invalid retry: unknown on value (%s), supported values: %s, %s", on, StepRetryOnTimeout, StepRetryOnNoOutputTimeout)
+		}
+	}
+	return nil
+}
+
+// ShouldRetry decides if a step run which finished with the given status and exit code should be retried.
+func (retry StepRetryModel) ShouldRetry(status StepRunStatus, exitCode int) bool {
+	if len(retry.ExitCodes) == 0 && len(retry.On) == 0 {
+		return status == StepRunStatusCodeFailed ||
+			status == StepRunStatusAbortedWithCustomTimeout ||
+			status == StepRunStatusAbortedWithNoOutputTimeout
+	}
+
+	switch status {
+	case StepRunStatusCodeFailed:
+		for _, code := range retry.ExitCodes {
+			if code == exitCode {
+				return true
+			}
+		}
+	case StepRunStatusAbortedWithCustomTimeout:
+		return retry.retriesOn(StepRetryOnTimeout)
+	case StepRunStatusAbortedWithNoOutputTimeout:
+		return retry.retriesOn(StepRetryOnNoOutputTimeout)
+	}
+	return false
+}
+
+// BackoffBefore returns the wait time before the given attempt (starting from 1).
+func (retry StepRetryModel) BackoffBefore(attempt int) time.Duration {
+	if attempt < 2 || retry.Backoff == 0 {
+		return 0
+	}
+
+	backoff := float64(retry.Backoff)
+	if retry.BackoffMultiplier > 0 {
+		backoff *= math.Pow(retry.BackoffMultiplier, float64(attempt-2))
+	}
+	return time.Duration(backoff * float64(time.Second))
+}
+
+func (retry StepRetryModel) retriesOn(reason string) bool {
+	for _, on := range retry.On {
+		if on == reason {
+			return true
+		}
+	}
+	return false
+}
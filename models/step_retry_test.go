@@ -0,0 +1,80 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestStepRetryModel_Unmarshal(t *testing.T) {
+	stepListItemStr := `
+script@1:
+  title: Flaky script
+  retry:
+    max_attempts: 3
+    backoff: 10
+    exit_codes: [ 1, 2 ]
+    on: [ timeout ]
+`
+	var stepListItem StepListItemModel
+	require.NoError(t, yaml.Unmarshal([]byte(stepListItemStr), &stepListItem))
+
+	stepID, step := stepListItem.GetStepIDAndStep()
+	require.Equal(t, "script@1", stepID)
+	require.Equal(t, "Flaky script", *step.Title)
+	require.Equal(t, &StepRetryModel{MaxAttempts: 3, Backoff: 10, ExitCodes: []int{1, 2}, On: []string{"timeout"}}, stepListItem.GetRetry())
+}
+
+func TestStepRetryModel_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		retry   StepRetryModel
+		wantErr string
+	}{
+		{name: "valid", retry: StepRetryModel{MaxAttempts: 2, Backoff: 5, BackoffMultiplier: 2, On: []string{"timeout", "no_output_timeout"}}},
+		{name: "missing max attempts", retry: StepRetryModel{}, wantErr: "invalid retry: max_attempts should be at least 1, got: 0"},
+		{name: "negative backoff", retry: StepRetryModel{MaxAttempts: 2, Backoff: -1}, wantErr: "invalid retry: backoff should not be negative, got: -1"},
+		{name: "unknown on value", retry: StepRetryModel{MaxAttempts: 2, On: []string{"failed"}}, wantErr: "invalid retry: unknown on value (failed), supported values: timeout, no_output_timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.retry.Validate()
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestStepRetryModel_ShouldRetry(t *testing.T) {
+	anyFailure := StepRetryModel{MaxAttempts: 2}
+	require.True(t, anyFailure.ShouldRetry(StepRunStatusCodeFailed, 1))
+	require.True(t, anyFailure.ShouldRetry(StepRunStatusAbortedWithNoOutputTimeout, 1))
+	require.False(t, anyFailure.ShouldRetry(StepRunStatusCodePreparationFailed, 1))
+
+	exitCodes := StepRetryModel{MaxAttempts: 2, ExitCodes: []int{3}}
+	require.True(t, exitCodes.ShouldRetry(StepRunStatusCodeFailed, 3))
+	require.False(t, exitCodes.ShouldRetry(StepRunStatusCodeFailed, 1))
+	require.False(t, exitCodes.ShouldRetry(StepRunStatusAbortedWithCustomTimeout, 1))
+
+	timeout := StepRetryModel{MaxAttempts: 2, On: []string{StepRetryOnTimeout}}
+	require.True(t, timeout.ShouldRetry(StepRunStatusAbortedWithCustomTimeout, 1))
+	require.False(t, timeout.ShouldRetry(StepRunStatusAbortedWithNoOutputTimeout, 1))
+	require.False(t, timeout.ShouldRetry(StepRunStatusCodeFailed, 1))
+}
+
+func TestStepRetryModel_BackoffBefore(t *testing.T) {
+	constant := StepRetryModel{MaxAttempts: 3, Backoff: 5}
+	require.Equal(t, time.Duration(0), constant.BackoffBefore(1))
+	require.Equal(t, 5*time.Second, constant.BackoffBefore(2))
+	require.Equal(t, 5*time.Second, constant.BackoffBefore(3))
+
+	exponential := StepRetryModel{MaxAttempts: 4, Backoff: 5, BackoffMultiplier: 2}
+	require.Equal(t, 5*time.Second, exponential.BackoffBefore(2))
+	require.Equal(t, 10*time.Second, exponential.BackoffBefore(3))
+	require.Equal(t, 20*time.Second, exponential.BackoffBefore(4))
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	envmanModels "github.com/bitrise-io/envman/models"
+)
 
 type WorkflowRunModes struct {
 	CIMode                  bool
@@ -14,6 +18,15 @@ type WorkflowRunModes struct {
 type StepExecutionPlan struct {
 	UUID   string `json:"uuid"`
 	StepID string `json:"step_id"`
+	// Steps are the plans of the steps of a parallel step group
+	Steps []StepExecutionPlan `json:"steps,omitempty"`
+	// StepBundleID is the ID of the step bundle the step is expanded from
+	StepBundleID string `json:"step_bundle_id,omitempty"`
+
+	// Step is the step list item to run
+	Step StepListItemModel `json:"-"`
+	// StepBundleEnvs are the inputs and envs of the step bundles the step is expanded from
+	StepBundleEnvs []envmanModels.EnvironmentItemModel `json:"-"`
 }
 
 type WorkflowExecutionPlan struct {
@@ -4,6 +4,8 @@ import "github.com/bitrise-io/go-utils/command"
 
 type goCmdBuilder struct {
 	goConfig GoConfigurationModel
+	// platform is the target platform of the build (<os>/<arch>[/<variant>]), the host platform if empty
+	platform string
 }
 
 func (g goCmdBuilder) goBuildArgs(outputBin string, explicitlyVendor bool) []string {
@@ -32,6 +34,7 @@ func (g goCmdBuilder) goBuildEnv(stepAbsDir string, shouldCheckGoSum bool) []str
 	if !shouldCheckGoSum {
 		envs = append(envs, "GOSUMDB=off")
 	}
+	envs = append(envs, goPlatformEnvs(g.platform)...)
 
 	return envs
 }
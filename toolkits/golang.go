@@ -28,6 +28,9 @@ import (
 
 // GoToolkit ...
 type GoToolkit struct {
+	// Platform is the platform (<os>/<arch>[/<variant>]) the steps are compiled for, for example the platform
+	// of the container running the step. The steps are compiled for the host if it is empty.
+	Platform string
 }
 
 // ToolkitName ...
@@ -251,8 +254,8 @@ func (toolkit GoToolkit) Install() error {
 
 // === Toolkit: Prepare for Step Run ===
 
-func goBuildStep(cmdRunner commandRunner, goConfig GoConfigurationModel, packageName, stepAbsDirPath, outputBinPath string) error {
-	cmdBuilder := goCmdBuilder{goConfig: goConfig}
+func goBuildStep(cmdRunner commandRunner, goConfig GoConfigurationModel, platform, packageName, stepAbsDirPath, outputBinPath string) error {
+	cmdBuilder := goCmdBuilder{goConfig: goConfig, platform: platform}
 
 	if isGoPathModeStep(stepAbsDirPath) {
 		log.Debugf("[Go deps] Step requires GOPATH mode")
@@ -266,7 +269,7 @@ func goBuildStep(cmdRunner commandRunner, goConfig GoConfigurationModel, package
 
 		log.Debugf("[Go deps] GO111MODULE='%s'", mode)
 		if isGoPathModeSupported(mode) {
-			return goBuildInGoPathMode(cmdRunner, goConfig, packageName, stepAbsDirPath, outputBinPath)
+			return goBuildInGoPathMode(cmdRunner, goConfig, platform, packageName, stepAbsDirPath, outputBinPath)
 		}
 
 		log.Debugf("[Go deps] Migrating Step to Go modules as Go installation does not support GOPATH mode")
@@ -291,7 +294,7 @@ func goBuildStep(cmdRunner commandRunner, goConfig GoConfigurationModel, package
 	return nil
 }
 
-func goBuildInGoPathMode(cmdRunner commandRunner, goConfig GoConfigurationModel, packageName, srcPath, outputBinPath string) error {
+func goBuildInGoPathMode(cmdRunner commandRunner, goConfig GoConfigurationModel, platform, packageName, srcPath, outputBinPath string) error {
 	workspaceRootPath, err := pathutil.NormalizedOSTempDirPath("bitrise-go-toolkit")
 	if err != nil {
 		return fmt.Errorf("Failed to create root directory of isolated workspace, error: %s", err)
@@ -307,6 +310,7 @@ func goBuildInGoPathMode(cmdRunner commandRunner, goConfig GoConfigurationModel,
 	cmd.Stderr = nil
 	cmd.Stdin = nil
 	cmd.Env = append(cmd.Env, "GOROOT="+goConfig.GOROOT)
+	cmd.Env = append(cmd.Env, goPlatformEnvs(platform)...)
 
 	buildCmd := command.NewWithCmd(cmd)
 
@@ -340,14 +344,35 @@ func stepBinaryFilename(sIDData models.StepIDData) string {
 	return safeStepID
 }
 
-func stepBinaryCacheFullPath(sIDData models.StepIDData) string {
-	return filepath.Join(goToolkitCacheRootPath(), stepBinaryFilename(sIDData))
+// stepBinaryCacheFullPath : the binaries compiled for a given platform are cached separately from the host binaries
+func stepBinaryCacheFullPath(sIDData models.StepIDData, platform string) string {
+	filename := stepBinaryFilename(sIDData)
+	if platform != "" {
+		filename += "-" + strings.ReplaceAll(platform, "/", "-")
+	}
+	return filepath.Join(goToolkitCacheRootPath(), filename)
 }
 
 // PrepareForStepRun ...
 func (toolkit GoToolkit) PrepareForStepRun(step stepmanModels.StepModel, sIDData models.StepIDData, stepAbsDirPath string) error {
-	fullStepBinPath := stepBinaryCacheFullPath(sIDData)
+	fullStepBinPath := stepBinaryCacheFullPath(sIDData, toolkit.Platform)
+
+	if err := toolkit.buildStepBinary(step, sIDData, stepAbsDirPath, fullStepBinPath); err != nil {
+		return err
+	}
+	if toolkit.Platform == "" {
+		return nil
+	}
 
+	// The toolkit's cache is not available in the container, but the step's dir is,
+	// so the binary is copied next to the step's sources
+	if err := command.CopyFile(fullStepBinPath, platformStepBinaryPath(stepAbsDirPath)); err != nil {
+		return fmt.Errorf("Failed to copy the step binary to the step's dir, error: %s", err)
+	}
+	return nil
+}
+
+func (toolkit GoToolkit) buildStepBinary(step stepmanModels.StepModel, sIDData models.StepIDData, stepAbsDirPath, fullStepBinPath string) error {
 	// try to use cached binary, if possible
 	if sIDData.IsUniqueResourceID() {
 		if exists, err := pathutil.IsPathExists(fullStepBinPath); err != nil {
@@ -374,14 +399,20 @@ func (toolkit GoToolkit) PrepareForStepRun(step stepmanModels.StepModel, sIDData
 			"Found Go version is older than required. Please run 'bitrise setup' to check and install the required version")
 	}
 
-	return goBuildStep(&defaultRunner{}, goConfig, step.Toolkit.Go.PackageName, stepAbsDirPath, fullStepBinPath)
+	if toolkit.Platform != "" {
+		log.Debugf("Compiling the step for platform: %s", toolkit.Platform)
+	}
+	return goBuildStep(&defaultRunner{}, goConfig, toolkit.Platform, step.Toolkit.Go.PackageName, stepAbsDirPath, fullStepBinPath)
 }
 
 // === Toolkit: Step Run ===
 
 // StepRunCommandArguments ...
 func (toolkit GoToolkit) StepRunCommandArguments(step stepmanModels.StepModel, sIDData models.StepIDData, stepAbsDirPath string) ([]string, error) {
-	fullStepBinPath := stepBinaryCacheFullPath(sIDData)
+	if toolkit.Platform != "" {
+		return []string{platformStepBinaryPath(stepAbsDirPath)}, nil
+	}
+	fullStepBinPath := stepBinaryCacheFullPath(sIDData, "")
 	return []string{fullStepBinPath}, nil
 }
 
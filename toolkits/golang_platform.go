@@ -0,0 +1,44 @@
+package toolkits
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const platformStepBinaryName = ".bitrise-step-bin"
+
+// goPlatformEnvs returns the envs cross-compiling for the platform (<os>/<arch>[/<variant>]).
+// Cgo is disabled, as a binary linked against the host's C libraries might not run on the target (for example on an alpine image).
+func goPlatformEnvs(platform string) []string {
+	if platform == "" {
+		return nil
+	}
+
+	parts := strings.SplitN(platform, "/", 3)
+	envs := []string{"CGO_ENABLED=0", "GOOS=" + parts[0]}
+	if len(parts) > 1 {
+		envs = append(envs, "GOARCH="+parts[1])
+	}
+	if len(parts) > 2 {
+		switch parts[1] {
+		case "arm":
+			envs = append(envs, "GOARM="+strings.TrimPrefix(parts[2], "v"))
+		case "amd64":
+			envs = append(envs, "GOAMD64="+parts[2])
+		}
+	}
+	return envs
+}
+
+// isHostPlatform returns whether the platform's os and arch are the host's, the binary built for the host runs on the platform.
+func isHostPlatform(platform string) bool {
+	parts := strings.SplitN(platform, "/", 3)
+	return len(parts) > 1 && parts[0] == runtime.GOOS && parts[1] == runtime.GOARCH
+}
+
+// platformStepBinaryPath is the path of the step binary compiled for a platform other than the host's,
+// the binary is placed in the step's dir, as it is available in the container running the step.
+func platformStepBinaryPath(stepAbsDirPath string) string {
+	return filepath.Join(stepAbsDirPath, platformStepBinaryName)
+}
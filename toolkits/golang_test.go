@@ -5,10 +5,12 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/bitrise-io/bitrise/models"
 	"github.com/bitrise-io/go-utils/command"
+	stepmanModels "github.com/bitrise-io/stepman/models"
 	"github.com/stretchr/testify/require"
 )
 
@@ -195,7 +197,7 @@ func Test_goBuildStep(t *testing.T) {
 				GOROOT:       "/goroot",
 			}
 
-			err = goBuildStep(&mockRunner, goConfig, tt.args.packageName, stepDir, tt.args.outputBinPath)
+			err = goBuildStep(&mockRunner, goConfig, "", tt.args.packageName, stepDir, tt.args.outputBinPath)
 
 			require.NoError(t, err, "goBuildStep()")
 			require.Equal(t, tt.wantCmds, mockRunner.cmds, "goBuildStep() run commands do not match")
@@ -260,6 +262,7 @@ func Benchmark_goBuildStep(b *testing.B) {
 
 				err = goBuildStep(&defaultRunner{},
 					goConfig,
+					"",
 					packageName,
 					stepPerTestDir,
 					filepath.Join(outputDir, fmt.Sprintf("%s_%d", mode, i)))
@@ -268,3 +271,61 @@ func Benchmark_goBuildStep(b *testing.B) {
 		})
 	}
 }
+
+func Test_goPlatformEnvs(t *testing.T) {
+	tests := []struct {
+		platform string
+		want     []string
+	}{
+		{platform: "", want: nil},
+		{platform: "linux/amd64", want: []string{"CGO_ENABLED=0", "GOOS=linux", "GOARCH=amd64"}},
+		{platform: "linux/arm64/v8", want: []string{"CGO_ENABLED=0", "GOOS=linux", "GOARCH=arm64"}},
+		{platform: "linux/arm/v7", want: []string{"CGO_ENABLED=0", "GOOS=linux", "GOARCH=arm", "GOARM=7"}},
+		{platform: "linux/amd64/v3", want: []string{"CGO_ENABLED=0", "GOOS=linux", "GOARCH=amd64", "GOAMD64=v3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			require.Equal(t, tt.want, goPlatformEnvs(tt.platform))
+		})
+	}
+
+	cmdBuilder := goCmdBuilder{goConfig: GoConfigurationModel{GoBinaryPath: "go", GOROOT: "/goroot"}, platform: "linux/arm64"}
+	require.Equal(t, []string{"PWD=/step", "GOROOT=/goroot", "GO111MODULE=on", "CGO_ENABLED=0", "GOOS=linux", "GOARCH=arm64"}, cmdBuilder.goBuildEnv("/step", true))
+}
+
+func TestGoToolkit_PrepareForStepRunOnPlatform(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	sIDData := models.StepIDData{SteplibSource: "https://github.com/bitrise-io/bitrise-steplib.git", IDorURI: "go-step", Version: "1.0.0"}
+	require.NotEqual(t, stepBinaryCacheFullPath(sIDData, ""), stepBinaryCacheFullPath(sIDData, "linux/s390x"))
+	require.Equal(t, stepBinaryCacheFullPath(sIDData, "")+"-linux-s390x", stepBinaryCacheFullPath(sIDData, "linux/s390x"))
+
+	// the binary compiled for the platform is cached already
+	cachedBinPath := stepBinaryCacheFullPath(sIDData, "linux/s390x")
+	require.NoError(t, os.MkdirAll(filepath.Dir(cachedBinPath), 0755))
+	require.NoError(t, os.WriteFile(cachedBinPath, []byte("s390x binary"), 0755))
+
+	stepDir := t.TempDir()
+	toolkit := ToolkitForStepOnPlatform(stepmanModels.StepModel{Toolkit: &stepmanModels.StepToolkitModel{Go: &stepmanModels.GoStepToolkitModel{}}}, "linux/s390x")
+	require.Equal(t, GoToolkit{Platform: "linux/s390x"}, toolkit)
+	require.NoError(t, toolkit.PrepareForStepRun(stepmanModels.StepModel{}, sIDData, stepDir))
+
+	// the binary is run from the step's dir, as the toolkit's cache is not available in the container
+	args, err := toolkit.StepRunCommandArguments(stepmanModels.StepModel{}, sIDData, stepDir)
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(stepDir, platformStepBinaryName)}, args)
+
+	content, err := os.ReadFile(args[0])
+	require.NoError(t, err)
+	require.Equal(t, "s390x binary", string(content))
+}
+
+func TestToolkitForStepOnPlatform(t *testing.T) {
+	goStep := stepmanModels.StepModel{Toolkit: &stepmanModels.StepToolkitModel{Go: &stepmanModels.GoStepToolkitModel{}}}
+
+	// the host's binary is used on the host's platform, it is not cross-compiled (with cgo disabled)
+	require.Equal(t, GoToolkit{}, ToolkitForStepOnPlatform(goStep, runtime.GOOS+"/"+runtime.GOARCH))
+	require.Equal(t, GoToolkit{}, ToolkitForStepOnPlatform(goStep, ""))
+	require.Equal(t, GoToolkit{Platform: "windows/s390x"}, ToolkitForStepOnPlatform(goStep, "windows/s390x"))
+	require.Equal(t, BashToolkit{}, ToolkitForStepOnPlatform(stepmanModels.StepModel{}, "windows/s390x"))
+}
@@ -63,11 +63,22 @@ type Toolkit interface {
 
 // ToolkitForStep ...
 func ToolkitForStep(step stepmanModels.StepModel) Toolkit {
+	return ToolkitForStepOnPlatform(step, "")
+}
+
+// ToolkitForStepOnPlatform returns the toolkit of the step, preparing the step to run on the given platform
+// (<os>/<arch>[/<variant>], for example the platform of the container running the step). An empty platform means the host,
+// steps are only cross-compiled for a platform other than the host's.
+func ToolkitForStepOnPlatform(step stepmanModels.StepModel, platform string) Toolkit {
+	if isHostPlatform(platform) {
+		platform = ""
+	}
+
 	var toolkit Toolkit = BashToolkit{}
 	if step.Toolkit != nil {
 		stepToolkit := step.Toolkit
 		if stepToolkit.Go != nil {
-			toolkit = GoToolkit{}
+			toolkit = GoToolkit{Platform: platform}
 		} else if stepToolkit.Swift != nil {
 			toolkit = SwiftToolkit{}
 		}